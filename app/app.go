@@ -1,6 +1,7 @@
 package app
 
 import (
+	"errors"
 	"fmt"
 	"image"
 	"log/slog"
@@ -10,6 +11,7 @@ import (
 
 	"github.com/soocke/pixel-bot-go/config"
 	"github.com/soocke/pixel-bot-go/domain/action"
+	"github.com/soocke/pixel-bot-go/domain/capture"
 	"github.com/soocke/pixel-bot-go/domain/fishing"
 	"github.com/soocke/pixel-bot-go/ui/presenter"
 	"github.com/soocke/pixel-bot-go/ui/theme"
@@ -23,6 +25,10 @@ import (
 
 const (
 	tick = 33 * time.Millisecond
+	// suggestFrames full-screen captures, suggestInterval apart, feed the
+	// water region suggestion.
+	suggestFrames   = 4
+	suggestInterval = 120 * time.Millisecond
 )
 
 type app struct {
@@ -59,6 +65,7 @@ func NewApp(title string, width, height int, cfg *config.Config, logger *slog.Lo
 	// Initialize UI styles and selection overlay.
 	theme.InitStyles()
 	a.selectionView = view.NewSelectionOverlay(cfg, a.configPath, logger)
+	a.selectionView.SetSuggester(a.suggestWaterRegion)
 	return a
 }
 
@@ -83,6 +90,25 @@ func (a *app) Run() (err error) {
 		a.container.Detection,
 		a.logger,
 	)
+	a.container.DetectionPresenter.Prior = a.container.Prior
+	a.container.DetectionPresenter.PriorPath = priorPath
+	a.container.RootView.OnPreviewSample(func(pt image.Point, add bool) {
+		if a.container.DetectionPresenter.LearnBlobColor(pt, add) {
+			if err := cfg.Save(a.configPath); err != nil {
+				a.logger.Error("config save failed", "error", err)
+			}
+		}
+	})
+	a.container.DetectionPresenter.Negatives = a.container.Negatives
+	a.container.DetectionPresenter.TemplateDir = templatesDir
+	a.container.RootView.OnPreviewReject(func(pt image.Point, remove bool) {
+		if remove {
+			a.container.DetectionPresenter.ForgetNegative(pt)
+		} else {
+			a.container.DetectionPresenter.RejectDetection(pt)
+		}
+	})
+	a.container.RootView.OnPreviewProcessed(a.container.DetectionPresenter.SetShowProcessed)
 	a.container.CapturePresenter = presenter.NewCapturePresenter(a.container.Capture, a.container.CaptureSvc, a.container.FSM, a.container.RootView)
 
 	// Focus watcher runs separately while FSM awaits focus.
@@ -97,6 +123,7 @@ func (a *app) Run() (err error) {
 			a.container.FSMPresenter.OnState(next)
 		}
 		focusWatcher.OnState(prev, next)
+		a.container.DetectionPresenter.OnState(prev, next)
 	})
 
 	// Start debug loggers when configured.
@@ -121,6 +148,14 @@ func (a *app) layout() {
 		if a.selectionView != nil {
 			a.selectionView.OpenOrFocus()
 		}
+	}, func() {
+		if dp := a.container.DetectionPresenter; dp != nil {
+			dp.SaveHeatmap()
+		}
+	}, func() {
+		if dp := a.container.DetectionPresenter; dp != nil {
+			dp.ResetAdaptiveTemplate()
+		}
 	}, a.exitHandler, func(title string) { a.selectedWindow = title })
 	a.container.UI = rv
 	// After view & selection overlay are ready, attach selection provider to capture service.
@@ -138,6 +173,17 @@ func (a *app) exitHandler() {
 	if a.container.FSM != nil {
 		a.container.FSM.Close()
 	}
+	a.container.DetectionPresenter.Close()
+	if t := a.container.Trace; t != nil {
+		if err := t.Close(); err != nil && a.container.Logger != nil {
+			a.container.Logger.Warn("bite trace write failed", "error", err)
+		}
+	}
+	if r := a.container.Recorder; r != nil {
+		if err := r.Close(); err != nil && a.container.Logger != nil {
+			a.container.Logger.Warn("bite recording write failed", "error", err)
+		}
+	}
 	Destroy(App)
 }
 
@@ -149,6 +195,29 @@ func (a *app) ScheduleUpdate() {
 	})
 }
 
+// suggestWaterRegion captures a few full-screen frames and proposes the water
+// area as capture selection. It takes about half a second; the selection
+// overlay calls it off the UI thread.
+func (a *app) suggestWaterRegion() (image.Rectangle, error) {
+	frames := make([]*image.RGBA, 0, suggestFrames)
+	for i := 0; i < suggestFrames; i++ {
+		if i > 0 {
+			time.Sleep(suggestInterval)
+		}
+		frame, err := capture.Grab()
+		if err != nil {
+			return image.Rectangle{}, err
+		}
+		frames = append(frames, frame)
+	}
+	region, ok := capture.SuggestWaterRegion(frames)
+	if !ok {
+		return image.Rectangle{}, errors.New("no water area found")
+	}
+	a.logger.Info("water region suggested", "rect", region.Rect, "coverage", region.Coverage, "score", region.Score)
+	return region.Rect, nil
+}
+
 // getSelectionRect proxies to selectionView (may be nil).
 // toggleCapture delegates to the capture presenter.
 func (a *app) toggleCapture() {
@@ -9,11 +9,21 @@ import (
 	"github.com/soocke/pixel-bot-go/domain/action"
 	"github.com/soocke/pixel-bot-go/domain/capture"
 	"github.com/soocke/pixel-bot-go/domain/fishing"
+	"github.com/soocke/pixel-bot-go/domain/tuning"
 	"github.com/soocke/pixel-bot-go/ui/model"
 	"github.com/soocke/pixel-bot-go/ui/presenter"
 	"github.com/soocke/pixel-bot-go/ui/view"
 )
 
+// priorPath is the spatial prior persistence file relative to the working directory.
+const priorPath = "pixel_bot_spatial_prior.json"
+
+// negativesDir holds negative template PNGs relative to the working directory.
+const negativesDir = "pixel_bot_negatives"
+
+// templatesDir is the template library holding each profile's adaptive template.
+const templatesDir = "pixel_bot_templates"
+
 // Container assembles models, services, presenters and the root view.
 type AppContainer struct {
 	Config     *config.Config
@@ -33,6 +43,10 @@ type AppContainer struct {
 	CapturePresenter   *presenter.CapturePresenter
 	Loop               *presenter.Loop
 	TargetImg          image.Image
+	Prior              *capture.SpatialPrior
+	Negatives          *capture.NegativeLibrary
+	Trace              *fishing.TraceWriter
+	Recorder           *tuning.SequenceRecorder
 }
 
 // BuildContainer constructs all components. Side-effects limited to asset loading.
@@ -45,14 +59,44 @@ func BuildContainer(cfg *config.Config, logger *slog.Logger, width, height int,
 	if img, err := assets.FishingTargetImage(); err == nil {
 		c.TargetImg = img
 	}
+	prior, err := capture.LoadSpatialPrior(priorPath)
+	if err != nil && logger != nil {
+		logger.Warn("spatial prior load failed; starting empty", "error", err)
+	}
+	c.Prior = prior
+	negatives, err := capture.LoadNegativeLibrary(negativesDir)
+	if err != nil && logger != nil {
+		logger.Warn("negative templates partly loaded", "error", err)
+	}
+	c.Negatives = negatives
 	c.FSM = fishing.NewFSM(logger, cfg, fishing.ActionCallbacks{
 		PressKey:   action.PressKey,
 		MoveCursor: action.MoveCursor,
 		ClickRight: action.ClickRight,
 		ParseVK:    action.ParseVK,
-	}, func(cfg *config.Config, l *slog.Logger) fishing.BiteDetectorContract {
-		return fishing.NewBiteDetector(cfg, l)
-	})
+	}, fishing.ConfiguredDetector)
+	if cfg.BiteTrace != "" {
+		trace, err := fishing.OpenTrace(cfg.BiteTrace)
+		if err != nil {
+			if logger != nil {
+				logger.Warn("bite trace disabled", "path", cfg.BiteTrace, "error", err)
+			}
+		} else {
+			c.Trace = trace
+			c.FSM.SetTrace(trace)
+		}
+	}
+	if cfg.BiteRecord != "" {
+		rec, err := tuning.NewSequenceRecorder(cfg.BiteRecord, logger)
+		if err != nil {
+			if logger != nil {
+				logger.Warn("bite recording disabled", "path", cfg.BiteRecord, "error", err)
+			}
+		} else {
+			c.Recorder = rec
+			c.FSM.SetRecorder(rec)
+		}
+	}
 	// View
 	c.RootView = view.NewRootView(cfg, cfgPath, logger)
 	// UI built externally after window list retrieval.
@@ -0,0 +1,114 @@
+// Command bitetrain fits a bite classifier to recorded bite traces. Every
+// trace (see Config.BiteTrace) needs a labels file next to it, named like the
+// trace with the extension replaced by ".labels.json", that lists the true
+// bite times of its casts (see tuning.LoadLabels). The command trains a
+// logistic regression on the frames of most labelled casts, reports how well
+// it separates bite frames on the casts held out and writes the model as JSON
+// for the "model" bite detector.
+//
+//	bitetrain -config pixle_bot_config.json -write session1.jsonl session2.csv
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/soocke/pixel-bot-go/config"
+	"github.com/soocke/pixel-bot-go/domain/fishing"
+	"github.com/soocke/pixel-bot-go/domain/tuning"
+)
+
+func main() {
+	cfgPath := flag.String("config", "pixle_bot_config.json", "config naming the model file")
+	out := flag.String("out", "", "model file to write; defaults to the config's bite_model")
+	early := flag.Duration("early", tuning.DefaultLabelWindow.Early, "how early before a labelled bite a frame counts as a bite frame")
+	late := flag.Duration("late", tuning.DefaultLabelWindow.Late, "how late after a labelled bite a frame counts as a bite frame")
+	iter := flag.Int("iter", tuning.DefaultTrainOptions.Iterations, "gradient descent iterations")
+	rate := flag.Float64("rate", tuning.DefaultTrainOptions.LearningRate, "gradient descent step size")
+	l2 := flag.Float64("l2", tuning.DefaultTrainOptions.L2, "weight decay")
+	holdout := flag.Float64("holdout", 0.2, "share of the labelled casts held out of training to measure the model")
+	write := flag.Bool("write", false, "select the model bite detector in -config")
+	flag.Parse()
+	if flag.NArg() == 0 {
+		fmt.Fprintln(os.Stderr, "usage: bitetrain [flags] trace...")
+		flag.PrintDefaults()
+		os.Exit(2)
+	}
+	opt := tuning.TrainOptions{Iterations: *iter, LearningRate: *rate, L2: *l2}
+	if *holdout < 0 || *holdout >= 1 {
+		fmt.Fprintln(os.Stderr, "bitetrain: -holdout must be in [0, 1)")
+		os.Exit(2)
+	}
+	if err := run(flag.Args(), *cfgPath, *out, tuning.Window{Early: *early, Late: *late}, opt, *holdout, *write); err != nil {
+		fmt.Fprintln(os.Stderr, "bitetrain:", err)
+		os.Exit(1)
+	}
+}
+
+func run(traces []string, cfgPath, out string, win tuning.Window, opt tuning.TrainOptions, holdout float64, write bool) error {
+	cfg, err := config.Load(cfgPath)
+	if err != nil {
+		return fmt.Errorf("load config: %w", err)
+	}
+	if out == "" {
+		out = cfg.BiteModel
+	}
+	var samples, held []tuning.Sample
+	var heldCasts int
+	for _, path := range traces {
+		casts, err := fishing.ReadTrace(path)
+		if err != nil {
+			return err
+		}
+		labels, err := tuning.LoadLabels(tuning.LabelsPath(path))
+		if err != nil {
+			return fmt.Errorf("labels of %s: %w", path, err)
+		}
+		train, test := tuning.SplitHoldout(casts, labels, holdout)
+		s := tuning.LabelCasts(train, labels, win)
+		h := tuning.LabelCasts(test, labels, win)
+		fmt.Printf("%s: %d casts, %d labelled, %d held out, %d training frames\n", path, len(casts), len(labels), len(test), len(s))
+		samples = append(samples, s...)
+		held = append(held, h...)
+		heldCasts += len(test)
+	}
+	model, m, err := tuning.TrainLogistic(samples, opt)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("\nthreshold %.2f\n", model.Threshold)
+	report("training", m)
+	if heldCasts > 0 {
+		report(fmt.Sprintf("held out (%d casts)", heldCasts), tuning.EvaluateModel(model, held))
+	} else {
+		fmt.Println("no casts held out; the training metrics are optimistic")
+	}
+	fmt.Println()
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "feature\tweight\tmean\tscale\t")
+	for i, name := range model.Features {
+		fmt.Fprintf(tw, "%s\t%+.3f\t%.3f\t%.3f\t\n", name, model.Weights[i], model.Mean[i], model.Scale[i])
+	}
+	fmt.Fprintf(tw, "bias\t%+.3f\t\t\t\n", model.Bias)
+	tw.Flush()
+
+	if err := model.Save(out); err != nil {
+		return err
+	}
+	fmt.Println("\nmodel written to", out)
+	if write {
+		cfg.BiteDetector, cfg.BiteModel = config.BiteDetectorModel, out
+		if err := cfg.Save(cfgPath); err != nil {
+			return fmt.Errorf("save config: %w", err)
+		}
+		fmt.Println("bite detector", config.BiteDetectorModel, "selected in", cfgPath)
+	}
+	return nil
+}
+
+func report(name string, m tuning.FrameMetrics) {
+	fmt.Printf("%s: precision %.3f, recall %.3f, F1 %.3f (TP %d, FP %d, FN %d, TN %d)\n",
+		name, m.Precision, m.Recall, m.F1, m.TP, m.FP, m.FN, m.TN)
+}
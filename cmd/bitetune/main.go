@@ -0,0 +1,135 @@
+// Command bitetune searches bite detector parameters offline. It replays
+// recorded ROI sequences labelled with true bite times (see
+// tuning.LoadSequence) through the bite detector for every parameter set of
+// a grid or random search, reports precision, recall and reaction latency and
+// can store the best set as a named bite preset of a config file.
+//
+//	bitetune -seq recordings -config pixle_bot_config.json -random 500 -preset lake
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"math/rand"
+	"os"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/soocke/pixel-bot-go/config"
+	"github.com/soocke/pixel-bot-go/domain/tuning"
+)
+
+func main() {
+	seqDir := flag.String("seq", "", "directory holding sequence directories (required)")
+	cfgPath := flag.String("config", "pixle_bot_config.json", "config whose bite parameters are the search base")
+	random := flag.Int("random", 0, "number of random parameter sets; 0 runs the grid search")
+	seed := flag.Int64("seed", 1, "random search seed")
+	early := flag.Duration("early", tuning.DefaultWindow.Early, "how early before a labelled bite a trigger still counts")
+	late := flag.Duration("late", tuning.DefaultWindow.Late, "how late after a labelled bite a trigger still counts")
+	top := flag.Int("top", 10, "number of best parameter sets to report")
+	preset := flag.String("preset", "", "store the best parameters in -config as the bite preset with this name")
+	selectPreset := flag.Bool("select", false, "with -preset, also make the stored preset the active bite preset")
+	out := flag.String("out", "", "also write the best parameters as JSON to this file")
+	flag.Parse()
+	if *seqDir == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+	if *selectPreset && *preset == "" {
+		fmt.Fprintln(os.Stderr, "bitetune: -select needs -preset")
+		os.Exit(2)
+	}
+	if err := run(*seqDir, *cfgPath, *random, *seed, tuning.Window{Early: *early, Late: *late}, *top, *preset, *selectPreset, *out); err != nil {
+		fmt.Fprintln(os.Stderr, "bitetune:", err)
+		os.Exit(1)
+	}
+}
+
+func run(seqDir, cfgPath string, random int, seed int64, win tuning.Window, top int, preset string, selectPreset bool, out string) error {
+	preset = strings.ToLower(strings.TrimSpace(preset))
+	if _, builtin := config.BitePresetParams(preset); builtin || preset == config.BitePresetCustom {
+		return fmt.Errorf("preset name %q is reserved", preset)
+	}
+	cfg, err := config.Load(cfgPath)
+	if err != nil {
+		return fmt.Errorf("load config: %w", err)
+	}
+	seqs, err := tuning.LoadSequences(seqDir)
+	if err != nil {
+		return err
+	}
+	frames, bites := 0, 0
+	for _, s := range seqs {
+		frames += len(s.Frames)
+		bites += len(s.Bites)
+	}
+	fmt.Printf("%d sequences, %d frames, %d labelled bites\n", len(seqs), frames, bites)
+
+	var candidates []config.BiteParams
+	if random > 0 {
+		candidates = tuning.Random(cfg.Bite, tuning.DefaultSpace(), random, rand.New(rand.NewSource(seed)))
+	} else {
+		candidates = tuning.Grid(cfg.Bite, tuning.DefaultSpace())
+	}
+	// The current parameters compete too, so -preset never stores a worse set.
+	candidates = append(candidates, cfg.Bite)
+	start := time.Now()
+	results := tuning.Search(seqs, candidates, win)
+	fmt.Printf("evaluated %d parameter sets in %s\n\n", len(results), time.Since(start).Round(time.Millisecond))
+
+	// Search normalises and deduplicates, so the current set is found by its
+	// normalised parameters.
+	base := cfg.Bite.Normalize()
+	i := slices.IndexFunc(results, func(r tuning.Result) bool { return r.Params == base })
+	report(append([]tuning.Result{results[i]}, results[:min(top, len(results))]...))
+
+	best := results[0]
+	if out != "" {
+		raw, err := json.MarshalIndent(best.Params, "", "  ")
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(out, append(raw, '\n'), 0o644); err != nil {
+			return err
+		}
+		fmt.Println("\nbest parameters written to", out)
+	}
+	if preset != "" {
+		if cfg.BitePresets == nil {
+			cfg.BitePresets = make(map[string]config.BiteParams)
+		}
+		cfg.BitePresets[preset] = best.Params
+		if selectPreset {
+			cfg.BitePreset, cfg.Bite = preset, best.Params
+		}
+		if err := cfg.Save(cfgPath); err != nil {
+			return fmt.Errorf("save config: %w", err)
+		}
+		fmt.Println("\nbest parameters stored in", cfgPath, "as bite preset", preset)
+		if !selectPreset {
+			fmt.Println("the active bite preset is still", cfg.BitePreset)
+		}
+	}
+	return nil
+}
+
+// report prints one row per result; the first row is the current config.
+func report(results []tuning.Result) {
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "rank\tF1\tprecision\trecall\tFP\tlatency\tmax\tpixel\tspike\tbase\tbaseline\tstd\tdebounce ms\t")
+	for i, r := range results {
+		rank := fmt.Sprint(i)
+		if i == 0 {
+			rank = "current"
+		}
+		m, p := r.Metrics, r.Params
+		fmt.Fprintf(tw, "%s\t%.3f\t%.3f\t%.3f\t%d\t%s\t%s\t%.0f\t%.3f\t%.3f\t%.1f\t%.2f\t%d\t\n",
+			rank, m.F1, m.Precision, m.Recall, m.FalsePositives,
+			m.MeanLatency.Round(time.Millisecond), m.MaxLatency.Round(time.Millisecond),
+			p.PixelRateThreshold, p.RatioThresholdSpike, p.RatioThresholdBase, p.BaselineDiffThresh, p.StdDevMultiplier, p.DebounceMs)
+	}
+	tw.Flush()
+}
@@ -2,7 +2,12 @@ package config
 
 import (
 	"encoding/json"
+	"fmt"
+	"math"
 	"os"
+	"strconv"
+	"strings"
+	"unicode"
 )
 
 // Config holds runtime configuration for detection and app behavior.
@@ -18,6 +23,12 @@ type Config struct {
 	Refine         bool    `json:"refine"`
 	StopOnScore    float64 `json:"stop_on_score"`
 	ReturnBestEven bool    `json:"return_best_even"`
+	// AutoThreshold replaces Threshold with one learned from template scores
+	// of target-free frames sampled during cooldown.
+	AutoThreshold bool `json:"auto_threshold"`
+	// FalsePositiveRate is the share (0.0001-0.2) of target-free search frames
+	// the learned threshold may accept.
+	FalsePositiveRate float64 `json:"false_positive_rate"`
 
 	// Selection rectangle persistence (Phase2)
 	SelectionX int `json:"selection_x"`
@@ -35,15 +46,357 @@ type Config struct {
 	MaxCastDurationSeconds int `json:"max_cast_duration_seconds"`
 	// CooldownSeconds defines how long to wait after reeling before attempting the next cast.
 	CooldownSeconds int `json:"cooldown_seconds"`
+	// BiteDetector names the bite detector used while monitoring (see
+	// fishing.BiteDetectorNames); unknown names fall back to "motion".
+	BiteDetector string `json:"bite_detector"`
+	// Ensemble configures the "ensemble" bite detector.
+	Ensemble EnsembleConfig `json:"ensemble"`
+	// BitePreset names the bite detector tuning ("calm", "choppy", "night" or
+	// a name in BitePresets); "custom" keeps the values in Bite as configured.
+	BitePreset string `json:"bite_preset"`
+	// BitePresets holds saved bite detector tunings by name, e.g. sets found
+	// by the bitetune command. The built-in names cannot be overridden.
+	BitePresets map[string]BiteParams `json:"bite_presets,omitempty"`
+	// Bite holds the bite detector tuning. Validate overwrites it with the
+	// values of BitePreset unless the preset is "custom".
+	Bite BiteParams `json:"bite"`
+	// BiteTrace is a file that records the bite detector features of every
+	// monitored frame and each cast's outcome for offline tuning; a ".csv"
+	// extension writes CSV, anything else JSON lines. Empty disables tracing.
+	// Read at startup.
+	BiteTrace string `json:"bite_trace"`
+	// BiteRecord is a directory that receives the ROI frames of every
+	// monitored cast as a sequence for cmd/bitetune. Empty disables
+	// recording. Read at startup.
+	BiteRecord string `json:"bite_record"`
+	// BiteModel is the JSON model file of the "model" bite detector, written
+	// by cmd/bitetrain.
+	BiteModel string `json:"bite_model"`
+
+	// Detector names the target detector used while searching (see
+	// capture.DetectorNames); unknown names fall back to "ncc".
+	Detector string `json:"detector"`
+	// MatchMode selects what template matching compares: "luma" (grayscale
+	// NCC) or "gradient" (edge orientations, robust at dusk/night).
+	MatchMode string `json:"match_mode"`
+	// BlobColors are the HSV ranges of the bobber's feathers used by the "hsv"
+	// detector; a pixel matching any range counts as bobber colour.
+	BlobColors []HSVRange `json:"blob_colors"`
+	// BlobMinArea/BlobMaxArea bound the pixel area of accepted colour blobs.
+	BlobMinArea int `json:"blob_min_area"`
+	BlobMaxArea int `json:"blob_max_area"`
+	// KeypointMinInliers is the number of geometrically consistent keypoint
+	// matches the "orb" detector requires to report the target.
+	KeypointMinInliers int `json:"keypoint_min_inliers"`
+
+	// Preprocess is the filter chain applied to search frames and bite ROIs
+	// before detection, in order (e.g. blur then CLAHE). Empty disables it.
+	Preprocess []FilterStage `json:"preprocess"`
 
 	// AnalysisScale optionally downsizes frames before expensive template matching.
 	// Range (0.2 - 1.0]. 1.0 means disabled. Smaller values reduce CPU at the cost of precision.
 	AnalysisScale float64 `json:"analysis_scale"`
 
+	// SplashSearch selects how pre/post cast frame differencing assists the search:
+	// "off" (template search only), "restrict" (limit template search to the changed
+	// region first) or "acquire" (use the changed region's centre as the target).
+	SplashSearch string `json:"splash_search"`
+	// SplashSettleMs is the delay after a cast before the post-cast frame is diffed.
+	SplashSettleMs int `json:"splash_settle_ms"`
+	// SpatialPrior searches the region where reel-confirmed bobbers usually land
+	// before falling back to the full selection.
+	SpatialPrior bool `json:"spatial_prior"`
+	// AdaptiveScale narrows MinScale..MaxScale around recently winning scales and
+	// widens back to the full range after consecutive missed search cycles.
+	AdaptiveScale bool `json:"adaptive_scale"`
+	// AdaptiveTemplate blends reel-confirmed detections into a running
+	// template that follows the lighting of the current fishing spot (opt-in).
+	AdaptiveTemplate bool `json:"adaptive_template"`
+	// AdaptiveRate is the weight (0.01-0.5) of each confirmed patch.
+	AdaptiveRate float64 `json:"adaptive_rate"`
+	// AdaptiveMinScore is the minimum correlation between a confirmed patch
+	// and the current template for the patch to be blended in.
+	AdaptiveMinScore float64 `json:"adaptive_min_score"`
+	// Profile names the fishing spot setup; learned per-spot data such as the
+	// adaptive template is stored under this name.
+	Profile string `json:"profile"`
+
 	// DarkMode persists user preference for dark theme across sessions.
 	DarkMode bool `json:"dark_mode"`
 }
 
+// Splash search modes accepted by Config.SplashSearch.
+const (
+	SplashSearchOff      = "off"
+	SplashSearchRestrict = "restrict"
+	SplashSearchAcquire  = "acquire"
+)
+
+// Target detector names accepted by Config.Detector.
+const (
+	DetectorNCC      = "ncc" // multi-scale template matching (default)
+	DetectorHSV      = "hsv" // colour blob segmentation
+	DetectorKeypoint = "orb" // FAST corners with rotation-invariant binary descriptors
+)
+
+// Preprocessing filter names accepted by FilterStage.Filter.
+const (
+	FilterBlur     = "blur"     // gaussian blur, Sigma in pixels
+	FilterEqualize = "equalize" // global histogram equalisation of luma
+	FilterCLAHE    = "clahe"    // contrast limited adaptive equalisation, ClipLimit and Tiles
+	FilterGamma    = "gamma"    // power curve per channel, Gamma (<1 brightens)
+	FilterChannel  = "channel"  // replace RGB by one channel: r, g, b or luma
+)
+
+// FilterStage is one step of the preprocessing chain. Only the parameters of
+// the named filter are used.
+type FilterStage struct {
+	Filter    string  `json:"filter"`
+	Sigma     float64 `json:"sigma,omitempty"`
+	ClipLimit float64 `json:"clip_limit,omitempty"`
+	Tiles     int     `json:"tiles,omitempty"`
+	Gamma     float64 `json:"gamma,omitempty"`
+	Channel   string  `json:"channel,omitempty"`
+}
+
+// Bite detector names accepted by Config.BiteDetector.
+const (
+	BiteDetectorMotion       = "motion"       // frame differencing over the whole ROI (default)
+	BiteDetectorDisplacement = "displacement" // vertical plunge of the bobber's centroid
+	BiteDetectorSplash       = "splash"       // onset of white foam around the bobber
+	BiteDetectorPeriodic     = "periodic"     // break in the bobber's learned idle rhythm
+	BiteDetectorSSIM         = "ssim"         // structural change, ignoring uniform brightness shifts
+	BiteDetectorModel        = "model"        // trained classifier loaded from Config.BiteModel
+	BiteDetectorEnsemble     = "ensemble"     // votes of several detectors, see EnsembleConfig
+)
+
+// DefaultBiteModel is the model file used when Config.BiteModel is empty.
+const DefaultBiteModel = "pixel_bot_bite_model.json"
+
+// Ensemble voting strategies accepted by EnsembleConfig.Strategy.
+const (
+	EnsembleAny      = "any"      // any member fires
+	EnsembleMajority = "majority" // more than half of the members fire on the same frame
+	EnsembleWeighted = "weighted" // the weights of the firing members reach Threshold of the total
+	EnsembleWindow   = "window"   // MinVotes members fire within WindowFrames frames
+)
+
+// EnsembleConfig configures the ensemble bite detector, which runs several
+// bite detectors on the same ROI frames and combines their votes.
+type EnsembleConfig struct {
+	// Members are the bite detector names to combine.
+	Members []string `json:"members"`
+	// Weights are the member weights for the "weighted" strategy, in member
+	// order; missing weights are 1.
+	Weights  []float64 `json:"weights"`
+	Strategy string    `json:"strategy"`
+	// Threshold is the share (0-1] of the total weight needed by "weighted".
+	Threshold float64 `json:"threshold"`
+	// WindowFrames and MinVotes configure "window".
+	WindowFrames int `json:"window_frames"`
+	MinVotes     int `json:"min_votes"`
+}
+
+// DefaultEnsemble combines all single detectors and fires when two of them
+// agree within five frames.
+func DefaultEnsemble() EnsembleConfig {
+	return EnsembleConfig{
+		Members:      []string{BiteDetectorMotion, BiteDetectorDisplacement, BiteDetectorSplash},
+		Weights:      []float64{1, 1, 1},
+		Strategy:     EnsembleWindow,
+		Threshold:    0.5,
+		WindowFrames: 5,
+		MinVotes:     2,
+	}
+}
+
+// Normalize returns a copy with fresh slices, member names lowercased,
+// the ensemble itself and duplicates removed, one non-negative weight per
+// member and all settings clamped. An empty member list selects the
+// defaults.
+func (e EnsembleConfig) Normalize() EnsembleConfig {
+	def := DefaultEnsemble()
+	var members []string
+	var weights []float64
+	seen := map[string]bool{}
+	for i, m := range e.Members {
+		m = strings.ToLower(strings.TrimSpace(m))
+		if m == "" || m == BiteDetectorEnsemble || seen[m] {
+			continue
+		}
+		seen[m] = true
+		wt := 1.0
+		if i < len(e.Weights) {
+			wt = math.Max(0, e.Weights[i])
+		}
+		members = append(members, m)
+		weights = append(weights, wt)
+	}
+	if len(members) == 0 {
+		members, weights = def.Members, def.Weights
+	}
+	e.Members, e.Weights = members, weights
+	e.Strategy = strings.ToLower(strings.TrimSpace(e.Strategy))
+	switch e.Strategy {
+	case EnsembleAny, EnsembleMajority, EnsembleWeighted, EnsembleWindow:
+	default:
+		e.Strategy = def.Strategy
+	}
+	if e.Threshold <= 0 {
+		e.Threshold = def.Threshold
+	}
+	e.Threshold = math.Min(1, e.Threshold)
+	if e.WindowFrames <= 0 {
+		e.WindowFrames = def.WindowFrames
+	}
+	e.WindowFrames = min(50, e.WindowFrames)
+	if e.MinVotes <= 0 {
+		e.MinVotes = def.MinVotes
+	}
+	e.MinVotes = min(len(e.Members), e.MinVotes)
+	return e
+}
+
+// Bite detector presets accepted by Config.BitePreset.
+const (
+	BitePresetCalm   = "calm"   // still water, daylight (default)
+	BitePresetChoppy = "choppy" // waves and rain: higher thresholds, 50 ms debounce
+	BitePresetNight  = "night"  // dark, low-contrast water: lower pixel thresholds
+	BitePresetCustom = "custom" // use Config.Bite as configured
+)
+
+// BiteParams tunes the bite detector. Durations are in milliseconds so the
+// detector behaves the same at any capture rate. Changes are in 8-bit luma
+// levels (per second for rates); ratios are shares of ROI pixels.
+type BiteParams struct {
+	// WindowMs is the time span of recent change rates used for the spike
+	// statistics.
+	WindowMs int `json:"window_ms"`
+	// MinStatsMs is the span of change rates required before spike detection
+	// starts; earlier frames only trigger on big changes.
+	MinStatsMs int `json:"min_stats_ms"`
+	// PixelRateThreshold is the per-pixel change rate (levels per second)
+	// that counts a pixel as changed.
+	PixelRateThreshold float64 `json:"pixel_rate_threshold"`
+	// RatioThresholdSpike is the share of changed pixels a spike needs.
+	RatioThresholdSpike float64 `json:"ratio_threshold_spike"`
+	// RatioThresholdBase is the share of changed pixels a baseline jump needs.
+	RatioThresholdBase float64 `json:"ratio_threshold_base"`
+	// BaselineDiffThresh is the mean difference to the slow background that
+	// counts as a baseline jump.
+	BaselineDiffThresh float64 `json:"baseline_diff_thresh"`
+	// StdDevMultiplier sets how many standard deviations above the window
+	// mean a change rate must be to count as a spike.
+	StdDevMultiplier float64 `json:"std_dev_multiplier"`
+	// BigImmediateRatio and BigImmediateRate (levels per second) trigger at
+	// once, before enough statistics exist, on a large change.
+	BigImmediateRatio float64 `json:"big_immediate_ratio"`
+	BigImmediateRate  float64 `json:"big_immediate_rate"`
+	// EMATimeConstantMs is the time constant of the slow background: it
+	// covers about 63% of a lasting change within this time.
+	EMATimeConstantMs int `json:"ema_time_constant_ms"`
+	// DebounceMs is how long candidate frames must persist before they count
+	// as a bite; 0 triggers on the first candidate frame.
+	DebounceMs int `json:"debounce_ms"`
+}
+
+// BitePresetParams returns the bite detector tuning of a built-in preset. ok
+// is false for "custom" and other names.
+func BitePresetParams(name string) (p BiteParams, ok bool) {
+	switch name {
+	case BitePresetCalm:
+		return BiteParams{
+			WindowMs: 1000, MinStatsMs: 250, PixelRateThreshold: 200,
+			RatioThresholdSpike: 0.18, RatioThresholdBase: 0.12, BaselineDiffThresh: 14,
+			StdDevMultiplier: 2.0, BigImmediateRatio: 0.20, BigImmediateRate: 240,
+			EMATimeConstantMs: 1640, DebounceMs: 0,
+		}, true
+	case BitePresetChoppy:
+		return BiteParams{
+			WindowMs: 1500, MinStatsMs: 400, PixelRateThreshold: 320,
+			RatioThresholdSpike: 0.25, RatioThresholdBase: 0.18, BaselineDiffThresh: 20,
+			StdDevMultiplier: 3.0, BigImmediateRatio: 0.30, BigImmediateRate: 360,
+			EMATimeConstantMs: 800, DebounceMs: 50,
+		}, true
+	case BitePresetNight:
+		return BiteParams{
+			WindowMs: 1000, MinStatsMs: 250, PixelRateThreshold: 120,
+			RatioThresholdSpike: 0.15, RatioThresholdBase: 0.10, BaselineDiffThresh: 9,
+			StdDevMultiplier: 2.0, BigImmediateRatio: 0.18, BigImmediateRate: 160,
+			EMATimeConstantMs: 1640, DebounceMs: 0,
+		}, true
+	}
+	return BiteParams{}, false
+}
+
+// Normalize fills zero fields (except DebounceMs, where zero is valid) from
+// the calm preset and clamps all values to usable ranges.
+func (p BiteParams) Normalize() BiteParams {
+	def, _ := BitePresetParams(BitePresetCalm)
+	if p.WindowMs <= 0 {
+		p.WindowMs = def.WindowMs
+	}
+	p.WindowMs = max(250, min(6000, p.WindowMs))
+	if p.MinStatsMs <= 0 {
+		p.MinStatsMs = def.MinStatsMs
+	}
+	p.MinStatsMs = max(100, min(p.WindowMs, p.MinStatsMs))
+	ratio := func(v, d float64) float64 {
+		if v <= 0 {
+			v = d
+		}
+		return math.Max(0.01, math.Min(1, v))
+	}
+	p.RatioThresholdSpike = ratio(p.RatioThresholdSpike, def.RatioThresholdSpike)
+	p.RatioThresholdBase = ratio(p.RatioThresholdBase, def.RatioThresholdBase)
+	p.BigImmediateRatio = ratio(p.BigImmediateRatio, def.BigImmediateRatio)
+	level := func(v, d, hi float64) float64 {
+		if v <= 0 {
+			v = d
+		}
+		return math.Max(1, math.Min(hi, v))
+	}
+	p.BaselineDiffThresh = level(p.BaselineDiffThresh, def.BaselineDiffThresh, 128)
+	p.PixelRateThreshold = level(p.PixelRateThreshold, def.PixelRateThreshold, 2560)
+	p.BigImmediateRate = level(p.BigImmediateRate, def.BigImmediateRate, 2560)
+	if p.StdDevMultiplier <= 0 {
+		p.StdDevMultiplier = def.StdDevMultiplier
+	}
+	p.StdDevMultiplier = math.Max(0.5, math.Min(10, p.StdDevMultiplier))
+	if p.EMATimeConstantMs <= 0 {
+		p.EMATimeConstantMs = def.EMATimeConstantMs
+	}
+	p.EMATimeConstantMs = max(100, min(60000, p.EMATimeConstantMs))
+	p.DebounceMs = max(0, min(1000, p.DebounceMs))
+	return p
+}
+
+// DefaultProfile is the profile name used when none is configured.
+const DefaultProfile = "default"
+
+// Template matching modes accepted by Config.MatchMode.
+const (
+	MatchModeLuma     = "luma"
+	MatchModeGradient = "gradient"
+)
+
+// HSVRange is an inclusive colour range. Hue is in degrees [0, 360); a range
+// with HMin > HMax wraps through 0 (e.g. 340-20 for red). Saturation and value
+// are in [0, 1].
+type HSVRange struct {
+	HMin float64 `json:"h_min"`
+	HMax float64 `json:"h_max"`
+	SMin float64 `json:"s_min"`
+	SMax float64 `json:"s_max"`
+	VMin float64 `json:"v_min"`
+	VMax float64 `json:"v_max"`
+}
+
+// DefaultBlobColors matches the red feathers of the default bobber.
+func DefaultBlobColors() []HSVRange {
+	return []HSVRange{{HMin: 340, HMax: 20, SMin: 0.45, SMax: 1, VMin: 0.35, VMax: 1}}
+}
+
 // Accessor helpers to satisfy fishing.ConfigLite without exposing struct embedding.
 
 // DefaultConfig returns a Config populated with standard defaults.
@@ -58,6 +411,8 @@ func DefaultConfig() *Config {
 		Refine:                 true,
 		StopOnScore:            0.80, // from pixle_bot_config.json
 		ReturnBestEven:         true,
+		AutoThreshold:          false,
+		FalsePositiveRate:      0.01,
 		SelectionX:             0, // persisted selection defaults
 		SelectionY:             0,
 		SelectionW:             0,
@@ -66,7 +421,26 @@ func DefaultConfig() *Config {
 		ROISizePx:              80,
 		MaxCastDurationSeconds: 16,
 		CooldownSeconds:        8, // from pixle_bot_config.json
+		BiteDetector:           BiteDetectorMotion,
+		BiteModel:              DefaultBiteModel,
+		Ensemble:               DefaultEnsemble(),
+		BitePreset:             BitePresetCalm,
+		Bite:                   calmBite(),
+		Detector:               DetectorNCC,
+		MatchMode:              MatchModeLuma,
+		BlobColors:             DefaultBlobColors(),
+		BlobMinArea:            20,
+		BlobMaxArea:            2500,
+		KeypointMinInliers:     4,
 		AnalysisScale:          1.0,
+		SplashSearch:           SplashSearchOff,
+		SplashSettleMs:         1500,
+		SpatialPrior:           true,
+		AdaptiveScale:          true,
+		AdaptiveTemplate:       false,
+		AdaptiveRate:           0.1,
+		AdaptiveMinScore:       0.85,
+		Profile:                DefaultProfile,
 		DarkMode:               true, // from pixle_bot_config.json
 	}
 }
@@ -94,6 +468,10 @@ func (c *Config) Validate() error {
 	if c.StopOnScore < 0 || c.StopOnScore > 1 {
 		c.StopOnScore = 0.95
 	}
+	if c.FalsePositiveRate <= 0 {
+		c.FalsePositiveRate = 0.01
+	}
+	c.FalsePositiveRate = math.Max(0.0001, math.Min(0.2, c.FalsePositiveRate))
 	if c.ReelKey == "" {
 		c.ReelKey = "F3"
 	}
@@ -119,6 +497,69 @@ func (c *Config) Validate() error {
 	if c.CooldownSeconds > 60 { // more than a minute likely unnecessary
 		c.CooldownSeconds = 60
 	}
+	c.BiteDetector = strings.ToLower(strings.TrimSpace(c.BiteDetector))
+	if c.BiteDetector == "" {
+		c.BiteDetector = BiteDetectorMotion
+	}
+	c.BiteModel = strings.TrimSpace(c.BiteModel)
+	if c.BiteModel == "" {
+		c.BiteModel = DefaultBiteModel
+	}
+	c.Ensemble = c.Ensemble.Normalize()
+	c.BitePreset = strings.ToLower(strings.TrimSpace(c.BitePreset))
+	if c.BitePreset == "" {
+		c.BitePreset = BitePresetCalm
+	}
+	presets := make(map[string]BiteParams, len(c.BitePresets))
+	for name, p := range c.BitePresets {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if _, builtin := BitePresetParams(name); name != "" && name != BitePresetCustom && !builtin {
+			presets[name] = p.Normalize()
+		}
+	}
+	c.BitePresets = presets
+	if len(presets) == 0 {
+		c.BitePresets = nil
+	}
+	if p, ok := BitePresetParams(c.BitePreset); ok {
+		c.Bite = p
+	} else if p, ok := c.BitePresets[c.BitePreset]; ok {
+		c.Bite = p
+	} else {
+		c.BitePreset = BitePresetCustom
+		c.Bite = c.Bite.Normalize()
+	}
+
+	if c.Detector == "" {
+		c.Detector = DetectorNCC
+	}
+	switch c.MatchMode {
+	case MatchModeLuma, MatchModeGradient:
+	default:
+		c.MatchMode = MatchModeLuma
+	}
+	if len(c.BlobColors) == 0 {
+		c.BlobColors = DefaultBlobColors()
+	}
+	// Normalize into a fresh slice; shallow config copies share the original.
+	colors := make([]HSVRange, len(c.BlobColors))
+	for i, r := range c.BlobColors {
+		r.HMin, r.HMax = math.Mod(math.Mod(r.HMin, 360)+360, 360), math.Mod(math.Mod(r.HMax, 360)+360, 360)
+		r.SMin, r.SMax = clamp01(r.SMin), clamp01(r.SMax)
+		r.VMin, r.VMax = clamp01(r.VMin), clamp01(r.VMax)
+		colors[i] = r
+	}
+	c.BlobColors = colors
+	c.Preprocess = NormalizeStages(c.Preprocess)
+	if c.BlobMinArea < 4 {
+		c.BlobMinArea = 4
+	}
+	if c.BlobMaxArea < c.BlobMinArea {
+		c.BlobMaxArea = c.BlobMinArea * 100
+	}
+	if c.KeypointMinInliers < 3 { // two points always fit a similarity
+		c.KeypointMinInliers = 3
+	}
 
 	// AnalysisScale validation
 	if c.AnalysisScale <= 0 {
@@ -131,9 +572,164 @@ func (c *Config) Validate() error {
 		c.AnalysisScale = 1.0
 	}
 
+	// Splash search validation
+	switch c.SplashSearch {
+	case SplashSearchOff, SplashSearchRestrict, SplashSearchAcquire:
+	default:
+		c.SplashSearch = SplashSearchOff
+	}
+	if c.SplashSettleMs <= 0 {
+		c.SplashSettleMs = 1500
+	}
+	if c.SplashSettleMs < 200 {
+		c.SplashSettleMs = 200
+	}
+	if c.SplashSettleMs > 5000 { // search times out after 5s; later diffs are useless
+		c.SplashSettleMs = 5000
+	}
+
+	// Adaptive template validation
+	if c.AdaptiveRate <= 0 {
+		c.AdaptiveRate = 0.1
+	}
+	c.AdaptiveRate = math.Max(0.01, math.Min(0.5, c.AdaptiveRate))
+	if c.AdaptiveMinScore <= 0 || c.AdaptiveMinScore > 1 {
+		c.AdaptiveMinScore = 0.85
+	}
+	c.Profile = sanitizeProfile(c.Profile)
+
 	return nil
 }
 
+// NormalizeStages returns a fresh copy of stages with filter names lowercased,
+// unknown filters dropped and parameters clamped to usable ranges.
+func NormalizeStages(stages []FilterStage) []FilterStage {
+	var out []FilterStage
+	for _, st := range stages {
+		st.Filter = strings.ToLower(strings.TrimSpace(st.Filter))
+		switch st.Filter {
+		case FilterBlur:
+			if st.Sigma <= 0 {
+				st.Sigma = 1
+			}
+			st.Sigma = math.Max(0.3, math.Min(5, st.Sigma))
+		case FilterEqualize:
+		case FilterCLAHE:
+			if st.ClipLimit <= 0 {
+				st.ClipLimit = 2
+			}
+			st.ClipLimit = math.Max(1, math.Min(10, st.ClipLimit))
+			if st.Tiles <= 0 {
+				st.Tiles = 8
+			}
+			st.Tiles = max(2, min(16, st.Tiles))
+		case FilterGamma:
+			if st.Gamma <= 0 {
+				st.Gamma = 1
+			}
+			st.Gamma = math.Max(0.1, math.Min(5, st.Gamma))
+		case FilterChannel:
+			st.Channel = strings.ToLower(strings.TrimSpace(st.Channel))
+			switch st.Channel {
+			case "r", "g", "b", "luma":
+			default:
+				st.Channel = "luma"
+			}
+		default:
+			continue
+		}
+		out = append(out, st)
+	}
+	return out
+}
+
+// FormatStages renders stages in the compact form read by ParseStages, e.g.
+// "blur:1.5, clahe:2, channel:r".
+func FormatStages(stages []FilterStage) string {
+	parts := make([]string, 0, len(stages))
+	for _, st := range stages {
+		switch st.Filter {
+		case FilterBlur:
+			parts = append(parts, st.Filter+":"+strconv.FormatFloat(st.Sigma, 'g', 3, 64))
+		case FilterCLAHE:
+			parts = append(parts, st.Filter+":"+strconv.FormatFloat(st.ClipLimit, 'g', 3, 64))
+		case FilterGamma:
+			parts = append(parts, st.Filter+":"+strconv.FormatFloat(st.Gamma, 'g', 3, 64))
+		case FilterChannel:
+			parts = append(parts, st.Filter+":"+st.Channel)
+		default:
+			parts = append(parts, st.Filter)
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// ParseStages reads a comma separated filter chain of name[:parameter]
+// entries. The parameter is the blur sigma, CLAHE clip limit, gamma or
+// channel name. An empty string yields no stages.
+func ParseStages(s string) ([]FilterStage, error) {
+	var stages []FilterStage
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		name, param, hasParam := strings.Cut(part, ":")
+		st := FilterStage{Filter: strings.ToLower(strings.TrimSpace(name))}
+		param = strings.TrimSpace(param)
+		var num float64
+		if hasParam && st.Filter != FilterChannel {
+			v, err := strconv.ParseFloat(param, 64)
+			if err != nil {
+				return nil, fmt.Errorf("filter %q: bad parameter %q", st.Filter, param)
+			}
+			num = v
+		}
+		switch st.Filter {
+		case FilterBlur:
+			st.Sigma = num
+		case FilterEqualize:
+		case FilterCLAHE:
+			st.ClipLimit = num
+		case FilterGamma:
+			st.Gamma = num
+		case FilterChannel:
+			st.Channel = param
+		default:
+			return nil, fmt.Errorf("unknown filter %q", st.Filter)
+		}
+		stages = append(stages, st)
+	}
+	return NormalizeStages(stages), nil
+}
+
+// sanitizeProfile keeps letters, digits, '-' and '_' so the profile name is
+// safe to use in file names.
+func sanitizeProfile(name string) string {
+	out := make([]rune, 0, len(name))
+	for _, r := range strings.TrimSpace(name) {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r) || r == '-' || r == '_':
+			out = append(out, r)
+		case r == ' ':
+			out = append(out, '_')
+		}
+	}
+	if len(out) == 0 {
+		return DefaultProfile
+	}
+	return string(out)
+}
+
+func calmBite() BiteParams {
+	p, _ := BitePresetParams(BitePresetCalm)
+	return p
+}
+
+func clamp01(v float64) float64 {
+	return math.Max(0, math.Min(1, v))
+}
+
 // Load attempts to read configuration from the given JSON file path. If the file does not
 // exist it returns DefaultConfig(). On JSON error it returns defaults with the error.
 func Load(path string) (*Config, error) {
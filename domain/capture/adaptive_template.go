@@ -0,0 +1,231 @@
+package capture
+
+import (
+	"errors"
+	"image"
+	"image/png"
+	"math"
+	"os"
+	"sync"
+)
+
+// adaptDriftLimit is the minimum luma NCC between the adapted and the base
+// template; updates that would push the template further away are refused.
+const adaptDriftLimit = 0.7
+
+var (
+	// ErrAdaptLowScore reports a patch that does not resemble the current template.
+	ErrAdaptLowScore = errors.New("adaptive template: patch score below minimum")
+	// ErrAdaptDrift reports an update refused by the drift guard.
+	ErrAdaptDrift = errors.New("adaptive template: update would drift from base template")
+)
+
+// AdaptiveTemplate is a running template that blends patches of confirmed
+// detections into the base template, so the target keeps matching under the
+// lighting of the current fishing spot. Transparent base pixels stay
+// transparent. Image returns a new image after every accepted update, which
+// keeps identity-keyed template caches valid. Safe for concurrent use.
+type AdaptiveTemplate struct {
+	mu      sync.Mutex
+	base    *image.RGBA
+	acc     []float64 // blended RGB, 3 values per pixel
+	img     *image.RGBA
+	updates int
+}
+
+// NewAdaptiveTemplate starts an adaptive template at base.
+func NewAdaptiveTemplate(base image.Image) *AdaptiveTemplate {
+	a := &AdaptiveTemplate{base: toRGBA(base)}
+	a.resetLocked()
+	return a
+}
+
+// LoadAdaptiveTemplate starts from base and restores the adapted template
+// saved at path. A missing file, or one whose size no longer matches base,
+// yields an unadapted template.
+func LoadAdaptiveTemplate(path string, base image.Image) (*AdaptiveTemplate, error) {
+	a := NewAdaptiveTemplate(base)
+	saved, err := readPNG(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return a, nil
+	}
+	if err != nil {
+		return a, err
+	}
+	if saved.Bounds().Size() != a.base.Bounds().Size() {
+		return a, nil
+	}
+	a.acc = rgbValues(saved)
+	a.img = a.render()
+	a.updates = 1
+	return a, nil
+}
+
+func (a *AdaptiveTemplate) resetLocked() {
+	a.acc = rgbValues(a.base)
+	a.img = a.base
+	a.updates = 0
+}
+
+// Image returns the current template.
+func (a *AdaptiveTemplate) Image() image.Image {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.img
+}
+
+// Updates returns the number of accepted updates since the last reset (1 for
+// a template restored from disk).
+func (a *AdaptiveTemplate) Updates() int {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.updates
+}
+
+// Reset discards all adaptation.
+func (a *AdaptiveTemplate) Reset() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.resetLocked()
+}
+
+// Update blends patch, the frame area of a confirmed detection, into the
+// template with weight rate (0-1]. The patch is resized to the template size.
+// It returns ErrAdaptLowScore when the patch's luma NCC against the current
+// template is below minScore and ErrAdaptDrift when the blended template
+// would correlate with the base template less than adaptDriftLimit.
+func (a *AdaptiveTemplate) Update(patch image.Image, rate, minScore float64) error {
+	if patch == nil || patch.Bounds().Empty() {
+		return errors.New("adaptive template: empty patch")
+	}
+	rate = math.Max(0, math.Min(1, rate))
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	b := a.base.Bounds()
+	p := rgbValues(resampleRGBA(patch, b.Dx(), b.Dy()))
+	mask := a.mask()
+	if s := lumaNCC(p, a.acc, mask); s < minScore {
+		return ErrAdaptLowScore
+	}
+	blend := make([]float64, len(a.acc))
+	for i := range blend {
+		blend[i] = (1-rate)*a.acc[i] + rate*p[i]
+	}
+	if lumaNCC(blend, rgbValues(a.base), mask) < adaptDriftLimit {
+		return ErrAdaptDrift
+	}
+	a.acc = blend
+	a.img = a.render()
+	a.updates++
+	return nil
+}
+
+// Save writes the current template to path as PNG.
+func (a *AdaptiveTemplate) Save(path string) error {
+	a.mu.Lock()
+	img := a.img
+	a.mu.Unlock()
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := png.Encode(f, img); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// mask reports which template pixels are opaque in the base template.
+func (a *AdaptiveTemplate) mask() []bool {
+	m := make([]bool, len(a.base.Pix)/4)
+	for i := range m {
+		m[i] = a.base.Pix[i*4+3] != 0
+	}
+	return m
+}
+
+// render builds an image from acc with the base template's alpha.
+func (a *AdaptiveTemplate) render() *image.RGBA {
+	out := image.NewRGBA(a.base.Bounds())
+	for i := 0; i < len(out.Pix)/4; i++ {
+		alpha := a.base.Pix[i*4+3]
+		if alpha == 0 {
+			continue
+		}
+		for k := 0; k < 3; k++ {
+			out.Pix[i*4+k] = uint8(math.Max(0, math.Min(255, math.Round(a.acc[i*3+k]))))
+		}
+		out.Pix[i*4+3] = alpha
+	}
+	return out
+}
+
+// rgbValues returns the RGB channels of img as float64, 3 values per pixel.
+func rgbValues(img *image.RGBA) []float64 {
+	b := img.Bounds()
+	out := make([]float64, 0, b.Dx()*b.Dy()*3)
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			i := img.PixOffset(x, y)
+			out = append(out, float64(img.Pix[i]), float64(img.Pix[i+1]), float64(img.Pix[i+2]))
+		}
+	}
+	return out
+}
+
+// lumaNCC correlates the luma of two RGB value slices over the masked pixels.
+func lumaNCC(a, b []float64, mask []bool) float64 {
+	var n, sa, sb, saa, sbb, sab float64
+	for i, on := range mask {
+		if !on {
+			continue
+		}
+		la := 0.2126*a[i*3] + 0.7152*a[i*3+1] + 0.0722*a[i*3+2]
+		lb := 0.2126*b[i*3] + 0.7152*b[i*3+1] + 0.0722*b[i*3+2]
+		n++
+		sa += la
+		sb += lb
+		saa += la * la
+		sbb += lb * lb
+		sab += la * lb
+	}
+	if n == 0 {
+		return 0
+	}
+	va, vb := saa-sa*sa/n, sbb-sb*sb/n
+	if va <= 1e-9 || vb <= 1e-9 {
+		return 0
+	}
+	return (sab - sa*sb/n) / math.Sqrt(va*vb)
+}
+
+// resampleRGBA resizes src to w x h with bilinear interpolation.
+func resampleRGBA(src image.Image, w, h int) *image.RGBA {
+	s := toRGBA(src)
+	sw, sh := s.Bounds().Dx(), s.Bounds().Dy()
+	out := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		fy := math.Max(0, math.Min(float64(sh-1), (float64(y)+0.5)*float64(sh)/float64(h)-0.5))
+		y0 := int(fy)
+		y1 := min(y0+1, sh-1)
+		ty := fy - float64(y0)
+		for x := 0; x < w; x++ {
+			fx := math.Max(0, math.Min(float64(sw-1), (float64(x)+0.5)*float64(sw)/float64(w)-0.5))
+			x0 := int(fx)
+			x1 := min(x0+1, sw-1)
+			tx := fx - float64(x0)
+			o := out.PixOffset(x, y)
+			for k := 0; k < 4; k++ {
+				v00 := float64(s.Pix[s.PixOffset(x0, y0)+k])
+				v10 := float64(s.Pix[s.PixOffset(x1, y0)+k])
+				v01 := float64(s.Pix[s.PixOffset(x0, y1)+k])
+				v11 := float64(s.Pix[s.PixOffset(x1, y1)+k])
+				top := v00*(1-tx) + v10*tx
+				bottom := v01*(1-tx) + v11*tx
+				out.Pix[o+k] = uint8(math.Round(top*(1-ty) + bottom*ty))
+			}
+		}
+	}
+	return out
+}
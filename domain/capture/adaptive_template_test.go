@@ -0,0 +1,89 @@
+package capture
+
+import (
+	"errors"
+	"image"
+	"math"
+	"path/filepath"
+	"testing"
+)
+
+// shadedBobber returns a water frame with a bobber at (40, 30) whose left
+// half lies in shadow, as under a tree at the fishing spot.
+func shadedBobber(seed int64) *image.RGBA {
+	frame := waterFrame(80, 60, seed)
+	paintBobber(frame, 40, 30, 10)
+	for y := 19; y <= 41; y++ {
+		for x := 29; x < 40; x++ {
+			i := frame.PixOffset(x, y)
+			for k := 0; k < 3; k++ {
+				frame.Pix[i+k] = uint8(float64(frame.Pix[i+k]) * 0.35)
+			}
+		}
+	}
+	return frame
+}
+
+func TestAdaptiveTemplate_LearnsSpotLighting(t *testing.T) {
+	base := bobberTemplate(10)
+	at := NewAdaptiveTemplate(base)
+	box := image.Rect(30, 20, 51, 41)
+	for seed := int64(1); seed <= 8; seed++ {
+		if err := at.Update(shadedBobber(seed).SubImage(box), 0.3, 0.5); err != nil {
+			t.Fatalf("update %d: %v", seed, err)
+		}
+	}
+	if at.Updates() != 8 {
+		t.Fatalf("updates = %d", at.Updates())
+	}
+	probe := shadedBobber(99)
+	opts := NCCOptions{Threshold: 0.5, Stride: 1}
+	before := MatchTemplateNCC(probe, base, opts)
+	after := MatchTemplateNCC(probe, at.Image(), opts)
+	t.Logf("score base %.3f adapted %.3f", before.Score, after.Score)
+	if after.Score <= before.Score+0.05 {
+		t.Fatalf("adapted score %.3f should clearly beat base %.3f", after.Score, before.Score)
+	}
+	if after.X != 30 || after.Y != 20 {
+		t.Fatalf("adapted match at (%d,%d) want (30,20)", after.X, after.Y)
+	}
+
+	path := filepath.Join(t.TempDir(), "adaptive.png")
+	if err := at.Save(path); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := LoadAdaptiveTemplate(path, base)
+	if err != nil || loaded.Updates() == 0 {
+		t.Fatalf("load: updates %d err %v", loaded.Updates(), err)
+	}
+	if s := MatchTemplateNCC(probe, loaded.Image(), opts).Score; s < after.Score-0.01 {
+		t.Fatalf("reloaded score %.3f want %.3f", s, after.Score)
+	}
+	at.Reset()
+	if at.Image() != image.Image(at.base) || at.Updates() != 0 {
+		t.Fatalf("reset must restore the base template")
+	}
+}
+
+func TestAdaptiveTemplate_Guards(t *testing.T) {
+	base := bobberTemplate(10)
+	at := NewAdaptiveTemplate(base)
+	water := waterFrame(21, 21, 4)
+	if err := at.Update(water, 0.5, 0.5); !errors.Is(err, ErrAdaptLowScore) {
+		t.Fatalf("water patch: err %v, want ErrAdaptLowScore", err)
+	}
+	// An inverted bobber passes a disabled score check but would cancel the
+	// template out; the drift guard must refuse it.
+	inverted := bobberTemplate(10)
+	for i := 0; i < len(inverted.Pix); i += 4 {
+		for k := 0; k < 3; k++ {
+			inverted.Pix[i+k] = 255 - inverted.Pix[i+k]
+		}
+	}
+	if err := at.Update(inverted, 0.5, math.Inf(-1)); !errors.Is(err, ErrAdaptDrift) {
+		t.Fatalf("inverted patch: err %v, want ErrAdaptDrift", err)
+	}
+	if at.Updates() != 0 {
+		t.Fatalf("rejected updates must not change the template")
+	}
+}
@@ -0,0 +1,145 @@
+package capture
+
+import (
+	"context"
+	"image"
+	"math"
+	"sort"
+	"sync"
+
+	"github.com/soocke/pixel-bot-go/config"
+)
+
+const (
+	defaultBackgroundHistory = 200
+	// backgroundMinSamples is the number of sampled frames needed before the
+	// learned distribution sets a threshold.
+	backgroundMinSamples = 20
+	// minAutoThreshold and maxAutoThreshold bound learned thresholds so a very
+	// clean or very cluttered scene cannot disable detection altogether.
+	minAutoThreshold = 0.5
+	maxAutoThreshold = 0.95
+	eulerGamma       = 0.5772156649015329
+)
+
+// BackgroundStats summarises the sampled background scores.
+type BackgroundStats struct {
+	Samples int
+	Mean    float64
+	StdDev  float64
+	Max     float64
+	P95     float64 // empirical 95th percentile
+}
+
+// BackgroundScores collects the best template score of frames known to hold
+// no target (e.g. captured during cooldown after the bobber was reeled in).
+// Each sample is the score a false positive on that frame would have had, so
+// the upper tail of the distribution sets the threshold for a false-positive
+// rate. The most recent samples are kept in a ring buffer. Safe for
+// concurrent use.
+type BackgroundScores struct {
+	mu     sync.Mutex
+	scores []float64
+	next   int
+	size   int
+}
+
+// NewBackgroundScores returns an empty collection keeping historySize scores.
+// Zero selects the default.
+func NewBackgroundScores(historySize int) *BackgroundScores {
+	if historySize <= 0 {
+		historySize = defaultBackgroundHistory
+	}
+	return &BackgroundScores{size: historySize}
+}
+
+// Add records the best score of a target-free frame.
+func (b *BackgroundScores) Add(score float64) {
+	if b == nil || math.IsNaN(score) {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if len(b.scores) < b.size {
+		b.scores = append(b.scores, score)
+		return
+	}
+	b.scores[b.next] = score
+	b.next = (b.next + 1) % b.size
+}
+
+// Reset discards all samples, e.g. after the scene or template changed.
+func (b *BackgroundScores) Reset() {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.scores, b.next = nil, 0
+}
+
+// Stats summarises the recorded scores.
+func (b *BackgroundScores) Stats() BackgroundStats {
+	if b == nil {
+		return BackgroundStats{}
+	}
+	b.mu.Lock()
+	sorted := append([]float64(nil), b.scores...)
+	b.mu.Unlock()
+	if len(sorted) == 0 {
+		return BackgroundStats{}
+	}
+	sort.Float64s(sorted)
+	var sum, sq float64
+	for _, s := range sorted {
+		sum += s
+	}
+	mean := sum / float64(len(sorted))
+	for _, s := range sorted {
+		sq += (s - mean) * (s - mean)
+	}
+	return BackgroundStats{
+		Samples: len(sorted),
+		Mean:    mean,
+		StdDev:  math.Sqrt(sq / float64(len(sorted))),
+		Max:     sorted[len(sorted)-1],
+		P95:     sorted[int(math.Ceil(0.95*float64(len(sorted))))-1],
+	}
+}
+
+// Threshold returns the score that target-free frames exceed with probability
+// fpr, clamped to [minAutoThreshold, maxAutoThreshold]. The per-frame best
+// scores are maxima over many placements, so a Gumbel distribution is fitted
+// to them by moments; this extrapolates beyond the highest sample when fpr is
+// smaller than one over the sample count. ok is false until enough samples
+// were recorded.
+func (b *BackgroundScores) Threshold(fpr float64) (float64, bool) {
+	st := b.Stats()
+	if st.Samples < backgroundMinSamples || fpr <= 0 || fpr >= 1 {
+		return 0, false
+	}
+	beta := st.StdDev * math.Sqrt(6) / math.Pi
+	mu := st.Mean - eulerGamma*beta
+	t := mu - beta*math.Log(-math.Log(1-fpr))
+	return math.Max(minAutoThreshold, math.Min(maxAutoThreshold, t)), true
+}
+
+// BackgroundScore returns the best template score anywhere in frame over the
+// configured scale range, without a threshold or early stop. On a frame
+// without the target it is the score a false positive would have.
+func BackgroundScore(ctx context.Context, frame *image.RGBA, tmpl image.Image, cfg *config.Config) (float64, error) {
+	local := *config.DefaultConfig()
+	if cfg != nil {
+		local = *cfg
+	}
+	local.ReturnBestEven = true
+	local.StopOnScore = 0
+	res, err := DetectTemplateDetailed(ctx, frame, tmpl, &local)
+	if err != nil {
+		return 0, err
+	}
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	return res.Score, nil
+}
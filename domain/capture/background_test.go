@@ -0,0 +1,75 @@
+package capture
+
+import (
+	"context"
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/soocke/pixel-bot-go/config"
+)
+
+func TestBackgroundScores_ThresholdMatchesFalsePositiveRate(t *testing.T) {
+	b := NewBackgroundScores(0)
+	rng := rand.New(rand.NewSource(3))
+	// Gumbel(0.6, 0.03) samples, the shape of per-frame maxima.
+	gumbel := func() float64 { return 0.6 - 0.03*math.Log(-math.Log(rng.Float64())) }
+	for i := 0; i < backgroundMinSamples-1; i++ {
+		b.Add(gumbel())
+	}
+	if _, ok := b.Threshold(0.01); ok {
+		t.Fatalf("threshold must wait for %d samples", backgroundMinSamples)
+	}
+	for i := 0; i < 200; i++ {
+		b.Add(gumbel())
+	}
+	if st := b.Stats(); st.Samples != defaultBackgroundHistory || st.P95 > st.Max || st.Mean >= st.P95 {
+		t.Fatalf("unexpected stats %+v", st)
+	}
+	loose, _ := b.Threshold(0.05)
+	strict, ok := b.Threshold(0.01)
+	if !ok || strict <= loose {
+		t.Fatalf("stricter rate must raise the threshold: 5%%=%.3f 1%%=%.3f", loose, strict)
+	}
+	exceed := 0
+	const trials = 20000
+	for i := 0; i < trials; i++ {
+		if gumbel() >= strict {
+			exceed++
+		}
+	}
+	if rate := float64(exceed) / trials; rate < 0.003 || rate > 0.03 {
+		t.Fatalf("threshold %.3f gives false-positive rate %.4f, want about 0.01", strict, rate)
+	}
+	b.Reset()
+	if st := b.Stats(); st.Samples != 0 {
+		t.Fatalf("reset kept %d samples", st.Samples)
+	}
+}
+
+func TestBackgroundScore_SeparatesTargetFromWater(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Stride = 2
+	tmpl := bobberTemplate(10)
+	b := NewBackgroundScores(0)
+	for seed := int64(1); seed <= backgroundMinSamples; seed++ {
+		s, err := BackgroundScore(context.Background(), waterFrame(160, 120, seed), tmpl, cfg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		b.Add(s)
+	}
+	threshold, ok := b.Threshold(0.01)
+	if !ok {
+		t.Fatalf("expected a threshold")
+	}
+	frame := waterFrame(160, 120, 99)
+	paintBobber(frame, 80, 60, 10)
+	s, err := BackgroundScore(context.Background(), frame, tmpl, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s < threshold {
+		t.Fatalf("target score %.3f below learned threshold %.3f (%+v)", s, threshold, b.Stats())
+	}
+}
@@ -1,6 +1,7 @@
 package capture
 
 import (
+	"context"
 	"errors"
 	"image"
 
@@ -10,8 +11,8 @@ import (
 // DetectTemplateDetailed performs a multi-scale normalized cross-correlation
 // (NCC) template match and returns the full result, including timing and scale
 // counts when DebugTiming is enabled. Transparent template pixels are masked
-// during matching.
-func DetectTemplateDetailed(frame *image.RGBA, tmpl image.Image, cfg *config.Config) (MultiScaleResult, error) {
+// during matching. The search is abandoned with ctx.Err() once ctx is done.
+func DetectTemplateDetailed(ctx context.Context, frame *image.RGBA, tmpl image.Image, cfg *config.Config) (MultiScaleResult, error) {
 	if frame == nil || tmpl == nil {
 		return MultiScaleResult{}, errors.New("detect template error")
 	}
@@ -24,7 +25,7 @@ func DetectTemplateDetailed(frame *image.RGBA, tmpl image.Image, cfg *config.Con
 	if err := local.Validate(); err != nil {
 		return MultiScaleResult{}, err
 	}
-	res := MultiScaleMatch(frame, tmpl, MultiScaleOptions{
+	return MultiScaleMatch(ctx, frame, tmpl, MultiScaleOptions{
 		Scales:    nil,
 		MinScale:  local.MinScale,
 		MaxScale:  local.MaxScale,
@@ -37,14 +38,22 @@ func DetectTemplateDetailed(frame *image.RGBA, tmpl image.Image, cfg *config.Con
 			DebugTiming:    true,
 		},
 		StopOnScore: local.StopOnScore,
+		Mode:        MatchModeFromConfig(local.MatchMode),
 	})
-	return res, nil
+}
+
+// MatchModeFromConfig maps Config.MatchMode onto a MatchMode.
+func MatchModeFromConfig(mode string) MatchMode {
+	if mode == config.MatchModeGradient {
+		return MatchGradient
+	}
+	return MatchLuma
 }
 
 // DetectTemplate is a compatibility helper that returns coordinates and a
 // boolean found flag.
 func DetectTemplate(frame *image.RGBA, tmpl image.Image, cfg *config.Config) (int, int, bool, error) {
-	res, err := DetectTemplateDetailed(frame, tmpl, cfg)
+	res, err := DetectTemplateDetailed(context.Background(), frame, tmpl, cfg)
 	if err != nil {
 		return 0, 0, false, err
 	}
@@ -0,0 +1,152 @@
+package capture
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"math"
+	"sort"
+	"sync"
+
+	"github.com/soocke/pixel-bot-go/config"
+)
+
+// Candidate is a possible target location reported by a TargetDetector.
+// FX/FY refine Bounds.Min to sub-pixel precision (detectors without sub-pixel
+// support set them to Bounds.Min). CX/CY is the target's centre, the centroid
+// for segmentation based detectors; every detector sets it, and the pixel
+// holding it (see Anchor) is the acquisition point handed to the fishing FSM.
+type Candidate struct {
+	Bounds    image.Rectangle
+	FX, FY    float64
+	CX, CY    float64
+	Score     float64 // detector confidence, higher is better
+	Scale     float64 // template scale (0 when not template based)
+	Sharpness float64 // peak sharpness (0 when not reported)
+	Inliers   int     // geometrically consistent feature matches (keypoint detectors)
+}
+
+// Anchor returns the pixel holding the target centre, the point the bot
+// monitors and clicks. CX/CY are continuous coordinates in which pixel (x, y)
+// spans [x, x+1), so the centre of a symmetric target lands on its middle
+// pixel.
+func (c Candidate) Anchor() image.Point {
+	return image.Pt(int(math.Floor(c.CX)), int(math.Floor(c.CY)))
+}
+
+// TargetDetector locates the fishing target in a frame. Detect returns the
+// candidates that pass the detector's own acceptance test, narrowed by lim,
+// ordered best first and ctx.Err() once ctx is done.
+type TargetDetector interface {
+	Name() string
+	Detect(ctx context.Context, frame *image.RGBA, lim SearchLimits) ([]Candidate, error)
+}
+
+// SearchLimits are the acceptance settings that change from search to search,
+// such as a learned threshold or a narrowed scale range. Passing them to
+// Detect lets one detector serve every search. Zero fields keep the values
+// the detector was built with; detectors ignore settings they do not use.
+type SearchLimits struct {
+	Threshold          float64
+	StopOnScore        float64
+	MinScale, MaxScale float64
+}
+
+// apply overrides the fields of cfg set in l.
+func (l SearchLimits) apply(cfg *config.Config) {
+	if l.Threshold > 0 {
+		cfg.Threshold = l.Threshold
+	}
+	if l.StopOnScore > 0 {
+		cfg.StopOnScore = l.StopOnScore
+	}
+	if l.MinScale > 0 && l.MaxScale >= l.MinScale {
+		cfg.MinScale, cfg.MaxScale = l.MinScale, l.MaxScale
+	}
+}
+
+// DetectorFactory builds a detector for the configured target template.
+type DetectorFactory func(cfg *config.Config, tmpl image.Image) (TargetDetector, error)
+
+var (
+	detectorsMu sync.RWMutex
+	detectors   = map[string]DetectorFactory{}
+)
+
+// RegisterDetector makes a detector available under name. Registering the same
+// name twice panics.
+func RegisterDetector(name string, factory DetectorFactory) {
+	detectorsMu.Lock()
+	defer detectorsMu.Unlock()
+	if _, dup := detectors[name]; dup {
+		panic("capture: detector registered twice: " + name)
+	}
+	detectors[name] = factory
+}
+
+// DetectorNames lists registered detector names in sorted order.
+func DetectorNames() []string {
+	detectorsMu.RLock()
+	defer detectorsMu.RUnlock()
+	names := make([]string, 0, len(detectors))
+	for name := range detectors {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// NewTargetDetector builds the detector registered under name.
+func NewTargetDetector(name string, cfg *config.Config, tmpl image.Image) (TargetDetector, error) {
+	detectorsMu.RLock()
+	factory := detectors[name]
+	detectorsMu.RUnlock()
+	if factory == nil {
+		return nil, fmt.Errorf("unknown target detector %q", name)
+	}
+	if cfg == nil {
+		cfg = config.DefaultConfig()
+	}
+	return factory(cfg, tmpl)
+}
+
+func init() {
+	RegisterDetector(config.DetectorNCC, newNCCDetector)
+}
+
+// nccDetector is the multi-scale normalized cross-correlation template matcher.
+type nccDetector struct {
+	cfg  config.Config
+	tmpl image.Image
+}
+
+func newNCCDetector(cfg *config.Config, tmpl image.Image) (TargetDetector, error) {
+	if tmpl == nil {
+		return nil, fmt.Errorf("%s detector: nil template", config.DetectorNCC)
+	}
+	return &nccDetector{cfg: *cfg, tmpl: tmpl}, nil
+}
+
+func (d *nccDetector) Name() string { return config.DetectorNCC }
+
+func (d *nccDetector) Detect(ctx context.Context, frame *image.RGBA, lim SearchLimits) ([]Candidate, error) {
+	local := d.cfg
+	lim.apply(&local)
+	res, err := DetectTemplateDetailed(ctx, frame, d.tmpl, &local)
+	if err != nil || !res.Found {
+		return nil, err
+	}
+	tb := d.tmpl.Bounds()
+	w := max(1, int(float64(tb.Dx())*res.Scale+0.5))
+	h := max(1, int(float64(tb.Dy())*res.Scale+0.5))
+	return []Candidate{{
+		Bounds:    image.Rect(res.X, res.Y, res.X+w, res.Y+h),
+		FX:        res.FX,
+		FY:        res.FY,
+		CX:        res.FX + float64(w)/2,
+		CY:        res.FY + float64(h)/2,
+		Score:     res.Score,
+		Scale:     res.Scale,
+		Sharpness: res.Sharpness,
+	}}, nil
+}
@@ -0,0 +1,168 @@
+package capture
+
+import (
+	"context"
+	"image"
+	"image/color"
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/soocke/pixel-bot-go/config"
+)
+
+// paintBobber draws an upright bobber of radius r centred at (cx, cy), see
+// paintBobberRotated.
+func paintBobber(img *image.RGBA, cx, cy, r int) {
+	paintBobberRotated(img, float64(cx), float64(cy), float64(r), 0)
+}
+
+// paintBobberRotated draws a red-over-white disc of radius r centred at
+// (cx, cy) with a dark stem and an asymmetric pattern of dark feather spots,
+// rotated by angle (radians). The spots and stem give keypoint detectors
+// corners to work with.
+func paintBobberRotated(img *image.RGBA, cx, cy, r, angle float64) {
+	sin, cos := math.Sincos(-angle)
+	dark := color.RGBA{30, 25, 20, 255}
+	spots := []image.Point{{-5, 3}, {0, 5}, {5, 3}, {-3, -5}, {4, -4}}
+	b := image.Rect(int(cx-r)-1, int(cy-r)-1, int(cx+r)+2, int(cy+r)+2).Intersect(img.Bounds())
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			// Upright coordinates of the pixel centre relative to the bobber centre.
+			dx, dy := float64(x)-cx, float64(y)-cy
+			ux, uy := dx*cos-dy*sin, dx*sin+dy*cos
+			if ux*ux+uy*uy > r*r {
+				continue
+			}
+			c := color.RGBA{200, 30, 30, 255}
+			if uy > 0 {
+				c = color.RGBA{235, 235, 225, 255}
+			}
+			if math.Abs(ux) < 0.7 && uy >= -0.6*r && uy <= -0.2*r {
+				c = dark
+			}
+			for _, o := range spots {
+				sx, sy := float64(o.X)*r/10, float64(o.Y)*r/10
+				if math.Hypot(ux-sx, uy-sy) <= 1.2 {
+					c = dark
+				}
+			}
+			img.SetRGBA(x, y, c)
+		}
+	}
+}
+
+// bobberTemplate returns a bobber of radius r on a transparent background.
+func bobberTemplate(r int) *image.RGBA {
+	tmpl := image.NewRGBA(image.Rect(0, 0, 2*r+1, 2*r+1))
+	paintBobber(tmpl, r, r, r)
+	return tmpl
+}
+
+// waterFrame returns a dark blue frame with mild deterministic noise.
+func waterFrame(w, h int, seed int64) *image.RGBA {
+	rng := rand.New(rand.NewSource(seed))
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for i := 0; i < len(img.Pix); i += 4 {
+		n := byte(rng.Intn(12))
+		img.Pix[i], img.Pix[i+1], img.Pix[i+2], img.Pix[i+3] = 20+n, 50+n, 90+n, 255
+	}
+	return img
+}
+
+type labelledFrame struct {
+	name   string
+	frame  *image.RGBA
+	target image.Rectangle // empty when the frame holds no target
+}
+
+func conformanceFrames() []labelledFrame {
+	var frames []labelledFrame
+	for i, c := range []struct{ x, y, r int }{{40, 30, 10}, {120, 80, 11}, {90, 50, 9}} {
+		f := waterFrame(160, 120, int64(i+1))
+		paintBobber(f, c.x, c.y, c.r)
+		frames = append(frames, labelledFrame{
+			name:   "bobber",
+			frame:  f,
+			target: image.Rect(c.x-c.r, c.y-c.r, c.x+c.r+1, c.y+c.r+1),
+		})
+	}
+	frames = append(frames, labelledFrame{name: "empty", frame: waterFrame(160, 120, 9)})
+	return frames
+}
+
+// TestTargetDetectorConformance runs every registered detector against the
+// same labelled frames.
+func TestTargetDetectorConformance(t *testing.T) {
+	tmpl := bobberTemplate(10)
+	frames := conformanceFrames()
+	for _, name := range DetectorNames() {
+		t.Run(name, func(t *testing.T) {
+			cfg := config.DefaultConfig()
+			cfg.Detector = name
+			det, err := NewTargetDetector(name, cfg, tmpl)
+			if err != nil {
+				t.Fatalf("construct: %v", err)
+			}
+			if det.Name() != name {
+				t.Fatalf("Name() = %q", det.Name())
+			}
+			for i, lf := range frames {
+				cands, err := det.Detect(context.Background(), lf.frame, SearchLimits{})
+				if err != nil {
+					t.Fatalf("frame %d (%s): %v", i, lf.name, err)
+				}
+				if lf.target.Empty() {
+					if len(cands) > 0 {
+						t.Fatalf("frame %d (%s): false positive at %v score %.2f", i, lf.name, cands[0].Bounds, cands[0].Score)
+					}
+					continue
+				}
+				if len(cands) == 0 {
+					t.Fatalf("frame %d (%s): target at %v not found", i, lf.name, lf.target)
+				}
+				c := cands[0]
+				centre := image.Pt(int(c.CX), int(c.CY))
+				if !centre.In(lf.target) {
+					t.Fatalf("frame %d (%s): candidate %v outside target %v", i, lf.name, c.Bounds, lf.target)
+				}
+				for j := 1; j < len(cands); j++ {
+					if cands[j].Score > cands[j-1].Score {
+						t.Fatalf("frame %d (%s): candidates not ordered best first", i, lf.name)
+					}
+				}
+			}
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+			if _, err := det.Detect(ctx, frames[0].frame, SearchLimits{}); err == nil {
+				t.Fatalf("cancelled context must return an error")
+			}
+		})
+	}
+}
+
+func TestNewTargetDetector_Unknown(t *testing.T) {
+	if _, err := NewTargetDetector("nope", nil, bobberTemplate(5)); err == nil {
+		t.Fatalf("expected error for unknown detector")
+	}
+}
+
+func TestNCCDetector_AppliesSearchLimits(t *testing.T) {
+	frame := waterFrame(160, 120, 3)
+	paintBobber(frame, 70, 50, 10)
+	cfg := config.DefaultConfig()
+	cfg.MinScale, cfg.MaxScale = 0.8, 1.2
+	det, err := NewTargetDetector(config.DetectorNCC, cfg, bobberTemplate(10))
+	if err != nil {
+		t.Fatalf("build: %v", err)
+	}
+	if cands, _ := det.Detect(context.Background(), frame, SearchLimits{}); len(cands) == 0 {
+		t.Fatalf("bobber not found with the built settings")
+	}
+	if cands, _ := det.Detect(context.Background(), frame, SearchLimits{Threshold: 0.9999}); len(cands) != 0 {
+		t.Fatalf("candidate %+v passed a per-search threshold of 0.9999", cands[0])
+	}
+	if cands, _ := det.Detect(context.Background(), frame, SearchLimits{MinScale: 0.95, MaxScale: 1.05}); len(cands) == 0 || math.Abs(cands[0].Scale-1) > 0.06 {
+		t.Fatalf("narrowed scale range: %+v", cands)
+	}
+}
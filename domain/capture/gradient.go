@@ -0,0 +1,153 @@
+package capture
+
+import (
+	"context"
+	"image"
+	"math"
+	"time"
+)
+
+// MatchMode selects the image representation compared during template matching.
+type MatchMode int
+
+const (
+	// MatchLuma correlates grayscale intensities (NCC).
+	MatchLuma MatchMode = iota
+	// MatchGradient compares Sobel gradient orientations, which are unaffected
+	// by monotonic brightness changes such as dusk, night or fog.
+	MatchGradient
+)
+
+const (
+	// minGradient is the Sobel magnitude (16-bit luma scale, roughly two 8-bit
+	// levels across an edge) below which a frame pixel carries no orientation.
+	minGradient = 2048
+	// templateEdgeFrac keeps template pixels whose gradient magnitude is at
+	// least this fraction of the strongest template gradient.
+	templateEdgeFrac = 0.2
+	// minTemplateEdges is the minimum number of edge pixels for a usable template.
+	minTemplateEdges = 8
+)
+
+// orientPrecomp holds per-pixel gradient orientation of a frame as the unit
+// vector of the doubled angle (cos 2θ, sin 2θ), so opposite edge polarities
+// compare equal. Pixels with weak gradients hold zero vectors.
+type orientPrecomp struct {
+	c, s []float32
+	W, H int
+}
+
+// orientTemplate lists the strong edge pixels of a (scaled) template.
+type orientTemplate struct {
+	px, py []int32
+	c, s   []float32
+	W, H   int
+}
+
+// sobel returns the Sobel gradient of gray (row-major, w x h) at interior
+// pixel (x, y).
+func sobel[T float32 | float64](gray []T, w, x, y int) (gx, gy float64) {
+	at := func(dx, dy int) float64 { return float64(gray[(y+dy)*w+x+dx]) }
+	gx = at(1, -1) + 2*at(1, 0) + at(1, 1) - at(-1, -1) - 2*at(-1, 0) - at(-1, 1)
+	gy = at(-1, 1) + 2*at(0, 1) + at(1, 1) - at(-1, -1) - 2*at(0, -1) - at(1, -1)
+	return gx, gy
+}
+
+// doubledAngle returns (cos 2θ, sin 2θ) for the gradient (gx, gy).
+func doubledAngle(gx, gy, mag2 float64) (float32, float32) {
+	return float32((gx*gx - gy*gy) / mag2), float32(2 * gx * gy / mag2)
+}
+
+// buildOrientPrecomp computes gradient orientations from a frame's grayscale.
+func buildOrientPrecomp(pre *grayPrecomp) *orientPrecomp {
+	if pre == nil {
+		return nil
+	}
+	W, H := pre.W, pre.H
+	o := &orientPrecomp{c: make([]float32, W*H), s: make([]float32, W*H), W: W, H: H}
+	for y := 1; y < H-1; y++ {
+		for x := 1; x < W-1; x++ {
+			gx, gy := sobel(pre.gray, W, x, y)
+			mag2 := gx*gx + gy*gy
+			if mag2 < minGradient*minGradient {
+				continue
+			}
+			o.c[y*W+x], o.s[y*W+x] = doubledAngle(gx, gy, mag2)
+		}
+	}
+	return o
+}
+
+// orientation returns the template's edge pixels, computed once per precomp.
+// It returns nil when the template has too few edges to match reliably.
+func (pc *templatePrecomp) orientation() *orientTemplate {
+	pc.oriOnce.Do(func() {
+		w, h := pc.W, pc.H
+		if w < 3 || h < 3 {
+			return
+		}
+		mags := make([]float64, w*h)
+		maxMag := 0.0
+		for y := 1; y < h-1; y++ {
+			for x := 1; x < w-1; x++ {
+				gx, gy := sobel(pc.gray, w, x, y)
+				m := math.Hypot(gx, gy)
+				mags[y*w+x] = m
+				maxMag = math.Max(maxMag, m)
+			}
+		}
+		limit := math.Max(minGradient, templateEdgeFrac*maxMag)
+		ot := &orientTemplate{W: w, H: h}
+		for y := 1; y < h-1; y++ {
+			for x := 1; x < w-1; x++ {
+				m := mags[y*w+x]
+				if m < limit {
+					continue
+				}
+				gx, gy := sobel(pc.gray, w, x, y)
+				c, s := doubledAngle(gx, gy, m*m)
+				ot.px, ot.py = append(ot.px, int32(x)), append(ot.py, int32(y))
+				ot.c, ot.s = append(ot.c, c), append(ot.s, s)
+			}
+		}
+		if len(ot.px) >= minTemplateEdges {
+			pc.ori = ot
+		}
+	})
+	return pc.ori
+}
+
+// matchOrientation scores each placement by the mean agreement (cosine of the
+// doubled angle difference) between template edge orientations and frame
+// orientations, in [-1, 1]. Frame pixels without a usable gradient count as
+// zero agreement. Search, refinement and result semantics follow
+// matchTemplateNCCGrayIntegralPre.
+func matchOrientation(ctx context.Context, frame *image.RGBA, ot *orientTemplate, opts NCCOptions, fo *orientPrecomp) NCCResult {
+	start := time.Now()
+	if frame == nil || ot == nil || fo == nil || fo.W < ot.W || fo.H < ot.H {
+		return NCCResult{Score: -1}
+	}
+	W := fo.W
+	offs := make([]int, len(ot.px))
+	for i := range ot.px {
+		offs[i] = int(ot.py[i])*W + int(ot.px[i])
+	}
+	n := float64(len(offs))
+	score := func(x, y int) (float64, bool) {
+		base := y*W + x
+		var sum float64
+		for i, off := range offs {
+			j := base + off
+			sum += float64(ot.c[i]*fo.c[j] + ot.s[i]*fo.s[j])
+		}
+		return sum / n, true
+	}
+	res, ok := scanPeak(ctx, fo.W, fo.H, ot.W, ot.H, frame.Bounds().Min, opts, score)
+	if !ok {
+		return NCCResult{Score: -1}
+	}
+	if opts.DebugTiming {
+		res.Dur = time.Since(start)
+	}
+	return res
+}
@@ -0,0 +1,88 @@
+package capture
+
+import (
+	"context"
+	"image"
+	"math"
+	"testing"
+)
+
+// darken simulates dusk/night lighting: a strong gamma curve, overall dimming
+// with a blue tint and a horizontal illumination falloff.
+func darken(src *image.RGBA, gamma, level float64) *image.RGBA {
+	b := src.Bounds()
+	out := image.NewRGBA(b)
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			i := src.PixOffset(x, y)
+			light := level * (1 - 0.5*float64(x-b.Min.X)/float64(b.Dx()))
+			for c, tint := range [3]float64{0.7, 0.85, 1} {
+				v := math.Pow(float64(src.Pix[i+c])/255, gamma) * light * tint
+				out.Pix[i+c] = byte(255*v + 0.5)
+			}
+			out.Pix[i+3] = 255
+		}
+	}
+	return out
+}
+
+// TestMatchGradient_DarkenedFrames compares luma NCC and gradient matching on
+// bobber frames under progressively darker lighting. Besides the hit rate it
+// compares peak sharpness, the margin over competing peaks that decides how
+// safely a threshold can be set.
+func TestMatchGradient_DarkenedFrames(t *testing.T) {
+	tmpl := bobberTemplate(10)
+	positions := []image.Point{{40, 30}, {120, 80}, {90, 55}, {60, 95}}
+	lighting := []struct{ gamma, level float64 }{{1.8, 0.6}, {2.2, 0.4}, {2.6, 0.3}}
+	hits := map[MatchMode]int{}
+	sharp := map[MatchMode]float64{}
+	total := 0
+	for li, l := range lighting {
+		for pi, pos := range positions {
+			frame := waterFrame(160, 120, int64(10*li+pi))
+			paintBobber(frame, pos.X, pos.Y, 10)
+			dark := darken(frame, l.gamma, l.level)
+			target := image.Rect(pos.X-10, pos.Y-10, pos.X+11, pos.Y+11)
+			total++
+			for _, mode := range []MatchMode{MatchLuma, MatchGradient} {
+				res, err := MultiScaleMatch(context.Background(), dark, tmpl, MultiScaleOptions{
+					MinScale: 0.9, MaxScale: 1.1, ScaleStep: 0.05, Mode: mode,
+					NCC: NCCOptions{Threshold: 0.73, Stride: 2, Refine: true},
+				})
+				if err != nil {
+					t.Fatal(err)
+				}
+				centre := image.Pt(res.X+10, res.Y+10)
+				if res.Found && centre.In(target) {
+					hits[mode]++
+					sharp[mode] += res.Sharpness
+				}
+			}
+		}
+	}
+	t.Logf("darkened accuracy: luma %d/%d (sharpness %.1f), gradient %d/%d (sharpness %.1f)",
+		hits[MatchLuma], total, sharp[MatchLuma]/float64(max(1, hits[MatchLuma])),
+		hits[MatchGradient], total, sharp[MatchGradient]/float64(max(1, hits[MatchGradient])))
+	if hits[MatchGradient] != total {
+		t.Fatalf("gradient matching found %d/%d darkened targets", hits[MatchGradient], total)
+	}
+	if hits[MatchGradient] < hits[MatchLuma] {
+		t.Fatalf("gradient matching (%d) less accurate than luma (%d) on dark frames", hits[MatchGradient], hits[MatchLuma])
+	}
+	if sharp[MatchGradient]/float64(hits[MatchGradient]) <= sharp[MatchLuma]/float64(max(1, hits[MatchLuma])) {
+		t.Fatalf("gradient peaks should stand out more than luma peaks on dark frames")
+	}
+}
+
+func TestMatchGradient_NoTargetBelowThreshold(t *testing.T) {
+	res, err := MultiScaleMatch(context.Background(), waterFrame(160, 120, 42), bobberTemplate(10), MultiScaleOptions{
+		MinScale: 0.9, MaxScale: 1.1, ScaleStep: 0.05, Mode: MatchGradient,
+		NCC: NCCOptions{Threshold: 0.73, Stride: 2, Refine: true},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Found {
+		t.Fatalf("gradient match on empty water: score %.2f at (%d,%d)", res.Score, res.X, res.Y)
+	}
+}
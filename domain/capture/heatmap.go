@@ -0,0 +1,197 @@
+package capture
+
+import (
+	"errors"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"io"
+	"math"
+)
+
+// ScoreMap holds the match score of every template placement on a frame.
+// Entry (x, y) is the score with the template's top-left corner at
+// Origin+(x, y); flat frame windows where NCC is undefined hold NaN.
+type ScoreMap struct {
+	W, H   int // number of placements along x and y
+	TW, TH int // scaled template size
+	Scale  float64
+	Origin image.Point
+	Scores []float64
+}
+
+// ComputeScoreMap evaluates the score of tmpl (resized by scale) at every
+// position of frame in the given match mode: masked NCC for MatchLuma, mean
+// edge orientation agreement for MatchGradient. It is intended for
+// diagnostics and tuning; the cost is proportional to frame area times
+// template area.
+func ComputeScoreMap(frame *image.RGBA, tmpl image.Image, scale float64, mode MatchMode) (*ScoreMap, error) {
+	if frame == nil || tmpl == nil {
+		return nil, errors.New("score map: nil frame or template")
+	}
+	if scale <= 0 {
+		scale = 1
+	}
+	pc := getScaledTemplatePrecompFromBase(getTemplatePrecomp(tmpl), scale)
+	if pc == nil {
+		return nil, errors.New("score map: template too small at scale")
+	}
+	fb := frame.Bounds()
+	W, H := fb.Dx(), fb.Dy()
+	if W < pc.W || H < pc.H {
+		return nil, errors.New("score map: template larger than frame")
+	}
+	pl := placements{pre: buildGrayPrecomp(frame)}
+	if mode == MatchGradient {
+		if pc.orientation() == nil {
+			return nil, errors.New("score map: template has too few edges")
+		}
+		pl.ori = buildOrientPrecomp(pl.pre)
+	}
+	score := pl.scorer(pc)
+	m := &ScoreMap{W: W - pc.W + 1, H: H - pc.H + 1, TW: pc.W, TH: pc.H, Scale: scale, Origin: fb.Min}
+	m.Scores = make([]float64, m.W*m.H)
+	for y := 0; y < m.H; y++ {
+		for x := 0; x < m.W; x++ {
+			s, ok := score(x, y)
+			if !ok {
+				s = math.NaN()
+			}
+			m.Scores[y*m.W+x] = s
+		}
+	}
+	return m, nil
+}
+
+// At returns the score at placement (x, y) relative to Origin (NaN outside).
+func (m *ScoreMap) At(x, y int) float64 {
+	if m == nil || x < 0 || y < 0 || x >= m.W || y >= m.H {
+		return math.NaN()
+	}
+	return m.Scores[y*m.W+x]
+}
+
+// Max returns the best placement in frame coordinates and its score.
+func (m *ScoreMap) Max() (image.Point, float64) {
+	best, bi := math.Inf(-1), -1
+	if m == nil {
+		return image.Point{}, best
+	}
+	for i, s := range m.Scores {
+		if s > best {
+			best, bi = s, i
+		}
+	}
+	if bi < 0 {
+		return image.Point{}, best
+	}
+	return m.Origin.Add(image.Pt(bi%m.W, bi/m.W)), best
+}
+
+// RenderHeatmap overlays the score map on a copy of frame. Each score is drawn
+// at the template centre of its placement using a blue-to-red colour ramp over
+// [0, 1]; non-positive and undefined scores leave the frame untouched. alpha
+// (0-1] controls overlay opacity.
+func RenderHeatmap(frame *image.RGBA, m *ScoreMap, alpha float64) *image.RGBA {
+	if frame == nil {
+		return nil
+	}
+	if alpha <= 0 || alpha > 1 {
+		alpha = 0.6
+	}
+	fb := frame.Bounds()
+	out := image.NewRGBA(fb)
+	draw.Draw(out, fb, frame, fb.Min, draw.Src)
+	if m == nil {
+		return out
+	}
+	offX, offY := m.TW/2, m.TH/2
+	for y := 0; y < m.H; y++ {
+		for x := 0; x < m.W; x++ {
+			s := m.Scores[y*m.W+x]
+			if !(s > 0) {
+				continue
+			}
+			px, py := m.Origin.X+x+offX, m.Origin.Y+y+offY
+			i := out.PixOffset(px, py)
+			c := heatColor(s)
+			a := alpha * math.Min(1, 0.35+s)
+			out.Pix[i] = blend(out.Pix[i], c.R, a)
+			out.Pix[i+1] = blend(out.Pix[i+1], c.G, a)
+			out.Pix[i+2] = blend(out.Pix[i+2], c.B, a)
+		}
+	}
+	return out
+}
+
+// WriteHeatmapPNG renders the heatmap overlay and encodes it as PNG to w.
+func WriteHeatmapPNG(w io.Writer, frame *image.RGBA, m *ScoreMap) error {
+	img := RenderHeatmap(frame, m, 0)
+	if img == nil {
+		return errors.New("heatmap: nil frame")
+	}
+	return png.Encode(w, img)
+}
+
+// heatColor maps s in [0, 1] onto a blue-cyan-green-yellow-red ramp.
+func heatColor(s float64) color.RGBA {
+	s = math.Max(0, math.Min(1, s))
+	seg := s * 4
+	f := seg - math.Floor(seg)
+	v := byte(255 * f)
+	switch {
+	case seg < 1:
+		return color.RGBA{0, v, 255, 255}
+	case seg < 2:
+		return color.RGBA{0, 255, 255 - v, 255}
+	case seg < 3:
+		return color.RGBA{v, 255, 0, 255}
+	case seg < 4:
+		return color.RGBA{255, 255 - v, 0, 255}
+	default:
+		return color.RGBA{255, 0, 0, 255}
+	}
+}
+
+func blend(dst, src byte, a float64) byte {
+	return byte(float64(dst)*(1-a) + float64(src)*a + 0.5)
+}
+
+// placements describes a frame for scoring single template placements: its
+// grayscale and, in gradient mode, its edge orientations.
+type placements struct {
+	pre *grayPrecomp
+	ori *orientPrecomp // nil in luma mode
+}
+
+// scorer returns the score of pc with its top-left corner at frame pixel
+// (x, y) relative to the frame origin: masked NCC, or the mean orientation
+// agreement when pl holds orientations. It returns nil when pc cannot be
+// scored.
+func (pl placements) scorer(pc *templatePrecomp) func(x, y int) (float64, bool) {
+	if pc == nil || pl.pre == nil {
+		return nil
+	}
+	W := pl.pre.W
+	if pl.ori == nil {
+		n := float64(pc.W * pc.H)
+		return func(x, y int) (float64, bool) {
+			return windowNCC(pl.pre, pc, W, x, y, n, pc.meanT, pc.stdT)
+		}
+	}
+	ot := pc.orientation()
+	if ot == nil {
+		return nil
+	}
+	fo := pl.ori
+	return func(x, y int) (float64, bool) {
+		base := y*W + x
+		var sum float64
+		for i := range ot.px {
+			j := base + int(ot.py[i])*W + int(ot.px[i])
+			sum += float64(ot.c[i]*fo.c[j] + ot.s[i]*fo.s[j])
+		}
+		return sum / float64(len(ot.px)), true
+	}
+}
@@ -0,0 +1,67 @@
+package capture
+
+import (
+	"bytes"
+	"image"
+	"image/png"
+	"math"
+	"testing"
+)
+
+func TestComputeScoreMap_PeakAtTemplate(t *testing.T) {
+	tmpl := blobFrame(15, 15, 7, 7, 2.5)
+	frame := blobFrame(64, 48, 40, 20, 2.5)
+	m, err := ComputeScoreMap(frame, tmpl, 1, MatchLuma)
+	if err != nil {
+		t.Fatalf("score map: %v", err)
+	}
+	if m.W != 64-15+1 || m.H != 48-15+1 {
+		t.Fatalf("unexpected map size %dx%d", m.W, m.H)
+	}
+	pt, score := m.Max()
+	if pt != image.Pt(33, 13) || score < 0.99 {
+		t.Fatalf("peak at %v score %.3f, want (33,13) ~1", pt, score)
+	}
+	if s := m.At(0, 0); !math.IsNaN(s) && s > 0.5 {
+		t.Fatalf("background placement should score low, got %.3f", s)
+	}
+}
+
+func TestComputeScoreMap_GradientMode(t *testing.T) {
+	tmpl := bobberTemplate(10)
+	frame := waterFrame(160, 120, 3)
+	paintBobber(frame, 90, 55, 10)
+	m, err := ComputeScoreMap(darken(frame, 2.2, 0.4), tmpl, 1, MatchGradient)
+	if err != nil {
+		t.Fatalf("score map: %v", err)
+	}
+	// The template's top-left corner sits at the bobber centre minus its radius.
+	if pt, score := m.Max(); pt != image.Pt(80, 45) || score < 0.8 {
+		t.Fatalf("gradient peak at %v score %.3f, want (80,45) ~1", pt, score)
+	}
+}
+
+func TestWriteHeatmapPNG_OverlaysFrame(t *testing.T) {
+	tmpl := blobFrame(15, 15, 7, 7, 2.5)
+	frame := blobFrame(64, 48, 40, 20, 2.5)
+	m, err := ComputeScoreMap(frame, tmpl, 1, MatchLuma)
+	if err != nil {
+		t.Fatalf("score map: %v", err)
+	}
+	var buf bytes.Buffer
+	if err := WriteHeatmapPNG(&buf, frame, m); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	img, err := png.Decode(&buf)
+	if err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if img.Bounds() != frame.Bounds() {
+		t.Fatalf("heatmap bounds %v, want %v", img.Bounds(), frame.Bounds())
+	}
+	// The peak (drawn at the template centre) must be tinted red.
+	r, g, b, _ := img.At(40, 20).RGBA()
+	if r <= g || r <= b {
+		t.Fatalf("peak pixel not red-tinted: r=%d g=%d b=%d", r>>8, g>>8, b>>8)
+	}
+}
@@ -0,0 +1,237 @@
+package capture
+
+import (
+	"context"
+	"image"
+	"math"
+	"sort"
+
+	"github.com/soocke/pixel-bot-go/config"
+)
+
+const (
+	blobMinAspect = 0.3   // minimum short/long side ratio of a blob's bounding box
+	blobMinFill   = 0.3   // minimum fraction of the bounding box covered by the blob
+	discFill      = 0.785 // fill ratio of a disc in its bounding square (pi/4)
+)
+
+func init() {
+	RegisterDetector(config.DetectorHSV, newHSVBlobDetector)
+}
+
+// hsvBlobDetector segments pixels within the configured HSV ranges and reports
+// compact connected components as candidates. It ignores the template, which
+// makes it a fallback for bobbers shaded differently from the template.
+type hsvBlobDetector struct {
+	colors  []config.HSVRange
+	minArea int
+	maxArea int
+}
+
+func newHSVBlobDetector(cfg *config.Config, _ image.Image) (TargetDetector, error) {
+	local := *cfg
+	if err := local.Validate(); err != nil {
+		return nil, err
+	}
+	return &hsvBlobDetector{colors: local.BlobColors, minArea: local.BlobMinArea, maxArea: local.BlobMaxArea}, nil
+}
+
+func (d *hsvBlobDetector) Name() string { return config.DetectorHSV }
+
+// Detect labels 8-connected components of matching pixels and filters them by
+// area, bounding-box aspect and fill ratio. The score rewards disc-like blobs.
+func (d *hsvBlobDetector) Detect(ctx context.Context, frame *image.RGBA, _ SearchLimits) ([]Candidate, error) {
+	if frame == nil {
+		return nil, nil
+	}
+	fb := frame.Bounds()
+	W, H := fb.Dx(), fb.Dy()
+	mask := make([]bool, W*H)
+	for y := 0; y < H; y++ {
+		if y%64 == 0 {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+		}
+		row := frame.Pix[y*frame.Stride : y*frame.Stride+W*4]
+		for x := 0; x < W; x++ {
+			h, s, v := rgbToHSV(row[x*4], row[x*4+1], row[x*4+2])
+			for _, r := range d.colors {
+				if hsvInRange(r, h, s, v) {
+					mask[y*W+x] = true
+					break
+				}
+			}
+		}
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	var cands []Candidate
+	for _, c := range labelComponents(mask, W, H) {
+		if c.area < d.minArea || c.area > d.maxArea {
+			continue
+		}
+		bw, bh := c.bounds.Dx(), c.bounds.Dy()
+		aspect := float64(min(bw, bh)) / float64(max(bw, bh))
+		fill := float64(c.area) / float64(bw*bh)
+		if aspect < blobMinAspect || fill < blobMinFill {
+			continue
+		}
+		b := c.bounds.Add(fb.Min)
+		cands = append(cands, Candidate{
+			Bounds: b,
+			FX:     float64(b.Min.X),
+			FY:     float64(b.Min.Y),
+			CX:     c.cx + float64(fb.Min.X),
+			CY:     c.cy + float64(fb.Min.Y),
+			Score:  0.5*math.Min(1, fill/discFill) + 0.5*aspect,
+		})
+	}
+	sort.SliceStable(cands, func(i, j int) bool { return cands[i].Score > cands[j].Score })
+	return cands, nil
+}
+
+// component is a connected region of a binary mask.
+type component struct {
+	bounds image.Rectangle
+	area   int
+	cx, cy float64 // centroid
+}
+
+// labelComponents returns the 8-connected components of mask (row-major, w*h).
+func labelComponents(mask []bool, w, h int) []component {
+	seen := make([]bool, len(mask))
+	var comps []component
+	var stack []int
+	for start, on := range mask {
+		if !on || seen[start] {
+			continue
+		}
+		seen[start] = true
+		stack = append(stack[:0], start)
+		c := component{bounds: image.Rect(start%w, start/w, start%w+1, start/w+1)}
+		var sx, sy int
+		for len(stack) > 0 {
+			i := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			x, y := i%w, i/w
+			c.area++
+			sx += x
+			sy += y
+			c.bounds = c.bounds.Union(image.Rect(x, y, x+1, y+1))
+			for dy := -1; dy <= 1; dy++ {
+				for dx := -1; dx <= 1; dx++ {
+					nx, ny := x+dx, y+dy
+					if nx < 0 || ny < 0 || nx >= w || ny >= h {
+						continue
+					}
+					j := ny*w + nx
+					if mask[j] && !seen[j] {
+						seen[j] = true
+						stack = append(stack, j)
+					}
+				}
+			}
+		}
+		c.cx, c.cy = float64(sx)/float64(c.area)+0.5, float64(sy)/float64(c.area)+0.5
+		comps = append(comps, c)
+	}
+	return comps
+}
+
+// rgbToHSV converts 8-bit RGB to hue in degrees [0, 360) and saturation and
+// value in [0, 1].
+func rgbToHSV(r, g, b uint8) (h, s, v float64) {
+	rf, gf, bf := float64(r)/255, float64(g)/255, float64(b)/255
+	hi := math.Max(rf, math.Max(gf, bf))
+	lo := math.Min(rf, math.Min(gf, bf))
+	v = hi
+	d := hi - lo
+	if hi == 0 || d == 0 {
+		return 0, 0, v
+	}
+	s = d / hi
+	switch hi {
+	case rf:
+		h = math.Mod((gf-bf)/d, 6)
+	case gf:
+		h = (bf-rf)/d + 2
+	default:
+		h = (rf-gf)/d + 4
+	}
+	h *= 60
+	if h < 0 {
+		h += 360
+	}
+	return h, s, v
+}
+
+func hsvInRange(r config.HSVRange, h, s, v float64) bool {
+	if s < r.SMin || s > r.SMax || v < r.VMin || v > r.VMax {
+		return false
+	}
+	if r.HMin <= r.HMax {
+		return h >= r.HMin && h <= r.HMax
+	}
+	return h >= r.HMin || h <= r.HMax
+}
+
+// LearnHSVRange derives a colour range from the pixels within radius of pt,
+// e.g. a point clicked on the bobber in the preview. Hue is averaged on the
+// colour circle; all ranges are widened by a margin so shading variations
+// still match. ok is false when pt lies outside frame or the sample is
+// achromatic (no meaningful hue).
+func LearnHSVRange(frame *image.RGBA, pt image.Point, radius int) (config.HSVRange, bool) {
+	if frame == nil || !pt.In(frame.Bounds()) {
+		return config.HSVRange{}, false
+	}
+	if radius <= 0 {
+		radius = 3
+	}
+	area := image.Rect(pt.X-radius, pt.Y-radius, pt.X+radius+1, pt.Y+radius+1).Intersect(frame.Bounds())
+	var sinSum, cosSum float64
+	var hues []float64
+	sMin, sMax, vMin, vMax := 1.0, 0.0, 1.0, 0.0
+	for y := area.Min.Y; y < area.Max.Y; y++ {
+		for x := area.Min.X; x < area.Max.X; x++ {
+			i := frame.PixOffset(x, y)
+			h, s, v := rgbToHSV(frame.Pix[i], frame.Pix[i+1], frame.Pix[i+2])
+			sMin, sMax = math.Min(sMin, s), math.Max(sMax, s)
+			vMin, vMax = math.Min(vMin, v), math.Max(vMax, v)
+			if s < 0.15 {
+				continue
+			}
+			rad := h * math.Pi / 180
+			sinSum += math.Sin(rad)
+			cosSum += math.Cos(rad)
+			hues = append(hues, h)
+		}
+	}
+	if len(hues) == 0 {
+		return config.HSVRange{}, false
+	}
+	mean := math.Atan2(sinSum, cosSum) * 180 / math.Pi
+	spread := 0.0
+	for _, h := range hues {
+		spread = math.Max(spread, hueDistance(h, mean))
+	}
+	half := math.Min(90, spread+12)
+	return config.HSVRange{
+		HMin: math.Mod(mean-half+360, 360),
+		HMax: math.Mod(mean+half+360, 360),
+		SMin: math.Max(0, sMin-0.15),
+		SMax: math.Min(1, sMax+0.15),
+		VMin: math.Max(0, vMin-0.2),
+		VMax: math.Min(1, vMax+0.2),
+	}, true
+}
+
+// hueDistance returns the angular distance between two hues in degrees.
+func hueDistance(a, b float64) float64 {
+	d := math.Abs(math.Mod(a-b, 360))
+	if d > 180 {
+		d = 360 - d
+	}
+	return d
+}
@@ -0,0 +1,92 @@
+package capture
+
+import (
+	"context"
+	"image"
+	"image/color"
+	"math"
+	"testing"
+
+	"github.com/soocke/pixel-bot-go/config"
+)
+
+func TestRGBToHSV(t *testing.T) {
+	cases := []struct {
+		r, g, b uint8
+		h, s, v float64
+	}{
+		{255, 0, 0, 0, 1, 1},
+		{0, 255, 0, 120, 1, 1},
+		{0, 0, 255, 240, 1, 1},
+		{255, 0, 128, 330, 1, 1},
+		{128, 128, 128, 0, 0, 128.0 / 255},
+	}
+	for _, c := range cases {
+		h, s, v := rgbToHSV(c.r, c.g, c.b)
+		if math.Abs(h-c.h) > 0.5 || math.Abs(s-c.s) > 0.01 || math.Abs(v-c.v) > 0.01 {
+			t.Fatalf("rgbToHSV(%d,%d,%d) = (%.1f,%.2f,%.2f) want (%.1f,%.2f,%.2f)", c.r, c.g, c.b, h, s, v, c.h, c.s, c.v)
+		}
+	}
+}
+
+func TestHSVBlobDetector_FiltersByShape(t *testing.T) {
+	frame := waterFrame(160, 120, 3)
+	paintBobber(frame, 50, 60, 10)
+	// A long red bar (wrong aspect) and a red speck (too small).
+	for x := 90; x < 150; x++ {
+		for y := 20; y < 24; y++ {
+			frame.SetRGBA(x, y, color.RGBA{200, 30, 30, 255})
+		}
+	}
+	frame.SetRGBA(120, 100, color.RGBA{200, 30, 30, 255})
+	det, err := NewTargetDetector(config.DetectorHSV, config.DefaultConfig(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cands, err := det.Detect(context.Background(), frame, SearchLimits{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cands) != 1 {
+		t.Fatalf("expected only the bobber, got %d candidates", len(cands))
+	}
+	if math.Abs(cands[0].CX-50.5) > 1.5 || cands[0].CY > 60 || cands[0].CY < 53 {
+		t.Fatalf("centroid (%.1f,%.1f) not on the red half of the bobber", cands[0].CX, cands[0].CY)
+	}
+	if cands[0].Score <= 0 || cands[0].Score > 1 {
+		t.Fatalf("score %.2f outside (0,1]", cands[0].Score)
+	}
+}
+
+func TestLearnHSVRange_FindsDifferentlyShadedBobber(t *testing.T) {
+	frame := waterFrame(160, 120, 4)
+	// A green-feathered bobber the default red range cannot see.
+	for y := 40; y <= 60; y++ {
+		for x := 70; x <= 90; x++ {
+			if (x-80)*(x-80)+(y-50)*(y-50) <= 100 {
+				frame.SetRGBA(x, y, color.RGBA{40, 170, 60, 255})
+			}
+		}
+	}
+	cfg := config.DefaultConfig()
+	det, _ := NewTargetDetector(config.DetectorHSV, cfg, nil)
+	if cands, _ := det.Detect(context.Background(), frame, SearchLimits{}); len(cands) != 0 {
+		t.Fatalf("default colours should not match a green bobber")
+	}
+	rng, ok := LearnHSVRange(frame, image.Pt(80, 50), 3)
+	if !ok {
+		t.Fatalf("expected a range from a chromatic sample")
+	}
+	if hueDistance(rng.HMin, 130) > 30 && hueDistance(rng.HMax, 130) > 30 {
+		t.Fatalf("learned hue %.0f-%.0f far from green", rng.HMin, rng.HMax)
+	}
+	cfg.BlobColors = []config.HSVRange{rng}
+	det, _ = NewTargetDetector(config.DetectorHSV, cfg, nil)
+	cands, _ := det.Detect(context.Background(), frame, SearchLimits{})
+	if len(cands) != 1 || !image.Pt(int(cands[0].CX), int(cands[0].CY)).In(image.Rect(75, 45, 86, 56)) {
+		t.Fatalf("learned range should find the green bobber, got %+v", cands)
+	}
+	if _, ok := LearnHSVRange(frame, image.Pt(500, 500), 3); ok {
+		t.Fatalf("points outside the frame must be rejected")
+	}
+}
@@ -0,0 +1,561 @@
+package capture
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"math"
+	"math/bits"
+	"math/cmplx"
+	"math/rand"
+	"sort"
+
+	"github.com/soocke/pixel-bot-go/config"
+)
+
+const (
+	fastThreshold     = 20  // FAST intensity difference (8-bit luma)
+	fastArc           = 9   // contiguous circle pixels required for a corner
+	maxKeypoints      = 500 // strongest corners kept per image
+	orientRadius      = 3   // intensity centroid radius for keypoint orientation
+	briefRadius       = 8   // sampling radius of descriptor test pairs
+	briefBits         = 256
+	minDescriptorBits = 64  // comparable bits required to trust a distance
+	maxMatchDistance  = 0.3 // maximum fraction of differing comparable bits
+	matchRatio        = 0.8 // ratio test: best must beat second best by this factor to stand alone
+	ransacIterations  = 300
+	ransacTolerance   = 1.5 // reprojection error (frame pixels) for an inlier
+)
+
+func init() {
+	RegisterDetector(config.DetectorKeypoint, newKeypointDetector)
+}
+
+// fastCircle is the Bresenham circle of radius 3 used by FAST, in order.
+var fastCircle = [16]image.Point{
+	{0, -3}, {1, -3}, {2, -2}, {3, -1}, {3, 0}, {3, 1}, {2, 2}, {1, 3},
+	{0, 3}, {-1, 3}, {-2, 2}, {-3, 1}, {-3, 0}, {-3, -1}, {-2, -2}, {-1, -3},
+}
+
+// briefPattern holds the descriptor test pairs (x1, y1, x2, y2), drawn once
+// from a fixed seed so descriptors are comparable across runs.
+var briefPattern = func() [briefBits][4]float64 {
+	rng := rand.New(rand.NewSource(0x0b0b))
+	var p [briefBits][4]float64
+	coord := func() float64 {
+		return math.Max(-briefRadius, math.Min(briefRadius, rng.NormFloat64()*briefRadius/2))
+	}
+	for i := range p {
+		p[i] = [4]float64{coord(), coord(), coord(), coord()}
+	}
+	return p
+}()
+
+// lumaPlane is an 8-bit grayscale image with a validity mask (false where the
+// source was transparent).
+type lumaPlane struct {
+	pix   []uint8
+	valid []bool
+	w, h  int
+}
+
+func newLumaPlane(img image.Image) *lumaPlane {
+	b := img.Bounds()
+	p := &lumaPlane{pix: make([]uint8, b.Dx()*b.Dy()), valid: make([]bool, b.Dx()*b.Dy()), w: b.Dx(), h: b.Dy()}
+	for y := 0; y < p.h; y++ {
+		for x := 0; x < p.w; x++ {
+			r, g, bb, a := img.At(b.Min.X+x, b.Min.Y+y).RGBA()
+			if a == 0 {
+				continue
+			}
+			p.pix[y*p.w+x] = uint8((77*r + 150*g + 29*bb) >> 16)
+			p.valid[y*p.w+x] = true
+		}
+	}
+	return p
+}
+
+// blurred returns a 3x3 box-filtered copy; invalid pixels are excluded from
+// the average and stay invalid.
+func (p *lumaPlane) blurred() *lumaPlane {
+	out := &lumaPlane{pix: make([]uint8, len(p.pix)), valid: p.valid, w: p.w, h: p.h}
+	for y := 0; y < p.h; y++ {
+		for x := 0; x < p.w; x++ {
+			if !p.valid[y*p.w+x] {
+				continue
+			}
+			sum, n := 0, 0
+			for dy := -1; dy <= 1; dy++ {
+				for dx := -1; dx <= 1; dx++ {
+					if v, ok := p.at(x+dx, y+dy); ok {
+						sum += int(v)
+						n++
+					}
+				}
+			}
+			out.pix[y*p.w+x] = uint8(sum / n)
+		}
+	}
+	return out
+}
+
+func (p *lumaPlane) at(x, y int) (uint8, bool) {
+	if x < 0 || y < 0 || x >= p.w || y >= p.h || !p.valid[y*p.w+x] {
+		return 0, false
+	}
+	return p.pix[y*p.w+x], true
+}
+
+// bilinear interpolates the plane at (x, y); ok is false unless all four
+// neighbouring pixels are valid.
+func (p *lumaPlane) bilinear(x, y float64) (float64, bool) {
+	x0, y0 := int(math.Floor(x)), int(math.Floor(y))
+	fx, fy := x-float64(x0), y-float64(y0)
+	v00, ok00 := p.at(x0, y0)
+	v10, ok10 := p.at(x0+1, y0)
+	v01, ok01 := p.at(x0, y0+1)
+	v11, ok11 := p.at(x0+1, y0+1)
+	if !ok00 || !ok10 || !ok01 || !ok11 {
+		return 0, false
+	}
+	top := float64(v00)*(1-fx) + float64(v10)*fx
+	bottom := float64(v01)*(1-fx) + float64(v11)*fx
+	return top*(1-fy) + bottom*fy, true
+}
+
+// keypoint is a FAST corner with its orientation and descriptor.
+type keypoint struct {
+	x, y  int
+	score int
+	angle float64
+	desc  [briefBits / 64]uint64
+	mask  [briefBits / 64]uint64 // descriptor bits that could be evaluated
+}
+
+// fastCorners detects FAST-9 corners whose full test circle is valid and
+// keeps the strongest after 3x3 non-maximum suppression.
+func fastCorners(p *lumaPlane) []keypoint {
+	scores := make([]int, p.w*p.h)
+	for y := 3; y < p.h-3; y++ {
+		for x := 3; x < p.w-3; x++ {
+			scores[y*p.w+x] = fastScore(p, x, y)
+		}
+	}
+	var kps []keypoint
+	for y := 3; y < p.h-3; y++ {
+		for x := 3; x < p.w-3; x++ {
+			s := scores[y*p.w+x]
+			if s == 0 {
+				continue
+			}
+			isMax := true
+			for dy := -1; dy <= 1 && isMax; dy++ {
+				for dx := -1; dx <= 1; dx++ {
+					n := scores[(y+dy)*p.w+x+dx]
+					if n > s || (n == s && (dy < 0 || (dy == 0 && dx < 0))) {
+						isMax = false
+						break
+					}
+				}
+			}
+			if isMax {
+				kps = append(kps, keypoint{x: x, y: y, score: s})
+			}
+		}
+	}
+	sort.SliceStable(kps, func(i, j int) bool { return kps[i].score > kps[j].score })
+	if len(kps) > maxKeypoints {
+		kps = kps[:maxKeypoints]
+	}
+	return kps
+}
+
+// fastScore returns the sum of absolute differences beyond the threshold when
+// (x, y) is a FAST corner and 0 otherwise.
+func fastScore(p *lumaPlane, x, y int) int {
+	c, ok := p.at(x, y)
+	if !ok {
+		return 0
+	}
+	var ring [16]int
+	for i, o := range fastCircle {
+		v, ok := p.at(x+o.X, y+o.Y)
+		if !ok {
+			return 0
+		}
+		ring[i] = int(v) - int(c)
+	}
+	for _, sign := range [2]int{1, -1} {
+		run, best, score := 0, 0, 0
+		for i := 0; i < 32; i++ {
+			d := sign * ring[i%16]
+			if d > fastThreshold {
+				run++
+				best = max(best, run)
+			} else {
+				run = 0
+			}
+		}
+		if best >= fastArc {
+			for _, d := range ring {
+				if d = sign * d; d > fastThreshold {
+					score += d - fastThreshold
+				}
+			}
+			return score
+		}
+	}
+	return 0
+}
+
+// describe computes the orientation (intensity centroid) and the steered
+// binary descriptor of each keypoint from the blurred plane.
+func describe(p *lumaPlane, kps []keypoint) {
+	for k := range kps {
+		kp := &kps[k]
+		var m10, m01 float64
+		for dy := -orientRadius; dy <= orientRadius; dy++ {
+			for dx := -orientRadius; dx <= orientRadius; dx++ {
+				if dx*dx+dy*dy > orientRadius*orientRadius {
+					continue
+				}
+				if v, ok := p.at(kp.x+dx, kp.y+dy); ok {
+					m10 += float64(dx) * float64(v)
+					m01 += float64(dy) * float64(v)
+				}
+			}
+		}
+		kp.angle = math.Atan2(m01, m10)
+		sin, cos := math.Sincos(kp.angle)
+		sample := func(x, y float64) (float64, bool) {
+			return p.bilinear(float64(kp.x)+x*cos-y*sin, float64(kp.y)+x*sin+y*cos)
+		}
+		for i, pair := range briefPattern {
+			a, okA := sample(pair[0], pair[1])
+			b, okB := sample(pair[2], pair[3])
+			if !okA || !okB {
+				continue
+			}
+			kp.mask[i/64] |= 1 << (i % 64)
+			if a < b {
+				kp.desc[i/64] |= 1 << (i % 64)
+			}
+		}
+	}
+}
+
+// extractKeypoints detects and describes keypoints of img.
+func extractKeypoints(img image.Image) []keypoint {
+	return planeKeypoints(newLumaPlane(img))
+}
+
+func planeKeypoints(plane *lumaPlane) []keypoint {
+	kps := fastCorners(plane)
+	describe(plane.blurred(), kps)
+	return kps
+}
+
+// resized returns the plane scaled by factor using bilinear interpolation.
+// Output pixels whose neighbourhood touches invalid input are invalid.
+func (p *lumaPlane) resized(factor float64) *lumaPlane {
+	w := max(1, int(math.Round(float64(p.w)*factor)))
+	h := max(1, int(math.Round(float64(p.h)*factor)))
+	out := &lumaPlane{pix: make([]uint8, w*h), valid: make([]bool, w*h), w: w, h: h}
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			sx := math.Max(0, math.Min(float64(p.w-1), (float64(x)+0.5)/factor-0.5))
+			sy := math.Max(0, math.Min(float64(p.h-1), (float64(y)+0.5)/factor-0.5))
+			if v, ok := p.bilinear(math.Min(sx, float64(p.w)-1.001), math.Min(sy, float64(p.h)-1.001)); ok {
+				out.pix[y*w+x] = uint8(v + 0.5)
+				out.valid[y*w+x] = true
+			}
+		}
+	}
+	return out
+}
+
+// descriptorDistance returns the fraction of differing bits among the bits
+// valid in both descriptors and the number of such bits.
+func descriptorDistance(a, b *keypoint) (float64, int) {
+	diff, valid := 0, 0
+	for i := range a.desc {
+		m := a.mask[i] & b.mask[i]
+		valid += bits.OnesCount64(m)
+		diff += bits.OnesCount64((a.desc[i] ^ b.desc[i]) & m)
+	}
+	if valid == 0 {
+		return 1, 0
+	}
+	return float64(diff) / float64(valid), valid
+}
+
+// correspondence pairs a template point with a frame point. tIdx identifies
+// the template keypoint so alternatives for the same point count once.
+type correspondence struct {
+	t, f complex128
+	tIdx int
+}
+
+// matchKeypoints pairs template keypoints, detected on the template resized
+// by scale, with frame keypoints. Matches passing the ratio test are kept
+// alone; ambiguous ones (e.g. repeated feather spots) contribute both of
+// their two nearest frame keypoints and geometric verification picks the
+// consistent one. Template points are mapped back to unscaled template
+// coordinates.
+func matchKeypoints(tmpl []keypoint, scale float64, frame []keypoint) []correspondence {
+	var out []correspondence
+	for i := range tmpl {
+		best, second, bestJ, secondJ := 1.0, 1.0, -1, -1
+		for j := range frame {
+			d, valid := descriptorDistance(&tmpl[i], &frame[j])
+			if valid < minDescriptorBits {
+				continue
+			}
+			if d < best {
+				best, second, bestJ, secondJ = d, best, j, bestJ
+			} else if d < second {
+				second, secondJ = d, j
+			}
+		}
+		if bestJ < 0 || best > maxMatchDistance {
+			continue
+		}
+		t := complex((float64(tmpl[i].x)+0.5)/scale-0.5, (float64(tmpl[i].y)+0.5)/scale-0.5)
+		out = append(out, correspondence{t: t, f: complex(float64(frame[bestJ].x), float64(frame[bestJ].y)), tIdx: i})
+		if best >= matchRatio*second && secondJ >= 0 && second <= maxMatchDistance {
+			out = append(out, correspondence{t: t, f: complex(float64(frame[secondJ].x), float64(frame[secondJ].y)), tIdx: i})
+		}
+	}
+	return out
+}
+
+// similarity is the transform f = a*t + b on complex coordinates (a encodes
+// scale and rotation).
+type similarity struct{ a, b complex128 }
+
+func (s similarity) apply(t complex128) complex128 { return s.a*t + s.b }
+
+// ransacSimilarity fits a similarity transform to the correspondences and
+// returns it with its inlier count, counting each template point at most
+// once. Models scaling outside [minScale, maxScale] are rejected. Pairs are
+// sampled exhaustively when few,
+// otherwise randomly with a fixed seed.
+func ransacSimilarity(ctx context.Context, cs []correspondence, minScale, maxScale float64) (similarity, int) {
+	if len(cs) < 2 {
+		return similarity{}, 0
+	}
+	// inliersOf keeps the closest consistent frame point per template point.
+	inliersOf := func(s similarity) []correspondence {
+		var in []correspondence
+		idx := map[int]int{}
+		for _, c := range cs {
+			d := cmplx.Abs(s.apply(c.t) - c.f)
+			if d > ransacTolerance {
+				continue
+			}
+			if k, ok := idx[c.tIdx]; ok {
+				if d < cmplx.Abs(s.apply(in[k].t)-in[k].f) {
+					in[k] = c
+				}
+				continue
+			}
+			idx[c.tIdx] = len(in)
+			in = append(in, c)
+		}
+		return in
+	}
+	var best similarity
+	bestN := 0
+	try := func(i, j int) {
+		dt := cs[j].t - cs[i].t
+		if cs[i].tIdx == cs[j].tIdx || cmplx.Abs(dt) < 2 {
+			return
+		}
+		a := (cs[j].f - cs[i].f) / dt
+		if scale := cmplx.Abs(a); scale < minScale || scale > maxScale {
+			return
+		}
+		s := similarity{a: a, b: cs[i].f - a*cs[i].t}
+		if n := len(inliersOf(s)); n > bestN {
+			best, bestN = s, n
+		}
+	}
+	if pairs := len(cs) * (len(cs) - 1) / 2; pairs <= ransacIterations {
+		for i := range cs {
+			if ctx.Err() != nil {
+				return similarity{}, 0
+			}
+			for j := i + 1; j < len(cs); j++ {
+				try(i, j)
+			}
+		}
+	} else {
+		rng := rand.New(rand.NewSource(1))
+		for it := 0; it < ransacIterations; it++ {
+			if it%64 == 0 && ctx.Err() != nil {
+				return similarity{}, 0
+			}
+			i, j := rng.Intn(len(cs)), rng.Intn(len(cs))
+			if i != j {
+				try(i, j)
+			}
+		}
+	}
+	if bestN < 2 {
+		return similarity{}, 0
+	}
+	// Least-squares refit on the consensus set.
+	in := inliersOf(best)
+	var mt, mf complex128
+	for _, c := range in {
+		mt += c.t
+		mf += c.f
+	}
+	n := complex(float64(len(in)), 0)
+	mt, mf = mt/n, mf/n
+	var num complex128
+	var den float64
+	for _, c := range in {
+		dt := c.t - mt
+		num += (c.f - mf) * cmplx.Conj(dt)
+		den += real(dt * cmplx.Conj(dt))
+	}
+	if den > 0 {
+		refit := similarity{a: num / complex(den, 0)}
+		refit.b = mf - refit.a*mt
+		if sc := cmplx.Abs(refit.a); sc < minScale || sc > maxScale {
+			return best, bestN
+		}
+		if m := len(inliersOf(refit)); m >= bestN {
+			best, bestN = refit, m
+		}
+	}
+	return best, bestN
+}
+
+// keypointDetector matches FAST corners with steered binary descriptors
+// between the template and the frame and locates the template with a RANSAC
+// similarity fit. Descriptors are rotation invariant; scale changes are
+// covered by describing the template at several scales spanning the
+// configured scale range.
+type keypointDetector struct {
+	levels     []keypointLevel
+	tw, th     int
+	minInliers int
+	up         float64 // internal upsampling of template and frame
+	// minScale and maxScale bound the accepted model scale, with a margin
+	// of one scale step around the configured range.
+	minScale, maxScale float64
+}
+
+// keypointLevel holds the template keypoints detected at one scale.
+type keypointLevel struct {
+	scale float64
+	kps   []keypoint
+}
+
+// keypointScaleStep spaces the template scales; descriptors tolerate about
+// half a step of scale mismatch.
+const keypointScaleStep = 0.1
+
+// smallTemplate is the template side (pixels) below which template and frame
+// are upsampled 2x before detection: the FAST circle and descriptor patch
+// would otherwise span most of a small bobber.
+const smallTemplate = 32
+
+func newKeypointDetector(cfg *config.Config, tmpl image.Image) (TargetDetector, error) {
+	if tmpl == nil {
+		return nil, fmt.Errorf("%s detector: nil template", config.DetectorKeypoint)
+	}
+	local := *cfg
+	if err := local.Validate(); err != nil {
+		return nil, err
+	}
+	tb := tmpl.Bounds()
+	d := &keypointDetector{tw: tb.Dx(), th: tb.Dy(), minInliers: local.KeypointMinInliers, up: 1}
+	d.minScale, d.maxScale = math.Max(0.05, local.MinScale-keypointScaleStep), local.MaxScale+keypointScaleStep
+	if min(d.tw, d.th) < smallTemplate {
+		d.up = 2
+	}
+	plane := newLumaPlane(tmpl)
+	steps := int(math.Ceil((local.MaxScale - local.MinScale) / keypointScaleStep))
+	for i := 0; i <= steps; i++ {
+		scale := local.MinScale + (local.MaxScale-local.MinScale)*float64(i)/float64(max(1, steps))
+		level := plane
+		if math.Abs(scale*d.up-1) > 1e-6 {
+			level = plane.resized(scale * d.up)
+		}
+		if kps := planeKeypoints(level); len(kps) >= d.minInliers {
+			d.levels = append(d.levels, keypointLevel{scale: scale, kps: kps})
+		}
+	}
+	return d, nil
+}
+
+func (d *keypointDetector) Name() string { return config.DetectorKeypoint }
+
+// Detect returns at most one candidate. Score is the fraction of template
+// keypoints confirmed as inliers and Inliers their count. A scale range in
+// lim skips the template scales outside it.
+func (d *keypointDetector) Detect(ctx context.Context, frame *image.RGBA, lim SearchLimits) ([]Candidate, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if frame == nil || len(d.levels) == 0 {
+		return nil, nil
+	}
+	fp := newLumaPlane(frame)
+	if d.up != 1 {
+		fp = fp.resized(d.up)
+	}
+	minScale, maxScale := d.minScale, d.maxScale
+	if lim.MinScale > 0 && lim.MaxScale >= lim.MinScale {
+		minScale = math.Max(minScale, lim.MinScale-keypointScaleStep)
+		maxScale = math.Min(maxScale, lim.MaxScale+keypointScaleStep)
+	}
+	frameKps := planeKeypoints(fp)
+	var model similarity
+	inliers, total := 0, 1
+	for _, level := range d.levels {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if level.scale < minScale || level.scale > maxScale {
+			continue
+		}
+		cs := matchKeypoints(level.kps, level.scale*d.up, frameKps)
+		for i := range cs {
+			cs[i].f = (cs[i].f+complex(0.5, 0.5))/complex(d.up, 0) - complex(0.5, 0.5)
+		}
+		m, n := ransacSimilarity(ctx, cs, minScale, maxScale)
+		if n > inliers {
+			model, inliers, total = m, n, len(level.kps)
+		}
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if inliers < d.minInliers {
+		return nil, nil
+	}
+	origin := complex(float64(frame.Bounds().Min.X), float64(frame.Bounds().Min.Y))
+	minX, minY := math.Inf(1), math.Inf(1)
+	maxX, maxY := math.Inf(-1), math.Inf(-1)
+	for _, c := range []complex128{0, complex(float64(d.tw), 0), complex(0, float64(d.th)), complex(float64(d.tw), float64(d.th))} {
+		p := model.apply(c) + origin
+		minX, maxX = math.Min(minX, real(p)), math.Max(maxX, real(p))
+		minY, maxY = math.Min(minY, imag(p)), math.Max(maxY, imag(p))
+	}
+	centre := model.apply(complex(float64(d.tw)/2, float64(d.th)/2)) + origin
+	bounds := image.Rect(int(math.Floor(minX)), int(math.Floor(minY)), int(math.Ceil(maxX)), int(math.Ceil(maxY)))
+	return []Candidate{{
+		Bounds:  bounds,
+		FX:      float64(bounds.Min.X),
+		FY:      float64(bounds.Min.Y),
+		CX:      real(centre),
+		CY:      imag(centre),
+		Score:   math.Min(1, float64(inliers)/float64(total)),
+		Scale:   cmplx.Abs(model.a),
+		Inliers: inliers,
+	}}, nil
+}
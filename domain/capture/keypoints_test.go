@@ -0,0 +1,63 @@
+package capture
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"math/cmplx"
+	"testing"
+
+	"github.com/soocke/pixel-bot-go/config"
+)
+
+// TestKeypointDetector_RotatedBobber checks that the keypoint detector
+// recovers a bobber seen under moderate rotation, where the upright template
+// no longer lines up pixel for pixel. The water noise is seeded and RANSAC
+// samples with a fixed seed, so every angle must be found.
+func TestKeypointDetector_RotatedBobber(t *testing.T) {
+	tmpl := bobberTemplate(10)
+	det, err := NewTargetDetector(config.DetectorKeypoint, config.DefaultConfig(), tmpl)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, deg := range []float64{-40, -30, -20, -10, 10, 20, 30, 40} {
+		t.Run(fmt.Sprintf("%+.0fdeg", deg), func(t *testing.T) {
+			frame := waterFrame(160, 120, 1)
+			paintBobberRotated(frame, 80, 60, 10, deg*math.Pi/180)
+			cands, err := det.Detect(context.Background(), frame, SearchLimits{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(cands) == 0 {
+				t.Fatalf("rotated bobber not found")
+			}
+			c := cands[0]
+			if math.Hypot(c.CX-80.5, c.CY-60.5) > 3 {
+				t.Fatalf("centre (%.1f,%.1f) want (80.5,60.5)", c.CX, c.CY)
+			}
+			if c.Inliers < config.DefaultConfig().KeypointMinInliers || c.Score <= 0 || c.Score > 1 {
+				t.Fatalf("inliers %d score %.2f", c.Inliers, c.Score)
+			}
+		})
+	}
+}
+
+func TestRansacSimilarity_RejectsOutliers(t *testing.T) {
+	want := similarity{a: cmplx.Rect(1.2, 0.4), b: complex(30, -12)}
+	var cs []correspondence
+	for i, p := range []complex128{1 + 1i, 9 + 2i, 4 + 8i, 12 + 12i, 2 + 14i, 15 + 5i} {
+		cs = append(cs, correspondence{t: p, f: want.apply(p), tIdx: i})
+	}
+	// Outliers, including an ambiguous alternative for template point 0.
+	cs = append(cs,
+		correspondence{t: 3 + 3i, f: 90 + 90i, tIdx: 6},
+		correspondence{t: 7 + 1i, f: -40 + 5i, tIdx: 7},
+		correspondence{t: 1 + 1i, f: 60 + 2i, tIdx: 0})
+	got, inliers := ransacSimilarity(context.Background(), cs, 0.5, 2)
+	if inliers != 6 {
+		t.Fatalf("inliers = %d, want 6", inliers)
+	}
+	if cmplx.Abs(got.a-want.a) > 1e-6 || cmplx.Abs(got.b-want.b) > 1e-6 {
+		t.Fatalf("model %v, want %v", got, want)
+	}
+}
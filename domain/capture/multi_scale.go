@@ -1,6 +1,7 @@
 package capture
 
 import (
+	"context"
 	"image"
 	"runtime"
 	"sync"
@@ -23,12 +24,18 @@ type MultiScaleOptions struct {
 	MinScale    float64
 	MaxScale    float64
 	ScaleStep   float64
+	Mode        MatchMode // image representation compared at each scale
 }
 
 // MultiScaleResult is the best match found across scales.
+// FX/FY hold the sub-pixel top-left of the match and Sharpness the ratio of
+// the best score to the strongest competing peak at the winning scale
+// (values near 1 indicate an ambiguous match).
 type MultiScaleResult struct {
 	X, Y            int
+	FX, FY          float64
 	Score           float64
+	Sharpness       float64
 	Scale           float64
 	Found           bool
 	Duration        time.Duration
@@ -37,22 +44,30 @@ type MultiScaleResult struct {
 
 // MultiScaleMatch is the public, single-call API for multi-scale matching.
 // It forwards to the parallel implementation.
-func MultiScaleMatch(frame *image.RGBA, tmpl image.Image, opts MultiScaleOptions) MultiScaleResult {
-	return MultiScaleMatchParallel(frame, tmpl, opts)
+func MultiScaleMatch(ctx context.Context, frame *image.RGBA, tmpl image.Image, opts MultiScaleOptions) (MultiScaleResult, error) {
+	return MultiScaleMatchParallel(ctx, frame, tmpl, opts)
 }
 
 // MultiScaleMatchParallel evaluates the template at multiple scales in
 // parallel and returns the best match. It supports an optional early-stop
-// threshold in MultiScaleOptions.StopOnScore.
-func MultiScaleMatchParallel(frame *image.RGBA, tmpl image.Image, opts MultiScaleOptions) MultiScaleResult {
+// threshold in MultiScaleOptions.StopOnScore. When ctx is cancelled pending
+// scales are skipped, running scans stop and ctx.Err() is returned.
+func MultiScaleMatchParallel(ctx context.Context, frame *image.RGBA, tmpl image.Image, opts MultiScaleOptions) (MultiScaleResult, error) {
 	if frame == nil || tmpl == nil {
-		return MultiScaleResult{}
+		return MultiScaleResult{}, nil
+	}
+	if err := ctx.Err(); err != nil {
+		return MultiScaleResult{}, err
 	}
 
 	preGray := buildGrayPrecomp(frame)
+	var preOrient *orientPrecomp
+	if opts.Mode == MatchGradient {
+		preOrient = buildOrientPrecomp(preGray)
+	}
 	baseTmpl := getTemplatePrecomp(tmpl)
 	if baseTmpl == nil {
-		return MultiScaleResult{}
+		return MultiScaleResult{}, nil
 	}
 
 	if len(opts.Scales) == 0 {
@@ -81,20 +96,28 @@ func MultiScaleMatchParallel(frame *image.RGBA, tmpl image.Image, opts MultiScal
 		if scale <= 0 {
 			continue
 		}
+		if ctx.Err() != nil {
+			break
+		}
 		wg.Add(1)
 		sem <- struct{}{}
 		go func(factor float64) {
 			defer wg.Done()
 			defer func() { <-sem }()
-			if atomic.LoadInt32(&earlyStop) == 1 {
+			if atomic.LoadInt32(&earlyStop) == 1 || ctx.Err() != nil {
 				return
 			}
 			scaledPc := getScaledTemplatePrecompFromBase(baseTmpl, factor)
 			if scaledPc == nil {
 				return
 			}
-			res := matchTemplateNCCGrayIntegralPre(frame, scaledPc, opts.NCC, preGray)
-			msr := MultiScaleResult{X: res.X, Y: res.Y, Score: res.Score, Scale: factor, Found: res.Found}
+			var res NCCResult
+			if opts.Mode == MatchGradient {
+				res = matchOrientation(ctx, frame, scaledPc.orientation(), opts.NCC, preOrient)
+			} else {
+				res = matchTemplateNCCGrayIntegralPre(ctx, frame, scaledPc, opts.NCC, preGray)
+			}
+			msr := MultiScaleResult{X: res.X, Y: res.Y, FX: res.SubX, FY: res.SubY, Score: res.Score, Sharpness: res.Sharpness, Scale: factor, Found: res.Found}
 			if opts.NCC.DebugTiming && res.Dur > 0 {
 				atomic.AddInt64(&totalDur, res.Dur.Nanoseconds())
 			}
@@ -130,5 +153,8 @@ func MultiScaleMatchParallel(frame *image.RGBA, tmpl image.Image, opts MultiScal
 	if count := atomic.LoadUint64(&scalesCount); count > 0 {
 		best.ScalesEvaluated = int(count)
 	}
-	return best
+	if err := ctx.Err(); err != nil {
+		return MultiScaleResult{}, err
+	}
+	return best, nil
 }
@@ -0,0 +1,28 @@
+package capture
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestMultiScaleMatch_CancelledContext(t *testing.T) {
+	tmpl := blobFrame(21, 21, 10, 10, 3.5)
+	frame := blobFrame(200, 150, 90, 70, 3.5)
+	opts := MultiScaleOptions{MinScale: 0.6, MaxScale: 1.4, ScaleStep: 0.05, NCC: NCCOptions{Threshold: 0.5, Stride: 1}}
+
+	res, err := MultiScaleMatch(context.Background(), frame, tmpl, opts)
+	if err != nil || !res.Found {
+		t.Fatalf("uncancelled search: found=%v err=%v", res.Found, err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	res, err = MultiScaleMatch(ctx, frame, tmpl, opts)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+	if res.Found {
+		t.Fatalf("cancelled search must not report a match")
+	}
+}
@@ -1,6 +1,7 @@
 package capture
 
 import (
+	"context"
 	"image"
 	"math"
 	"sync"
@@ -25,12 +26,22 @@ type templatePrecomp struct {
 	W, H  int
 	meanT float64
 	stdT  float64
+
+	scaledMu sync.Mutex
+	scaled   map[[2]int]*templatePrecomp // scaled variants keyed by [width,height]
+
+	oriOnce sync.Once
+	ori     *orientTemplate // gradient edges for MatchGradient, see orientation
 }
 
-// tmplCacheByDim caches templatePrecomp instances by their [width,height].
+// maxCachedTemplates bounds tmplCache; the cache is reset when it fills up.
+const maxCachedTemplates = 32
+
+// tmplCache caches templatePrecomp instances by template image identity.
+// Template images must not be mutated after their first use.
 var (
-	tmplCacheMu    sync.RWMutex
-	tmplCacheByDim = map[[2]int]*templatePrecomp{}
+	tmplCacheMu sync.RWMutex
+	tmplCache   = map[image.Image]*templatePrecomp{}
 )
 
 // getTemplatePrecomp returns a cached templatePrecomp for tmpl or builds and
@@ -44,9 +55,8 @@ func getTemplatePrecomp(tmpl image.Image) *templatePrecomp {
 	if w == 0 || h == 0 {
 		return nil
 	}
-	key := [2]int{w, h}
 	tmplCacheMu.RLock()
-	pc := tmplCacheByDim[key]
+	pc := tmplCache[tmpl]
 	tmplCacheMu.RUnlock()
 	if pc != nil {
 		return pc
@@ -78,8 +88,11 @@ func getTemplatePrecomp(tmpl image.Image) *templatePrecomp {
 	pc = &templatePrecomp{gray: gray, sumT: sumT, sumT2: sumT2, W: w, H: h, meanT: meanT, stdT: stdT}
 	tmplCacheMu.Lock()
 	// Double-check another goroutine didn't insert meanwhile; keep first to avoid duplicate slices.
-	if existing := tmplCacheByDim[key]; existing == nil {
-		tmplCacheByDim[key] = pc
+	if existing := tmplCache[tmpl]; existing == nil {
+		if len(tmplCache) >= maxCachedTemplates {
+			clear(tmplCache)
+		}
+		tmplCache[tmpl] = pc
 	} else {
 		pc = existing
 	}
@@ -103,9 +116,9 @@ func getScaledTemplatePrecompFromBase(base *templatePrecomp, factor float64) *te
 		return nil
 	}
 	key := [2]int{w, h}
-	tmplCacheMu.RLock()
-	pc := tmplCacheByDim[key]
-	tmplCacheMu.RUnlock()
+	base.scaledMu.Lock()
+	pc := base.scaled[key]
+	base.scaledMu.Unlock()
 	if pc != nil {
 		return pc
 	}
@@ -169,20 +182,24 @@ func getScaledTemplatePrecompFromBase(base *templatePrecomp, factor float64) *te
 		stdT = math.Sqrt(varT)
 	}
 	pc = &templatePrecomp{gray: gray, sumT: sumT, sumT2: sumT2, W: w, H: h, meanT: meanT, stdT: stdT}
-	tmplCacheMu.Lock()
-	if existing := tmplCacheByDim[key]; existing == nil {
-		tmplCacheByDim[key] = pc
+	base.scaledMu.Lock()
+	if base.scaled == nil {
+		base.scaled = map[[2]int]*templatePrecomp{}
+	}
+	if existing := base.scaled[key]; existing == nil {
+		base.scaled[key] = pc
 	} else {
 		pc = existing
 	}
-	tmplCacheMu.Unlock()
+	base.scaledMu.Unlock()
 	return pc
 }
 
 // matchTemplateNCCGrayIntegralPre computes normalized cross-correlation (NCC)
 // between a templatePrecomp and a frame represented by grayPrecomp. It returns
-// the best match position and score according to opts.
-func matchTemplateNCCGrayIntegralPre(frame *image.RGBA, pc *templatePrecomp, opts NCCOptions, pre *grayPrecomp) NCCResult {
+// the best match position and score according to opts. The scan stops early
+// with Score -1 once ctx is cancelled; callers check ctx.Err().
+func matchTemplateNCCGrayIntegralPre(ctx context.Context, frame *image.RGBA, pc *templatePrecomp, opts NCCOptions, pre *grayPrecomp) NCCResult {
 	start := time.Now()
 	res := NCCResult{Score: -1}
 	if frame == nil || pc == nil || pre == nil {
@@ -200,6 +217,9 @@ func matchTemplateNCCGrayIntegralPre(frame *image.RGBA, pc *templatePrecomp, opt
 	if stdT <= 1e-9 {
 		ref := float64(pc.gray[0])
 		for y := 0; y <= H-h; y += opts.Stride {
+			if ctx.Err() != nil {
+				return NCCResult{Score: -1}
+			}
 			for x := 0; x <= W-w; x += opts.Stride {
 				cy := y + h/2
 				cx := x + w/2
@@ -218,6 +238,7 @@ func matchTemplateNCCGrayIntegralPre(frame *image.RGBA, pc *templatePrecomp, opt
 				}
 				if ok {
 					res.X, res.Y = x+fb.Min.X, y+fb.Min.Y
+					res.SubX, res.SubY = float64(res.X), float64(res.Y)
 					res.Score = 1
 					res.Found = true
 					if opts.DebugTiming {
@@ -233,38 +254,46 @@ func matchTemplateNCCGrayIntegralPre(frame *image.RGBA, pc *templatePrecomp, opt
 		return res
 	}
 
+	score := func(x, y int) (float64, bool) {
+		return windowNCC(pre, pc, W, x, y, n, meanT, stdT)
+	}
+	res, ok := scanPeak(ctx, W, H, w, h, fb.Min, opts, score)
+	if !ok {
+		return NCCResult{Score: -1}
+	}
+	if opts.DebugTiming {
+		res.Dur = time.Since(start)
+	}
+	return res
+}
+
+// scanPeak evaluates score at every opts.Stride-th placement of a w x h
+// template in a W x H frame, optionally refines around the coarse peak and
+// returns the best placement offset by origin with its sub-pixel estimate and
+// sharpness. ok is false when ctx was cancelled during the scan.
+func scanPeak(ctx context.Context, W, H, w, h int, origin image.Point, opts NCCOptions, score func(x, y int) (float64, bool)) (res NCCResult, ok bool) {
 	bestX, bestY, bestScore := 0, 0, -1.0
 	stride := opts.Stride
 	if stride <= 0 {
 		stride = 1
 	}
+	peaks := peakTracker{w: w, h: h}
 	for y := 0; y <= H-h; y += stride {
+		if ctx.Err() != nil {
+			return NCCResult{Score: -1}, false
+		}
 		for x := 0; x <= W-w; x += stride {
-			sumF := integralSum(pre.integral, pre.W, x, y, x+w-1, y+h-1)
-			sumF2 := integralSum(pre.integralSq, pre.W, x, y, x+w-1, y+h-1)
-			meanF := sumF / n
-			varF := (sumF2 - sumF*sumF/n) / n
-			if varF <= 1e-9 {
-				continue
+			s, ok := score(x, y)
+			if !ok {
+				s = -1
 			}
-			stdF := math.Sqrt(varF)
-			var sumFT float64
-			for i := 0; i < len(pc.gray); i++ {
-				py := i / w
-				px := i % w
-				sumFT += pre.gray[(y+py)*W+(x+px)] * float64(pc.gray[i])
-			}
-			numer := sumFT - n*meanF*meanT
-			denom := n * stdF * stdT
-			if denom <= 0 {
-				continue
-			}
-			score := numer / denom
-			if score > bestScore {
-				bestScore, bestX, bestY = score, x, y
+			peaks.add(x, y, s)
+			if s > bestScore {
+				bestScore, bestX, bestY = s, x, y
 			}
 		}
 	}
+	refined := stride == 1
 	if opts.Refine && stride > 1 {
 		minY := max(0, bestY-stride)
 		maxY := min(H-h, bestY+stride)
@@ -272,41 +301,136 @@ func matchTemplateNCCGrayIntegralPre(frame *image.RGBA, pc *templatePrecomp, opt
 		maxX := min(W-w, bestX+stride)
 		for y := minY; y <= maxY; y++ {
 			for x := minX; x <= maxX; x++ {
-				sumF := integralSum(pre.integral, pre.W, x, y, x+w-1, y+h-1)
-				sumF2 := integralSum(pre.integralSq, pre.W, x, y, x+w-1, y+h-1)
-				meanF := sumF / n
-				varF := (sumF2 - sumF*sumF/n) / n
-				if varF <= 1e-9 {
-					continue
-				}
-				stdF := math.Sqrt(varF)
-				var sumFT float64
-				for i := 0; i < len(pc.gray); i++ {
-					py := i / w
-					px := i % w
-					sumFT += pre.gray[(y+py)*W+(x+px)] * float64(pc.gray[i])
-				}
-				numer := sumFT - n*meanF*meanT
-				denom := n * stdF * stdT
-				if denom <= 0 {
-					continue
-				}
-				score := numer / denom
-				if score > bestScore {
-					bestScore, bestX, bestY = score, x, y
+				if s, ok := score(x, y); ok && s > bestScore {
+					bestScore, bestX, bestY = s, x, y
 				}
 			}
 		}
+		refined = true
 	}
-	res.X, res.Y, res.Score = bestX+fb.Min.X, bestY+fb.Min.Y, bestScore
+	subX, subY := float64(bestX), float64(bestY)
+	if refined {
+		subX += peakOffset(score, bestX, bestY, bestScore, 1, 0, W-w, H-h)
+		subY += peakOffset(score, bestX, bestY, bestScore, 0, 1, W-w, H-h)
+	}
+	res.X, res.Y, res.Score = bestX+origin.X, bestY+origin.Y, bestScore
+	res.SubX, res.SubY = subX+float64(origin.X), subY+float64(origin.Y)
+	res.Sharpness = peaks.sharpness(bestX, bestY, bestScore)
 	res.Found = bestScore >= opts.Threshold
-	if !res.Found && opts.ReturnBestEven {
-		res.X, res.Y = bestX+fb.Min.X, bestY+fb.Min.Y
+	return res, true
+}
+
+// windowNCC returns the NCC score of the template placed with its top-left
+// corner at (x, y). ok is false for flat frame windows where NCC is undefined.
+func windowNCC(pre *grayPrecomp, pc *templatePrecomp, W, x, y int, n, meanT, stdT float64) (float64, bool) {
+	w, h := pc.W, pc.H
+	sumF := integralSum(pre.integral, pre.W, x, y, x+w-1, y+h-1)
+	sumF2 := integralSum(pre.integralSq, pre.W, x, y, x+w-1, y+h-1)
+	meanF := sumF / n
+	varF := (sumF2 - sumF*sumF/n) / n
+	if varF <= 1e-9 {
+		return 0, false
+	}
+	stdF := math.Sqrt(varF)
+	var sumFT float64
+	for i := 0; i < len(pc.gray); i++ {
+		py := i / w
+		px := i % w
+		sumFT += pre.gray[(y+py)*W+(x+px)] * float64(pc.gray[i])
+	}
+	numer := sumFT - n*meanF*meanT
+	denom := n * stdF * stdT
+	if denom <= 0 {
+		return 0, false
+	}
+	return numer / denom, true
+}
+
+// peakOffset fits a parabola through the scores at the peak and its two
+// neighbours along (dx, dy) and returns the sub-pixel offset of the vertex,
+// limited to [-0.5, 0.5]. It returns 0 at the borders or on a flat/non-peak.
+func peakOffset(score func(x, y int) (float64, bool), x, y int, s0 float64, dx, dy, maxX, maxY int) float64 {
+	if x-dx < 0 || y-dy < 0 || x+dx > maxX || y+dy > maxY {
+		return 0
 	}
-	if opts.DebugTiming {
-		res.Dur = time.Since(start)
+	sm, okm := score(x-dx, y-dy)
+	sp, okp := score(x+dx, y+dy)
+	if !okm || !okp {
+		return 0
 	}
-	return res
+	curv := sm - 2*s0 + sp
+	if curv >= 0 {
+		return 0
+	}
+	off := (sm - sp) / (2 * curv)
+	return math.Max(-0.5, math.Min(0.5, off))
+}
+
+// minCompetingScore bounds the denominator of the sharpness ratio so that a
+// frame without any competing peak yields a large but finite value.
+const minCompetingScore = 0.01
+
+// trackedPeaks is the number of separate peaks a peakTracker remembers.
+const trackedPeaks = 8
+
+// scoredPlacement is a template placement with its score.
+type scoredPlacement struct {
+	x, y int
+	s    float64
+}
+
+// peakTracker follows the strongest peaks of a coarse scan without keeping
+// the score grid. Each tracked peak covers the footprint of a w x h match
+// (half a template in each axis); a placement inside a peak's footprint
+// raises that peak, any other placement starts a new one, replacing the
+// weakest once trackedPeaks are held.
+type peakTracker struct {
+	w, h  int
+	peaks [trackedPeaks]scoredPlacement
+	n     int
+}
+
+// covers reports whether (x, y) lies in the footprint of a match at p.
+func (t *peakTracker) covers(p scoredPlacement, x, y int) bool {
+	return abs(x-p.x) <= t.w/2 && abs(y-p.y) <= t.h/2
+}
+
+// add records the score s of the placement (x, y).
+func (t *peakTracker) add(x, y int, s float64) {
+	weakest := 0
+	for i := 0; i < t.n; i++ {
+		p := &t.peaks[i]
+		if t.covers(*p, x, y) {
+			if s > p.s {
+				*p = scoredPlacement{x, y, s}
+			}
+			return
+		}
+		if p.s < t.peaks[weakest].s {
+			weakest = i
+		}
+	}
+	if t.n < trackedPeaks {
+		t.peaks[t.n] = scoredPlacement{x, y, s}
+		t.n++
+	} else if s > t.peaks[weakest].s {
+		t.peaks[weakest] = scoredPlacement{x, y, s}
+	}
+}
+
+// sharpness returns the ratio of the best score to the strongest tracked
+// peak outside the best match's footprint.
+func (t *peakTracker) sharpness(bestX, bestY int, best float64) float64 {
+	if best <= 0 || t.n == 0 {
+		return 0
+	}
+	second := -1.0
+	for _, p := range t.peaks[:t.n] {
+		if !t.covers(p, bestX, bestY) && p.s > second {
+			second = p.s
+		}
+	}
+	return best / math.Max(second, minCompetingScore)
 }
 
 // buildGrayPrecomp computes per-pixel grayscale values and their summed-area
@@ -375,10 +499,12 @@ type NCCOptions struct {
 
 // NCCResult holds the outcome of a template matching operation.
 type NCCResult struct {
-	X, Y  int
-	Score float64
-	Found bool
-	Dur   time.Duration // Only set if DebugTiming
+	X, Y       int
+	SubX, SubY float64 // sub-pixel top-left from a parabolic fit (equals X/Y without a stride-1 pass)
+	Score      float64
+	Sharpness  float64 // best score divided by the strongest competing peak's score
+	Found      bool
+	Dur        time.Duration // Only set if DebugTiming
 }
 
 // MatchTemplateNCC performs masked NCC on RGBA images. Template pixels with
@@ -404,7 +530,7 @@ func MatchTemplateNCC(frame *image.RGBA, tmpl image.Image, opts NCCOptions) NCCR
 		return NCCResult{Score: -1}
 	}
 	pc := getTemplatePrecomp(tmpl)
-	res := matchTemplateNCCGrayIntegralPre(frame, pc, opts, pre)
+	res := matchTemplateNCCGrayIntegralPre(context.Background(), frame, pc, opts, pre)
 	return res
 }
 func max(a, b int) int {
@@ -419,3 +545,9 @@ func min(a, b int) int {
 	}
 	return b
 }
+func abs(a int) int {
+	if a < 0 {
+		return -a
+	}
+	return a
+}
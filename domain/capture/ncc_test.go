@@ -0,0 +1,102 @@
+package capture
+
+import (
+	"image"
+	"math"
+	"testing"
+)
+
+// blobFrame renders a gaussian blob of the given sigma centred at (cx, cy)
+// (fractional coordinates allowed) on a dark background.
+func blobFrame(w, h int, cx, cy, sigma float64) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			dx, dy := float64(x)-cx, float64(y)-cy
+			v := 20 + 200*math.Exp(-(dx*dx+dy*dy)/(2*sigma*sigma))
+			i := img.PixOffset(x, y)
+			img.Pix[i], img.Pix[i+1], img.Pix[i+2], img.Pix[i+3] = byte(v), byte(v), byte(v), 255
+		}
+	}
+	return img
+}
+
+func TestMatchTemplateNCC_SubPixelPeak(t *testing.T) {
+	tmpl := blobFrame(21, 21, 10, 10, 3.5)
+	for _, off := range []float64{0.0, 0.3, -0.4} {
+		frame := blobFrame(80, 60, 40+off, 25-off, 3.5)
+		res := MatchTemplateNCC(frame, tmpl, NCCOptions{Threshold: 0.5, Stride: 3, Refine: true})
+		if !res.Found {
+			t.Fatalf("offset %.1f: expected match", off)
+		}
+		wantX, wantY := 30+off, 15-off
+		if math.Abs(res.SubX-wantX) > 0.2 || math.Abs(res.SubY-wantY) > 0.2 {
+			t.Fatalf("offset %.1f: sub-pixel (%.2f,%.2f) want (%.2f,%.2f)", off, res.SubX, res.SubY, wantX, wantY)
+		}
+	}
+}
+
+func TestMatchTemplateNCC_SharpnessReflectsCompetingPeaks(t *testing.T) {
+	tmpl := blobFrame(21, 21, 10, 10, 3.5)
+	single := blobFrame(100, 60, 30, 30, 3.5)
+	res := MatchTemplateNCC(single, tmpl, NCCOptions{Threshold: 0.5, Stride: 2, Refine: true})
+	double := blobFrame(100, 60, 30, 30, 3.5)
+	twin := blobFrame(100, 60, 72, 30, 3.5)
+	for i := range double.Pix {
+		if twin.Pix[i] > double.Pix[i] {
+			double.Pix[i] = twin.Pix[i]
+		}
+	}
+	amb := MatchTemplateNCC(double, tmpl, NCCOptions{Threshold: 0.5, Stride: 2, Refine: true})
+	if amb.Sharpness > 1.1 {
+		t.Fatalf("twin peaks should be ambiguous, sharpness %.2f", amb.Sharpness)
+	}
+	if res.Sharpness <= amb.Sharpness*1.5 {
+		t.Fatalf("single peak sharpness %.2f should exceed twin %.2f", res.Sharpness, amb.Sharpness)
+	}
+}
+
+func TestPeakTracker_FindsRunnerUpOutsideFootprint(t *testing.T) {
+	// Two smooth peaks on a noisy floor, scanned row by row like scanPeak.
+	field := func(x, y int) float64 {
+		bump := func(cx, cy int, amp float64) float64 {
+			dx, dy := float64(x-cx), float64(y-cy)
+			return amp * math.Exp(-(dx*dx+dy*dy)/50)
+		}
+		noise := float64((x*7919+y*104729)%97) / 97 * 0.2
+		return math.Max(math.Max(bump(30, 20, 0.95), bump(150, 90, 0.6)), noise)
+	}
+	pt := peakTracker{w: 21, h: 21}
+	best, bx, by := -1.0, 0, 0
+	want := -1.0
+	for y := 0; y < 120; y++ {
+		for x := 0; x < 200; x++ {
+			s := field(x, y)
+			pt.add(x, y, s)
+			if s > best {
+				best, bx, by = s, x, y
+			}
+		}
+	}
+	for y := 0; y < 120; y++ {
+		for x := 0; x < 200; x++ {
+			if (abs(x-bx) > 10 || abs(y-by) > 10) && field(x, y) > want {
+				want = field(x, y)
+			}
+		}
+	}
+	if got := pt.sharpness(bx, by, best); math.Abs(got-best/want) > 1e-9 {
+		t.Fatalf("sharpness %.4f, exhaustive %.4f", got, best/want)
+	}
+}
+
+func TestGetTemplatePrecomp_DistinguishesSameSizedTemplates(t *testing.T) {
+	a := blobFrame(21, 21, 10, 10, 3.5)
+	b := blobFrame(21, 21, 5, 5, 2)
+	if getTemplatePrecomp(a) == getTemplatePrecomp(b) {
+		t.Fatalf("templates of equal size must not share a cache entry")
+	}
+	if getScaledTemplatePrecompFromBase(getTemplatePrecomp(a), 0.8) == getScaledTemplatePrecompFromBase(getTemplatePrecomp(b), 0.8) {
+		t.Fatalf("scaled variants of different templates must not share a cache entry")
+	}
+}
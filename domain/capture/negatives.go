@@ -0,0 +1,335 @@
+package capture
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/png"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// negativeMargin is the score band below the target score in which a
+	// negative match penalises a candidate instead of rejecting it.
+	negativeMargin = 0.05
+	// negativeSlack is the search slack around a candidate, as a fraction of
+	// the compared template's larger side.
+	negativeSlack = 0.25
+	// maxNegativeRetries bounds how often a detector is re-run after all of
+	// its candidates were rejected.
+	maxNegativeRetries = 3
+	// negativeRemoveScore is the score at which a negative counts as showing
+	// the clicked spot in RemoveAt.
+	negativeRemoveScore = 0.9
+)
+
+// negativeItem is one negative template and the file it was loaded from.
+type negativeItem struct {
+	path string
+	img  *image.RGBA
+}
+
+// NegativeLibrary holds negative templates: crops of recurring scene elements
+// (lily pads, rocks, UI icons) that must not be taken for the target. Each
+// template is persisted as a PNG file in the library directory. Safe for
+// concurrent use.
+type NegativeLibrary struct {
+	mu    sync.Mutex
+	dir   string
+	items []negativeItem
+}
+
+// LoadNegativeLibrary reads all PNG files in dir. A missing directory yields
+// an empty library; unreadable files are skipped and reported in the error.
+func LoadNegativeLibrary(dir string) (*NegativeLibrary, error) {
+	l := &NegativeLibrary{dir: dir}
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return l, nil
+	}
+	if err != nil {
+		return l, err
+	}
+	var errs []error
+	for _, e := range entries {
+		if e.IsDir() || !strings.EqualFold(filepath.Ext(e.Name()), ".png") {
+			continue
+		}
+		path := filepath.Join(dir, e.Name())
+		img, err := readPNG(path)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("negative %s: %w", e.Name(), err))
+			continue
+		}
+		l.items = append(l.items, negativeItem{path: path, img: img})
+	}
+	return l, errors.Join(errs...)
+}
+
+func readPNG(path string) (*image.RGBA, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	img, err := png.Decode(f)
+	if err != nil {
+		return nil, err
+	}
+	return toRGBA(img), nil
+}
+
+// toRGBA copies img into a new RGBA image with a zero origin.
+func toRGBA(img image.Image) *image.RGBA {
+	b := img.Bounds()
+	out := image.NewRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	draw.Draw(out, out.Bounds(), img, b.Min, draw.Src)
+	return out
+}
+
+// Len returns the number of negative templates.
+func (l *NegativeLibrary) Len() int {
+	if l == nil {
+		return 0
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return len(l.items)
+}
+
+// Templates returns the negative templates. The images must not be modified.
+func (l *NegativeLibrary) Templates() []image.Image {
+	if l == nil {
+		return nil
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	out := make([]image.Image, len(l.items))
+	for i, it := range l.items {
+		out[i] = it.img
+	}
+	return out
+}
+
+// Add stores a copy of img as a new negative template and writes it to the
+// library directory, returning the file path.
+func (l *NegativeLibrary) Add(img image.Image) (string, error) {
+	if l == nil || img == nil || img.Bounds().Empty() {
+		return "", errors.New("negative library: empty template")
+	}
+	neg := toRGBA(img)
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if err := os.MkdirAll(l.dir, 0o755); err != nil {
+		return "", err
+	}
+	path := filepath.Join(l.dir, fmt.Sprintf("negative_%s.png", time.Now().Format("20060102_150405.000")))
+	f, err := os.Create(path)
+	if err != nil {
+		return "", err
+	}
+	if err := png.Encode(f, neg); err != nil {
+		f.Close()
+		os.Remove(path)
+		return "", err
+	}
+	if err := f.Close(); err != nil {
+		return "", err
+	}
+	l.items = append(l.items, negativeItem{path: path, img: neg})
+	return path, nil
+}
+
+// RemoveAt deletes the negative templates that show the spot around pt in
+// frame, e.g. a bobber that was wrongly suppressed. It returns the number of
+// templates removed.
+func (l *NegativeLibrary) RemoveAt(frame *image.RGBA, pt image.Point) (int, error) {
+	if l == nil || frame == nil {
+		return 0, nil
+	}
+	pre := buildGrayPrecomp(frame)
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	kept := l.items[:0]
+	removed := 0
+	var errs []error
+	for _, it := range l.items {
+		score := bestScoreNear(context.Background(), placements{pre: pre}, frame.Bounds(), getTemplatePrecomp(it.img), float64(pt.X), float64(pt.Y))
+		if score < negativeRemoveScore {
+			kept = append(kept, it)
+			continue
+		}
+		if err := os.Remove(it.path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			errs = append(errs, err)
+			kept = append(kept, it)
+			continue
+		}
+		removed++
+	}
+	clear(l.items[len(kept):])
+	l.items = kept
+	return removed, errors.Join(errs...)
+}
+
+// negativeDetector rejects or penalises candidates of the wrapped detector
+// that correlate with a negative template at least as strongly as with the
+// target template.
+type negativeDetector struct {
+	inner     TargetDetector
+	positive  image.Image
+	negatives []*templatePrecomp
+	mode      MatchMode
+}
+
+// WithNegatives wraps det so candidates resembling a negative template are
+// suppressed. A candidate is compared with the target template (at the
+// candidate's scale) and with every negative (resized by negScale, the size
+// of analysed frames relative to the frames negatives were cropped from),
+// scoring both in mode so they are on the scale of the template search.
+// Candidates whose best negative score reaches the target score are rejected;
+// those within negativeMargin below it lose the difference from their score.
+// When every candidate is rejected the rejected areas are blanked and the
+// detector runs again, so single-candidate detectors can reach the target
+// behind a decoy. det is returned unchanged when there are no negatives.
+func WithNegatives(det TargetDetector, positive image.Image, negatives []image.Image, negScale float64, mode MatchMode) TargetDetector {
+	if det == nil || positive == nil || len(negatives) == 0 {
+		return det
+	}
+	if negScale <= 0 {
+		negScale = 1
+	}
+	d := &negativeDetector{inner: det, positive: positive, mode: mode}
+	for _, neg := range negatives {
+		if pc := getScaledTemplatePrecompFromBase(getTemplatePrecomp(neg), negScale); pc != nil {
+			d.negatives = append(d.negatives, pc)
+		}
+	}
+	if len(d.negatives) == 0 {
+		return det
+	}
+	return d
+}
+
+func (d *negativeDetector) Name() string { return d.inner.Name() }
+
+func (d *negativeDetector) Detect(ctx context.Context, frame *image.RGBA, lim SearchLimits) ([]Candidate, error) {
+	if frame == nil {
+		return nil, nil
+	}
+	pl := placements{pre: buildGrayPrecomp(frame)}
+	if d.mode == MatchGradient {
+		pl.ori = buildOrientPrecomp(pl.pre)
+	}
+	work := frame
+	for attempt := 0; ; attempt++ {
+		cands, err := d.inner.Detect(ctx, work, lim)
+		if err != nil {
+			return nil, err
+		}
+		var kept []Candidate
+		var rejected []image.Rectangle
+		for _, c := range cands {
+			pos, neg := d.scores(ctx, pl, frame.Bounds(), c)
+			switch {
+			case neg >= pos:
+				rejected = append(rejected, c.Bounds)
+			case neg > pos-negativeMargin:
+				c.Score -= neg - (pos - negativeMargin)
+				kept = append(kept, c)
+			default:
+				kept = append(kept, c)
+			}
+		}
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if len(kept) > 0 || len(rejected) == 0 || attempt == maxNegativeRetries {
+			sort.SliceStable(kept, func(i, j int) bool { return kept[i].Score > kept[j].Score })
+			return kept, nil
+		}
+		if work == frame {
+			work = toRGBA(frame)
+			work.Rect = frame.Bounds()
+		}
+		for _, r := range rejected {
+			blank(work, r)
+		}
+	}
+}
+
+// scores returns the best target and negative template scores around c.
+func (d *negativeDetector) scores(ctx context.Context, pl placements, fb image.Rectangle, c Candidate) (pos, neg float64) {
+	base := getTemplatePrecomp(d.positive)
+	scale := c.Scale
+	if scale <= 0 && base != nil {
+		scale = float64(max(c.Bounds.Dx(), c.Bounds.Dy())) / float64(max(base.W, base.H))
+	}
+	pos = bestScoreNear(ctx, pl, fb, getScaledTemplatePrecompFromBase(base, scale), c.CX, c.CY)
+	neg = -1
+	for _, pc := range d.negatives {
+		neg = math.Max(neg, bestScoreNear(ctx, pl, fb, pc, c.CX, c.CY))
+	}
+	return pos, neg
+}
+
+// bestScoreNear returns the best score of pc centred within a small slack
+// around (cx, cy) of the frame with bounds fb, or -1 when the template does
+// not fit or has no score in pl's mode.
+func bestScoreNear(ctx context.Context, pl placements, fb image.Rectangle, pc *templatePrecomp, cx, cy float64) float64 {
+	score := pl.scorer(pc)
+	if score == nil {
+		return -1
+	}
+	pre := pl.pre
+	slack := max(2, int(negativeSlack*float64(max(pc.W, pc.H))))
+	x0 := int(cx) - pc.W/2 - slack - fb.Min.X
+	y0 := int(cy) - pc.H/2 - slack - fb.Min.Y
+	r := image.Rect(x0, y0, x0+pc.W+2*slack, y0+pc.H+2*slack).Intersect(image.Rect(0, 0, pre.W, pre.H))
+	if r.Dx() < pc.W || r.Dy() < pc.H {
+		return -1
+	}
+	best := -1.0
+	for y := r.Min.Y; y <= r.Max.Y-pc.H; y++ {
+		if ctx.Err() != nil {
+			return -1
+		}
+		for x := r.Min.X; x <= r.Max.X-pc.W; x++ {
+			if s, ok := score(x, y); ok && s > best {
+				best = s
+			}
+		}
+	}
+	return best
+}
+
+// blank fills r in img with its mean colour so it no longer matches anything.
+func blank(img *image.RGBA, r image.Rectangle) {
+	r = r.Intersect(img.Bounds())
+	if r.Empty() {
+		return
+	}
+	var sum [3]int
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			i := img.PixOffset(x, y)
+			sum[0] += int(img.Pix[i])
+			sum[1] += int(img.Pix[i+1])
+			sum[2] += int(img.Pix[i+2])
+		}
+	}
+	n := r.Dx() * r.Dy()
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			i := img.PixOffset(x, y)
+			img.Pix[i], img.Pix[i+1], img.Pix[i+2], img.Pix[i+3] = uint8(sum[0]/n), uint8(sum[1]/n), uint8(sum[2]/n), 255
+		}
+	}
+}
@@ -0,0 +1,136 @@
+package capture
+
+import (
+	"context"
+	"image"
+	"image/color"
+	"math"
+	"math/rand"
+	"os"
+	"testing"
+
+	"github.com/soocke/pixel-bot-go/config"
+)
+
+// decoyScene returns a frame with a glare-speckled bobber at (50, 60) and a
+// two-tone disc (a lily pad with a bright rim) at (150, 60) that matches the
+// template better than the speckled bobber does.
+func decoyScene() (frame *image.RGBA, bobber, decoy image.Rectangle) {
+	frame = waterFrame(200, 120, 3)
+	paintBobber(frame, 50, 60, 10)
+	rng := rand.New(rand.NewSource(5))
+	for y := 48; y < 73; y++ {
+		for x := 38; x < 63; x++ {
+			i := frame.PixOffset(x, y)
+			n := rng.Intn(161) - 80
+			for k := 0; k < 3; k++ {
+				frame.Pix[i+k] = uint8(max(0, min(255, int(frame.Pix[i+k])+n)))
+			}
+		}
+	}
+	top, rim := color.RGBA{60, 70, 40, 255}, color.RGBA{210, 220, 150, 255}
+	for y := 50; y <= 70; y++ {
+		for x := 140; x <= 160; x++ {
+			if (x-150)*(x-150)+(y-60)*(y-60) > 100 {
+				continue
+			}
+			if y > 60 {
+				frame.SetRGBA(x, y, rim)
+			} else {
+				frame.SetRGBA(x, y, top)
+			}
+		}
+	}
+	return frame, image.Rect(40, 50, 61, 71), image.Rect(139, 49, 162, 72)
+}
+
+func TestWithNegatives_RejectsDecoy(t *testing.T) {
+	frame, bobber, decoy := decoyScene()
+	tmpl := bobberTemplate(10)
+	cfg := config.DefaultConfig()
+	cfg.Stride = 1
+	det, err := NewTargetDetector(config.DetectorNCC, cfg, tmpl)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if WithNegatives(det, tmpl, nil, 1, MatchLuma) != det {
+		t.Fatalf("no negatives must return the detector unchanged")
+	}
+	centre := func(c Candidate) image.Point { return image.Pt(int(c.CX), int(c.CY)) }
+	cands, err := det.Detect(context.Background(), frame, SearchLimits{})
+	if err != nil || len(cands) == 0 || !centre(cands[0]).In(decoy) {
+		t.Fatalf("precondition: decoy should outscore the bobber, got %+v (%v)", cands, err)
+	}
+	filtered := WithNegatives(det, tmpl, []image.Image{frame.SubImage(decoy)}, 1, MatchLuma)
+	if filtered.Name() != det.Name() {
+		t.Fatalf("Name() = %q", filtered.Name())
+	}
+	cands, err = filtered.Detect(context.Background(), frame, SearchLimits{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cands) == 0 || !centre(cands[0]).In(bobber) {
+		t.Fatalf("want bobber at %v, got %+v", bobber, cands)
+	}
+}
+
+func TestNegativeLibrary_AddLoadRemove(t *testing.T) {
+	frame, _, decoy := decoyScene()
+	dir := t.TempDir()
+	lib, err := LoadNegativeLibrary(dir)
+	if err != nil || lib.Len() != 0 {
+		t.Fatalf("empty dir: len %d err %v", lib.Len(), err)
+	}
+	path, err := lib.Add(frame.SubImage(decoy))
+	if err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := LoadNegativeLibrary(dir)
+	if err != nil || loaded.Len() != 1 {
+		t.Fatalf("reload: len %d err %v", loaded.Len(), err)
+	}
+	if got := loaded.Templates()[0].Bounds().Size(); got != decoy.Size() {
+		t.Fatalf("template size %v want %v", got, decoy.Size())
+	}
+	if n, err := loaded.RemoveAt(frame, image.Pt(50, 60)); n != 0 || err != nil {
+		t.Fatalf("bobber click removed %d (%v)", n, err)
+	}
+	if n, err := loaded.RemoveAt(frame, image.Pt(150, 60)); n != 1 || err != nil {
+		t.Fatalf("decoy click removed %d (%v)", n, err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Fatalf("negative file still present: %v", err)
+	}
+}
+
+// TestWithNegatives_ScoresInMatchMode checks that candidates of a gradient
+// search are compared with the templates by gradient score, the scale of the
+// candidate's own score.
+func TestWithNegatives_ScoresInMatchMode(t *testing.T) {
+	tmpl := bobberTemplate(10)
+	frame := waterFrame(160, 120, 3)
+	paintBobber(frame, 90, 55, 10)
+	frame = darken(frame, 2.2, 0.4)
+	cfg := config.DefaultConfig()
+	cfg.MatchMode = config.MatchModeGradient
+	cfg.Threshold, cfg.Stride, cfg.MinScale, cfg.MaxScale, cfg.ScaleStep = 0.73, 2, 0.9, 1.1, 0.05
+	det, err := NewTargetDetector(config.DetectorNCC, cfg, tmpl)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cands, err := det.Detect(context.Background(), frame, SearchLimits{})
+	if err != nil || len(cands) == 0 {
+		t.Fatalf("precondition: gradient search should find the bobber, got %+v (%v)", cands, err)
+	}
+	// The bobber itself as a negative must score exactly like the target.
+	c := cands[0]
+	d := WithNegatives(det, tmpl, []image.Image{frame.SubImage(c.Bounds)}, 1, MatchGradient).(*negativeDetector)
+	pl := placements{pre: buildGrayPrecomp(frame), ori: buildOrientPrecomp(buildGrayPrecomp(frame))}
+	pos, neg := d.scores(context.Background(), pl, frame.Bounds(), c)
+	if math.Abs(pos-c.Score) > 1e-6 || neg < pos {
+		t.Fatalf("template score %.3f, negative score %.3f, candidate score %.3f", pos, neg, c.Score)
+	}
+	if cands, _ := d.Detect(context.Background(), frame, SearchLimits{}); len(cands) != 0 {
+		t.Fatalf("bobber matching its own negative kept: %+v", cands)
+	}
+}
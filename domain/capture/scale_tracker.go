@@ -0,0 +1,94 @@
+package capture
+
+import (
+	"math"
+	"sort"
+	"sync"
+)
+
+const (
+	defaultScaleHistory  = 8
+	defaultMissesToWiden = 2
+	scaleTrackerMinHits  = 3
+)
+
+// ScaleTracker learns the winning template scale of successful searches and
+// narrows the scale range around it. After MissesToWiden consecutive search
+// cycles without a match the history is dropped and the full configured range
+// is used again. Safe for concurrent use.
+type ScaleTracker struct {
+	mu            sync.Mutex
+	history       []float64
+	next          int
+	misses        int
+	size          int
+	missesToWiden int
+}
+
+// NewScaleTracker returns a tracker remembering historySize winning scales.
+// Zero arguments select defaults.
+func NewScaleTracker(historySize, missesToWiden int) *ScaleTracker {
+	if historySize <= 0 {
+		historySize = defaultScaleHistory
+	}
+	if missesToWiden <= 0 {
+		missesToWiden = defaultMissesToWiden
+	}
+	return &ScaleTracker{size: historySize, missesToWiden: missesToWiden}
+}
+
+// Hit records the scale of a successful match.
+func (t *ScaleTracker) Hit(scale float64) {
+	if t == nil || scale <= 0 {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.misses = 0
+	if len(t.history) < t.size {
+		t.history = append(t.history, scale)
+		return
+	}
+	t.history[t.next] = scale
+	t.next = (t.next + 1) % t.size
+}
+
+// Miss records a search cycle that ended without a match.
+func (t *ScaleTracker) Miss() {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.misses++
+	if t.misses >= t.missesToWiden {
+		t.history = t.history[:0]
+		t.next = 0
+	}
+}
+
+// Range returns the scale range to search given the configured bounds. The
+// range is centred on the median winning scale and spans the observed spread
+// plus one step on each side (at least two steps). narrowed is false when
+// there is not enough history and the configured range is returned.
+func (t *ScaleTracker) Range(minScale, maxScale, step float64) (lo, hi float64, narrowed bool) {
+	if t == nil || step <= 0 {
+		return minScale, maxScale, false
+	}
+	t.mu.Lock()
+	hist := append([]float64(nil), t.history...)
+	t.mu.Unlock()
+	if len(hist) < scaleTrackerMinHits {
+		return minScale, maxScale, false
+	}
+	sort.Float64s(hist)
+	median := hist[len(hist)/2]
+	spread := math.Max(median-hist[0], hist[len(hist)-1]-median)
+	half := math.Max(spread+step, 2*step)
+	lo = math.Max(minScale, median-half)
+	hi = math.Min(maxScale, median+half)
+	if hi < lo {
+		return minScale, maxScale, false
+	}
+	return lo, hi, lo > minScale || hi < maxScale
+}
@@ -0,0 +1,43 @@
+package capture
+
+import "testing"
+
+func TestScaleTracker_NarrowsAroundWinningScale(t *testing.T) {
+	tr := NewScaleTracker(0, 0)
+	if lo, hi, narrowed := tr.Range(0.6, 1.4, 0.05); narrowed || lo != 0.6 || hi != 1.4 {
+		t.Fatalf("empty tracker must return configured range, got %.2f-%.2f narrowed=%v", lo, hi, narrowed)
+	}
+	for _, s := range []float64{1.0, 1.05, 1.0} {
+		tr.Hit(s)
+	}
+	lo, hi, narrowed := tr.Range(0.6, 1.4, 0.05)
+	if !narrowed {
+		t.Fatalf("expected narrowed range")
+	}
+	if lo > 0.95 || hi < 1.05 {
+		t.Fatalf("range %.2f-%.2f must contain observed scales", lo, hi)
+	}
+	if lo < 0.85 || hi > 1.15 {
+		t.Fatalf("range %.2f-%.2f too wide", lo, hi)
+	}
+}
+
+func TestScaleTracker_WidensAfterConsecutiveMisses(t *testing.T) {
+	tr := NewScaleTracker(4, 2)
+	for i := 0; i < 4; i++ {
+		tr.Hit(1.2)
+	}
+	tr.Miss()
+	if _, _, narrowed := tr.Range(0.6, 1.4, 0.05); !narrowed {
+		t.Fatalf("single miss should keep the narrowed range")
+	}
+	tr.Hit(1.2)
+	tr.Miss()
+	if _, _, narrowed := tr.Range(0.6, 1.4, 0.05); !narrowed {
+		t.Fatalf("misses interrupted by a hit are not consecutive")
+	}
+	tr.Miss()
+	if lo, hi, narrowed := tr.Range(0.6, 1.4, 0.05); narrowed || lo != 0.6 || hi != 1.4 {
+		t.Fatalf("expected full range after consecutive misses, got %.2f-%.2f", lo, hi)
+	}
+}
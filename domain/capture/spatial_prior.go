@@ -0,0 +1,174 @@
+package capture
+
+import (
+	"encoding/json"
+	"image"
+	"os"
+	"sort"
+	"sync"
+)
+
+const (
+	defaultPriorBins     = 24
+	priorMinSamples      = 5
+	priorDecayTotal      = 400 // halve counts beyond this so the prior follows camera changes
+	defaultPriorHotShare = 0.8
+)
+
+// SpatialPrior accumulates a 2D histogram of confirmed target positions so
+// searches can visit the likely landing band first. Positions are normalised
+// to the frame size, so the prior survives AnalysisScale changes, but not a
+// moved capture area or another target; see Bind. Safe for concurrent use.
+type SpatialPrior struct {
+	mu     sync.Mutex
+	bins   int
+	counts []float64
+	total  float64
+	key    string // capture area and target the positions were learned for
+}
+
+// spatialPriorFile is the JSON representation persisted to disk.
+type spatialPriorFile struct {
+	Bins   int       `json:"bins"`
+	Counts []float64 `json:"counts"`
+	Total  float64   `json:"total"`
+	Key    string    `json:"key,omitempty"`
+}
+
+// NewSpatialPrior returns an empty prior with bins x bins cells.
+func NewSpatialPrior(bins int) *SpatialPrior {
+	if bins <= 0 {
+		bins = defaultPriorBins
+	}
+	return &SpatialPrior{bins: bins, counts: make([]float64, bins*bins)}
+}
+
+// Add records a confirmed position pt inside bounds.
+func (p *SpatialPrior) Add(pt image.Point, bounds image.Rectangle) {
+	if p == nil || bounds.Empty() || !pt.In(bounds) {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	bx := (pt.X - bounds.Min.X) * p.bins / bounds.Dx()
+	by := (pt.Y - bounds.Min.Y) * p.bins / bounds.Dy()
+	p.counts[by*p.bins+bx]++
+	p.total++
+	if p.total > priorDecayTotal {
+		for i := range p.counts {
+			p.counts[i] /= 2
+		}
+		p.total /= 2
+	}
+}
+
+// Bind ties the prior to the capture area and target identified by key.
+// Positions learned under another key point elsewhere on screen, so they are
+// forgotten; Bind reports whether that happened.
+func (p *SpatialPrior) Bind(key string) bool {
+	if p == nil {
+		return false
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.key == key {
+		return false
+	}
+	p.key = key
+	if p.total == 0 {
+		return false
+	}
+	clear(p.counts)
+	p.total = 0
+	return true
+}
+
+// Samples returns the (decayed) number of recorded positions.
+func (p *SpatialPrior) Samples() int {
+	if p == nil {
+		return 0
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return int(p.total)
+}
+
+// Ready reports whether enough positions were recorded to trust the prior.
+func (p *SpatialPrior) Ready() bool { return p.Samples() >= priorMinSamples }
+
+// HotRegion returns the bounding rectangle, mapped into bounds, of the most
+// populated cells holding at least share (0-1] of all recorded positions.
+// It returns false until the prior is Ready.
+func (p *SpatialPrior) HotRegion(bounds image.Rectangle, share float64) (image.Rectangle, bool) {
+	if p == nil || bounds.Empty() {
+		return image.Rectangle{}, false
+	}
+	if share <= 0 || share > 1 {
+		share = defaultPriorHotShare
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.total < priorMinSamples {
+		return image.Rectangle{}, false
+	}
+	order := make([]int, 0, len(p.counts))
+	for i, c := range p.counts {
+		if c > 0 {
+			order = append(order, i)
+		}
+	}
+	sort.Slice(order, func(a, b int) bool { return p.counts[order[a]] > p.counts[order[b]] })
+	minX, minY, maxX, maxY := p.bins, p.bins, -1, -1
+	var acc float64
+	for _, i := range order {
+		bx, by := i%p.bins, i/p.bins
+		minX, minY = min(minX, bx), min(minY, by)
+		maxX, maxY = max(maxX, bx), max(maxY, by)
+		acc += p.counts[i]
+		if acc >= share*p.total {
+			break
+		}
+	}
+	W, H := bounds.Dx(), bounds.Dy()
+	r := image.Rect(
+		bounds.Min.X+minX*W/p.bins,
+		bounds.Min.Y+minY*H/p.bins,
+		bounds.Min.X+(maxX+1)*W/p.bins,
+		bounds.Min.Y+(maxY+1)*H/p.bins,
+	)
+	return r, true
+}
+
+// LoadSpatialPrior reads a prior from path. A missing file yields an empty prior.
+func LoadSpatialPrior(path string) (*SpatialPrior, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return NewSpatialPrior(0), nil
+		}
+		return NewSpatialPrior(0), err
+	}
+	var f spatialPriorFile
+	if err := json.Unmarshal(data, &f); err != nil {
+		return NewSpatialPrior(0), err
+	}
+	if f.Bins <= 0 || len(f.Counts) != f.Bins*f.Bins {
+		return NewSpatialPrior(0), nil
+	}
+	return &SpatialPrior{bins: f.Bins, counts: f.Counts, total: f.Total, key: f.Key}, nil
+}
+
+// Save writes the prior to path in JSON format.
+func (p *SpatialPrior) Save(path string) error {
+	if p == nil {
+		return nil
+	}
+	p.mu.Lock()
+	f := spatialPriorFile{Bins: p.bins, Counts: append([]float64(nil), p.counts...), Total: p.total, Key: p.key}
+	p.mu.Unlock()
+	data, err := json.Marshal(f)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
@@ -0,0 +1,91 @@
+package capture
+
+import (
+	"image"
+	"path/filepath"
+	"testing"
+)
+
+func TestSpatialPrior_HotRegionCoversLandingBand(t *testing.T) {
+	bounds := image.Rect(0, 0, 240, 240)
+	p := NewSpatialPrior(24)
+	if _, ok := p.HotRegion(bounds, 0.8); ok {
+		t.Fatalf("empty prior must not report a hot region")
+	}
+	// Casts land around (120,160) with one outlier.
+	for i := 0; i < 9; i++ {
+		p.Add(image.Pt(110+i*2, 155+i%3), bounds)
+	}
+	p.Add(image.Pt(5, 5), bounds)
+	r, ok := p.HotRegion(bounds, 0.8)
+	if !ok {
+		t.Fatalf("expected hot region after %d samples", p.Samples())
+	}
+	if !image.Pt(120, 158).In(r) {
+		t.Fatalf("hot region %v misses landing band", r)
+	}
+	if image.Pt(5, 5).In(r) {
+		t.Fatalf("hot region %v should exclude outlier", r)
+	}
+	if r.Dx()*r.Dy() > bounds.Dx()*bounds.Dy()/4 {
+		t.Fatalf("hot region %v too large", r)
+	}
+}
+
+func TestSpatialPrior_SaveLoadRoundTrip(t *testing.T) {
+	bounds := image.Rect(0, 0, 100, 50)
+	p := NewSpatialPrior(10)
+	for i := 0; i < 6; i++ {
+		p.Add(image.Pt(70, 20), bounds)
+	}
+	path := filepath.Join(t.TempDir(), "prior.json")
+	if err := p.Save(path); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+	q, err := LoadSpatialPrior(path)
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if q.Samples() != 6 {
+		t.Fatalf("expected 6 samples, got %d", q.Samples())
+	}
+	r1, _ := p.HotRegion(bounds, 1)
+	r2, _ := q.HotRegion(bounds, 1)
+	if r1 != r2 {
+		t.Fatalf("hot region changed after reload: %v vs %v", r1, r2)
+	}
+	if _, err := LoadSpatialPrior(filepath.Join(t.TempDir(), "missing.json")); err != nil {
+		t.Fatalf("missing file should yield empty prior, got %v", err)
+	}
+}
+
+func TestSpatialPrior_BindForgetsOtherAreas(t *testing.T) {
+	bounds := image.Rect(0, 0, 100, 50)
+	p := NewSpatialPrior(10)
+	if p.Bind("area a") {
+		t.Fatalf("empty prior reported forgotten positions")
+	}
+	for i := 0; i < 6; i++ {
+		p.Add(image.Pt(70, 20), bounds)
+	}
+	if p.Bind("area a") || p.Samples() != 6 {
+		t.Fatalf("rebinding the same area dropped positions")
+	}
+	path := filepath.Join(t.TempDir(), "prior.json")
+	if err := p.Save(path); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+	q, err := LoadSpatialPrior(path)
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if q.Bind("area a") || q.Samples() != 6 {
+		t.Fatalf("reloaded prior lost its area")
+	}
+	if !q.Bind("area b") || q.Samples() != 0 {
+		t.Fatalf("positions of another area kept: %d samples", q.Samples())
+	}
+	if _, ok := q.HotRegion(bounds, 1); ok {
+		t.Fatalf("hot region reported after the area changed")
+	}
+}
@@ -0,0 +1,147 @@
+package capture
+
+import (
+	"image"
+)
+
+// SplashOptions configures pre/post cast frame differencing.
+// Zero values select defaults suitable for typical selection sizes.
+type SplashOptions struct {
+	DiffThreshold int     // per-pixel luma difference counted as change (default 24)
+	CellSize      int     // side of the grid cells used to group changed pixels (default 8)
+	CellFill      float64 // fraction of changed pixels for a cell to be active (default 0.15)
+	MinArea       int     // minimum changed pixels for a region to qualify (default 12)
+	MaxAreaFrac   float64 // regions covering more of the frame are ignored as global change (default 0.25)
+}
+
+// SplashResult describes the dominant new object between two frames.
+// Region and X/Y are expressed in the coordinate space of the post-cast frame.
+type SplashResult struct {
+	Region image.Rectangle
+	X, Y   int
+	Area   int
+	Found  bool
+}
+
+func (o SplashOptions) withDefaults() SplashOptions {
+	if o.DiffThreshold <= 0 {
+		o.DiffThreshold = 24
+	}
+	if o.CellSize <= 0 {
+		o.CellSize = 8
+	}
+	if o.CellFill <= 0 || o.CellFill > 1 {
+		o.CellFill = 0.15
+	}
+	if o.MinArea <= 0 {
+		o.MinArea = 12
+	}
+	if o.MaxAreaFrac <= 0 || o.MaxAreaFrac > 1 {
+		o.MaxAreaFrac = 0.25
+	}
+	return o
+}
+
+// LocateSplash diffs frames captured just before and just after a cast and
+// returns the most prominent compact region that changed, which is assumed to
+// be where the bobber landed. Both frames must have the same dimensions.
+// Changed pixels are grouped on a coarse grid and connected cells form
+// candidate regions; regions larger than MaxAreaFrac of the frame (camera or
+// lighting changes) are discarded.
+func LocateSplash(before, after *image.RGBA, opts SplashOptions) SplashResult {
+	if before == nil || after == nil {
+		return SplashResult{}
+	}
+	bb, ab := before.Bounds(), after.Bounds()
+	W, H := ab.Dx(), ab.Dy()
+	if W <= 0 || H <= 0 || bb.Dx() != W || bb.Dy() != H {
+		return SplashResult{}
+	}
+	opts = opts.withDefaults()
+	cs := opts.CellSize
+	cw, ch := (W+cs-1)/cs, (H+cs-1)/cs
+	counts := make([]int, cw*ch)
+	sumX := make([]int, cw*ch)
+	sumY := make([]int, cw*ch)
+	for y := 0; y < H; y++ {
+		rowB := before.Pix[y*before.Stride : y*before.Stride+W*4]
+		rowA := after.Pix[y*after.Stride : y*after.Stride+W*4]
+		cy := y / cs
+		for x := 0; x < W; x++ {
+			i := x * 4
+			lb := (77*int(rowB[i]) + 150*int(rowB[i+1]) + 29*int(rowB[i+2])) >> 8
+			la := (77*int(rowA[i]) + 150*int(rowA[i+1]) + 29*int(rowA[i+2])) >> 8
+			d := la - lb
+			if d < 0 {
+				d = -d
+			}
+			if d <= opts.DiffThreshold {
+				continue
+			}
+			c := cy*cw + x/cs
+			counts[c]++
+			sumX[c] += x
+			sumY[c] += y
+		}
+	}
+	need := int(float64(cs*cs)*opts.CellFill + 0.5)
+	if need < 1 {
+		need = 1
+	}
+	active := make([]bool, cw*ch)
+	for i, n := range counts {
+		active[i] = n >= need
+	}
+
+	best := SplashResult{}
+	maxArea := int(float64(W*H) * opts.MaxAreaFrac)
+	visited := make([]bool, cw*ch)
+	stack := make([]int, 0, 64)
+	for start := range active {
+		if !active[start] || visited[start] {
+			continue
+		}
+		// Flood fill over 8-connected active cells.
+		var area, sx, sy int
+		minCX, minCY, maxCX, maxCY := cw, ch, -1, -1
+		stack = append(stack[:0], start)
+		visited[start] = true
+		for len(stack) > 0 {
+			c := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			cx, cy := c%cw, c/cw
+			area += counts[c]
+			sx += sumX[c]
+			sy += sumY[c]
+			minCX, minCY = min(minCX, cx), min(minCY, cy)
+			maxCX, maxCY = max(maxCX, cx), max(maxCY, cy)
+			for dy := -1; dy <= 1; dy++ {
+				for dx := -1; dx <= 1; dx++ {
+					nx, ny := cx+dx, cy+dy
+					if nx < 0 || ny < 0 || nx >= cw || ny >= ch {
+						continue
+					}
+					n := ny*cw + nx
+					if active[n] && !visited[n] {
+						visited[n] = true
+						stack = append(stack, n)
+					}
+				}
+			}
+		}
+		region := image.Rect(minCX*cs, minCY*cs, min((maxCX+1)*cs, W), min((maxCY+1)*cs, H))
+		if area < opts.MinArea || region.Dx()*region.Dy() > maxArea {
+			continue
+		}
+		if area > best.Area {
+			best = SplashResult{
+				Region: region.Add(ab.Min),
+				X:      sx/area + ab.Min.X,
+				Y:      sy/area + ab.Min.Y,
+				Area:   area,
+				Found:  true,
+			}
+		}
+	}
+	return best
+}
@@ -0,0 +1,69 @@
+package capture
+
+import (
+	"image"
+	"testing"
+)
+
+// fillFrame returns a w x h RGBA frame filled with a uniform grey level.
+func fillFrame(w, h int, lum byte) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for i := 0; i < len(img.Pix); i += 4 {
+		img.Pix[i], img.Pix[i+1], img.Pix[i+2], img.Pix[i+3] = lum, lum, lum, 255
+	}
+	return img
+}
+
+// paintRect sets the RGB values of r (clamped to the frame) to the given colour.
+func paintRect(img *image.RGBA, r image.Rectangle, cr, cg, cb byte) {
+	r = r.Intersect(img.Bounds())
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			i := img.PixOffset(x, y)
+			img.Pix[i], img.Pix[i+1], img.Pix[i+2] = cr, cg, cb
+		}
+	}
+}
+
+func TestLocateSplash_FindsNewObject(t *testing.T) {
+	before := fillFrame(160, 120, 60)
+	after := fillFrame(160, 120, 60)
+	paintRect(after, image.Rect(100, 40, 116, 56), 200, 40, 40)
+	res := LocateSplash(before, after, SplashOptions{})
+	if !res.Found {
+		t.Fatalf("expected splash region")
+	}
+	if res.X < 104 || res.X > 112 || res.Y < 44 || res.Y > 52 {
+		t.Fatalf("unexpected centroid (%d,%d)", res.X, res.Y)
+	}
+	if !image.Pt(108, 48).In(res.Region) {
+		t.Fatalf("region %v does not contain object centre", res.Region)
+	}
+}
+
+func TestLocateSplash_PrefersCompactRegionOverGlobalChange(t *testing.T) {
+	before := fillFrame(160, 120, 60)
+	after := fillFrame(160, 120, 60)
+	// Large change (e.g. character animation / camera shift) covering most of the frame.
+	paintRect(after, image.Rect(0, 0, 100, 120), 120, 120, 120)
+	// Small new object outside it.
+	paintRect(after, image.Rect(130, 50, 142, 62), 250, 250, 250)
+	res := LocateSplash(before, after, SplashOptions{})
+	if !res.Found {
+		t.Fatalf("expected compact region to be found")
+	}
+	if res.Region.Dx() > 16 || res.Region.Dy() > 16 {
+		t.Fatalf("expected compact region, got %v", res.Region)
+	}
+}
+
+func TestLocateSplash_NoChange(t *testing.T) {
+	before := fillFrame(64, 64, 90)
+	after := fillFrame(64, 64, 92)
+	if res := LocateSplash(before, after, SplashOptions{}); res.Found {
+		t.Fatalf("unexpected splash on near-identical frames: %+v", res)
+	}
+	if res := LocateSplash(before, fillFrame(32, 64, 90), SplashOptions{}); res.Found {
+		t.Fatalf("mismatched frame sizes must not match")
+	}
+}
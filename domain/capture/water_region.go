@@ -0,0 +1,278 @@
+package capture
+
+import (
+	"image"
+	"math"
+)
+
+const (
+	waterCell = 16 // analysis cell size in pixels
+	// waterHueMin/waterHueMax bound the hues (degrees) counted as water colour;
+	// hues outside fade out over waterHueFade degrees.
+	waterHueMin  = 165.0
+	waterHueMax  = 250.0
+	waterHueFade = 40.0
+	// waterCellScore is the minimum combined score of a water cell.
+	waterCellScore = 0.6
+	// waterMinCells is the smallest water component worth suggesting.
+	waterMinCells = 6
+	// waterEdgeCoverage is the minimum share of water cells in an edge row or
+	// column of the suggestion; sparser edges are trimmed.
+	waterEdgeCoverage = 0.5
+	// waterStrongEdge is the luma gradient (8-bit levels per pixel) of hard
+	// edges such as UI borders, text or terrain outlines.
+	waterStrongEdge = 40
+)
+
+// WaterRegion is a suggested capture selection.
+type WaterRegion struct {
+	Rect     image.Rectangle
+	Coverage float64 // share of cells inside Rect classified as water
+	Score    float64 // mean water score of the cells inside Rect
+}
+
+// waterCellStats holds the per-cell features of SuggestWaterRegion.
+type waterCellStats struct {
+	r, g, b  float64 // mean colour
+	std      float64 // luma standard deviation (texture)
+	edges    float64 // share of pixels on hard edges
+	temporal float64 // mean absolute luma change between frames
+}
+
+// SuggestWaterRegion looks for the water area of full-screen captures and
+// proposes a selection rectangle around it. Frames are split into cells that
+// are scored on colour (blue-green hues), texture (fine ripples rather than
+// flat sky or hard UI and terrain edges) and, when several frames of the same
+// size are given, temporal variance (water moves, scenery and UI do not). The
+// suggestion is the bounding box of the largest connected group of water
+// cells with sparse edge rows and columns trimmed. ok is false when no
+// sizeable water area was found.
+func SuggestWaterRegion(frames []*image.RGBA) (WaterRegion, bool) {
+	if len(frames) == 0 || frames[0] == nil {
+		return WaterRegion{}, false
+	}
+	fb := frames[0].Bounds()
+	var seq []*image.RGBA
+	for _, f := range frames {
+		if f != nil && f.Bounds() == fb {
+			seq = append(seq, f)
+		}
+	}
+	cw, ch := fb.Dx()/waterCell, fb.Dy()/waterCell
+	if cw == 0 || ch == 0 {
+		return WaterRegion{}, false
+	}
+	stats := waterStats(seq, cw, ch)
+	scores := make([]float64, cw*ch)
+	water := make([]bool, cw*ch)
+	for i, st := range stats {
+		scores[i] = waterScore(st, len(seq) > 1)
+		water[i] = scores[i] >= waterCellScore
+	}
+	var best component
+	for _, c := range labelComponents4(water, cw, ch) {
+		if c.area > best.area {
+			best = c
+		}
+	}
+	if best.area < waterMinCells {
+		return WaterRegion{}, false
+	}
+	r := trimSparseEdges(water, cw, best.bounds)
+	var n, sum float64
+	var hits int
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			i := y*cw + x
+			sum += scores[i]
+			n++
+			if water[i] {
+				hits++
+			}
+		}
+	}
+	rect := image.Rect(r.Min.X*waterCell, r.Min.Y*waterCell, r.Max.X*waterCell, r.Max.Y*waterCell).Add(fb.Min)
+	return WaterRegion{Rect: rect, Coverage: float64(hits) / n, Score: sum / n}, true
+}
+
+// waterStats computes the features of each cell of the cw x ch grid.
+func waterStats(frames []*image.RGBA, cw, ch int) []waterCellStats {
+	f0 := frames[0]
+	W, H := cw*waterCell, ch*waterCell
+	gray := func(f *image.RGBA) []float64 {
+		g := make([]float64, W*H)
+		for y := 0; y < H; y++ {
+			row := f.Pix[y*f.Stride:]
+			for x := 0; x < W; x++ {
+				g[y*W+x] = 0.299*float64(row[x*4]) + 0.587*float64(row[x*4+1]) + 0.114*float64(row[x*4+2])
+			}
+		}
+		return g
+	}
+	lumas := make([][]float64, len(frames))
+	for i, f := range frames {
+		lumas[i] = gray(f)
+	}
+	l0 := lumas[0]
+	stats := make([]waterCellStats, cw*ch)
+	area := float64(waterCell * waterCell)
+	for cy := 0; cy < ch; cy++ {
+		for cx := 0; cx < cw; cx++ {
+			st := &stats[cy*cw+cx]
+			var sum, sq float64
+			edges := 0
+			for y := cy * waterCell; y < (cy+1)*waterCell; y++ {
+				row := f0.Pix[y*f0.Stride:]
+				for x := cx * waterCell; x < (cx+1)*waterCell; x++ {
+					st.r += float64(row[x*4])
+					st.g += float64(row[x*4+1])
+					st.b += float64(row[x*4+2])
+					v := l0[y*W+x]
+					sum += v
+					sq += v * v
+					if x+1 < W && y+1 < H && math.Abs(l0[y*W+x+1]-v)+math.Abs(l0[(y+1)*W+x]-v) > waterStrongEdge {
+						edges++
+					}
+				}
+			}
+			st.r, st.g, st.b = st.r/area, st.g/area, st.b/area
+			mean := sum / area
+			st.std = math.Sqrt(math.Max(0, sq/area-mean*mean))
+			st.edges = float64(edges) / area
+			if len(lumas) < 2 {
+				continue
+			}
+			var diff float64
+			for k := 1; k < len(lumas); k++ {
+				prev, cur := lumas[k-1], lumas[k]
+				for y := cy * waterCell; y < (cy+1)*waterCell; y++ {
+					for x := cx * waterCell; x < (cx+1)*waterCell; x++ {
+						diff += math.Abs(cur[y*W+x] - prev[y*W+x])
+					}
+				}
+			}
+			st.temporal = diff / (area * float64(len(lumas)-1))
+		}
+	}
+	return stats
+}
+
+// waterScore combines the cell features into a water likelihood in [0, 1].
+func waterScore(st waterCellStats, temporal bool) float64 {
+	h, s, v := rgbToHSV(uint8(st.r), uint8(st.g), uint8(st.b))
+	colour := 0.4 // achromatic (dark or grey water) is undecided
+	if s >= 0.12 && v >= 0.05 {
+		dist := 0.0
+		if h < waterHueMin {
+			dist = math.Min(waterHueMin-h, h+360-waterHueMax)
+		} else if h > waterHueMax {
+			dist = math.Min(h-waterHueMax, waterHueMin+360-h)
+		}
+		colour = math.Max(0, 1-dist/waterHueFade)
+	}
+	// Ripples: some texture, but not flat and not dominated by hard edges.
+	texture := band(st.std, 0.8, 2.5, 25, 45) * math.Max(0, 1-st.edges/0.15)
+	if colour < 0.3 || texture == 0 {
+		return 0
+	}
+	if !temporal {
+		return 0.4*colour + 0.6*texture
+	}
+	motion := band(st.temporal, 0.3, 1, 20, 40)
+	// Static cells stay below waterCellScore however blue and textured.
+	return 0.3*colour + 0.2*texture + 0.5*motion
+}
+
+// band is 1 for v in [lo, hi], 0 outside (zeroLo, zeroHi) and linear in between.
+func band(v, zeroLo, lo, hi, zeroHi float64) float64 {
+	switch {
+	case v <= zeroLo || v >= zeroHi:
+		return 0
+	case v < lo:
+		return (v - zeroLo) / (lo - zeroLo)
+	case v > hi:
+		return (zeroHi - v) / (zeroHi - hi)
+	default:
+		return 1
+	}
+}
+
+// labelComponents4 returns the 4-connected components of mask (row-major,
+// w*h). Unlike labelComponents, diagonal neighbours are not joined, so thin
+// diagonal bridges do not merge separate areas.
+func labelComponents4(mask []bool, w, h int) []component {
+	seen := make([]bool, len(mask))
+	var comps []component
+	var stack []int
+	for start, on := range mask {
+		if !on || seen[start] {
+			continue
+		}
+		seen[start] = true
+		stack = append(stack[:0], start)
+		c := component{bounds: image.Rect(start%w, start/w, start%w+1, start/w+1)}
+		for len(stack) > 0 {
+			i := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			x, y := i%w, i/w
+			c.area++
+			c.bounds = c.bounds.Union(image.Rect(x, y, x+1, y+1))
+			for _, d := range [4][2]int{{1, 0}, {-1, 0}, {0, 1}, {0, -1}} {
+				nx, ny := x+d[0], y+d[1]
+				if nx < 0 || ny < 0 || nx >= w || ny >= h {
+					continue
+				}
+				if j := ny*w + nx; mask[j] && !seen[j] {
+					seen[j] = true
+					stack = append(stack, j)
+				}
+			}
+		}
+		comps = append(comps, c)
+	}
+	return comps
+}
+
+// trimSparseEdges shrinks r (in cells) while its sparsest edge row or column
+// holds fewer than waterEdgeCoverage water cells.
+func trimSparseEdges(water []bool, w int, r image.Rectangle) image.Rectangle {
+	count := func(x0, y0, x1, y1 int) float64 {
+		hits := 0
+		for y := y0; y < y1; y++ {
+			for x := x0; x < x1; x++ {
+				if water[y*w+x] {
+					hits++
+				}
+			}
+		}
+		return float64(hits) / float64((x1-x0)*(y1-y0))
+	}
+	for r.Dx() > 1 && r.Dy() > 1 {
+		edges := [4]float64{
+			count(r.Min.X, r.Min.Y, r.Max.X, r.Min.Y+1), // top
+			count(r.Min.X, r.Max.Y-1, r.Max.X, r.Max.Y), // bottom
+			count(r.Min.X, r.Min.Y, r.Min.X+1, r.Max.Y), // left
+			count(r.Max.X-1, r.Min.Y, r.Max.X, r.Max.Y), // right
+		}
+		worst := 0
+		for i, c := range edges {
+			if c < edges[worst] {
+				worst = i
+			}
+		}
+		if edges[worst] >= waterEdgeCoverage {
+			break
+		}
+		switch worst {
+		case 0:
+			r.Min.Y++
+		case 1:
+			r.Max.Y--
+		case 2:
+			r.Min.X++
+		default:
+			r.Max.X--
+		}
+	}
+	return r
+}
@@ -0,0 +1,106 @@
+package capture
+
+import (
+	"image"
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// Known regions of the synthetic game screen built by waterScene.
+var (
+	sceneBounds = image.Rect(0, 0, 640, 360)
+	sceneSky    = image.Rect(0, 0, 640, 96)
+	sceneLand   = image.Rect(0, 96, 208, 320)
+	sceneWater  = image.Rect(208, 112, 608, 304)
+	sceneUI     = image.Rect(0, 320, 640, 360)
+)
+
+// waterScene renders frame number t of a synthetic game screen: a flat sky
+// gradient, textured green-brown land, rippling blue water and a dark UI bar
+// with bright glyphs. Only the water changes between frames.
+func waterScene(t int) *image.RGBA {
+	img := image.NewRGBA(sceneBounds)
+	set := func(x, y int, r, g, b float64) {
+		i := img.PixOffset(x, y)
+		img.Pix[i], img.Pix[i+1], img.Pix[i+2], img.Pix[i+3] = clampUnit8(r), clampUnit8(g), clampUnit8(b), 255
+	}
+	land := rand.New(rand.NewSource(7))
+	phase := float64(t) * 0.9
+	for y := 0; y < sceneBounds.Dy(); y++ {
+		for x := 0; x < sceneBounds.Dx(); x++ {
+			pt := image.Pt(x, y)
+			switch {
+			case pt.In(sceneUI):
+				v := 40.0
+				if (x/6)%5 == 0 && (y-326)%14 < 8 && y > 326 {
+					v = 220 // glyph strokes
+				}
+				set(x, y, v, v, v+5)
+			case pt.In(sceneWater):
+				ripple := 9 * math.Sin(float64(x)*0.35+phase) * math.Cos(float64(y)*0.5-phase*0.7)
+				set(x, y, 25+ripple*0.5, 85+ripple, 135+ripple)
+			case pt.In(sceneSky):
+				set(x, y, 140+float64(y)*0.3, 180+float64(y)*0.2, 235)
+			default:
+				n := float64(land.Intn(40))
+				if (x/9+y/7)%3 == 0 {
+					n += 35 // clumps of grass and rock
+				}
+				set(x, y, 70+n, 90+n, 40+n/2)
+			}
+		}
+	}
+	return img
+}
+
+func clampUnit8(v float64) uint8 {
+	return uint8(math.Max(0, math.Min(255, math.Round(v))))
+}
+
+func iou(a, b image.Rectangle) float64 {
+	in := a.Intersect(b)
+	if in.Empty() {
+		return 0
+	}
+	ia := float64(in.Dx() * in.Dy())
+	return ia / (float64(a.Dx()*a.Dy()+b.Dx()*b.Dy()) - ia)
+}
+
+func TestSuggestWaterRegion_FindsWaterInSyntheticScene(t *testing.T) {
+	frames := []*image.RGBA{waterScene(0), waterScene(1), waterScene(2), waterScene(3)}
+	got, ok := SuggestWaterRegion(frames)
+	if !ok {
+		t.Fatalf("expected a suggestion")
+	}
+	if v := iou(got.Rect, sceneWater); v < 0.85 {
+		t.Fatalf("suggested %v, want about %v (IoU %.2f)", got.Rect, sceneWater, v)
+	}
+	if got.Coverage < 0.9 {
+		t.Fatalf("coverage %.2f too low", got.Coverage)
+	}
+}
+
+func TestSuggestWaterRegion_SingleFrameUsesColourAndTexture(t *testing.T) {
+	got, ok := SuggestWaterRegion([]*image.RGBA{waterScene(0)})
+	if !ok {
+		t.Fatalf("expected a suggestion")
+	}
+	if v := iou(got.Rect, sceneWater); v < 0.8 {
+		t.Fatalf("suggested %v, want about %v (IoU %.2f)", got.Rect, sceneWater, v)
+	}
+}
+
+func TestSuggestWaterRegion_StaticBlueIsNotWater(t *testing.T) {
+	// Frozen ripples look like water in a single frame but do not move.
+	frozen := []*image.RGBA{waterScene(0), waterScene(0), waterScene(0)}
+	if got, ok := SuggestWaterRegion(frozen); ok {
+		t.Fatalf("frozen ripples: unexpected suggestion %v (coverage %.2f)", got.Rect, got.Coverage)
+	}
+	// Without ripples the water looks like sky: blue but flat.
+	flat := waterScene(0)
+	paintRect(flat, sceneWater, 25, 85, 135)
+	if got, ok := SuggestWaterRegion([]*image.RGBA{flat}); ok {
+		t.Fatalf("flat blue: unexpected suggestion %v (coverage %.2f)", got.Rect, got.Coverage)
+	}
+}
@@ -9,25 +9,47 @@ import (
 	"github.com/soocke/pixel-bot-go/config"
 )
 
-// BiteDetector detects bites from ROI frames.
+// biteChangeLag is the time span over which frame changes are measured.
+// Comparing against the frame about this old, rather than the previous one,
+// makes a sudden change look the same at any capture rate; dividing by the
+// actual span turns continuous motion into a rate that does not depend on
+// the capture rate either.
+const biteChangeLag = 50 * time.Millisecond
+
+// lumaFrame is a luma copy of a past ROI frame.
+type lumaFrame struct {
+	pix []byte
+	t   time.Time
+}
+
+// rateSample is a change rate in the spike statistics window.
+type rateSample struct {
+	t time.Time
+	v float64
+}
+
+// BiteDetector detects bites from ROI frames. All statistics are normalised
+// by the frame timestamps, so detection does not depend on the capture rate.
 // Not safe for concurrent use; call FeedFrame from a single goroutine.
 type BiteDetector struct {
+	castTimer
 	cfg                                                                  *config.Config
+	params                                                               config.BiteParams
 	logger                                                               *slog.Logger
-	monitoringStarted                                                    time.Time
-	prev                                                                 []byte
-	ema                                                                  []byte
+	history                                                              []lumaFrame // recent frames, oldest first
+	free                                                                 [][]byte
+	ema                                                                  []float32
+	emaTS                                                                time.Time
 	cur                                                                  []byte
 	w, h                                                                 int
-	window                                                               []float64
-	wIdx                                                                 int
-	wCount                                                               int
+	window                                                               []rateSample
 	frameCnt                                                             int
 	triggered                                                            bool
 	lastSpike                                                            time.Time
 	lastFrameTS                                                          time.Time
 	prevCandidate                                                        bool
 	candidateFrames                                                      int
+	candidateSince                                                       time.Time
 	statsFrozen                                                          bool
 	framesCandidateStarted                                               int
 	framesCandidateAborted                                               int
@@ -37,42 +59,34 @@ type BiteDetector struct {
 	minDiffBaseMean, maxDiffBaseMean                                     float64
 	lastDT, lastRatioChanged, lastDiffBaseMean                           float64
 	lastCandidateSpike, lastCandidateBaseJump, lastCandidateBigImmediate bool
+	last                                                                 BiteFeatures
+	hasLast                                                              bool
 }
 
-const (
-	windowSize          = 20
-	minFramesForStats   = 5
-	pixelDiffThreshold  = 10
-	ratioThresholdSpike = 0.18
-	ratioThresholdBase  = 0.12
-	baselineDiffThresh  = 14
-	stdDevMultiplier    = 2.0
-	bigImmediateRatio   = 0.20
-	bigImmediateDiff    = 12
-	emaAlpha            = 0.03
-	frameDebounceNeeded = 1
-)
-
-// NewBiteDetector returns a configured BiteDetector. If cfg is nil the
+// NewBiteDetector returns a BiteDetector tuned by cfg.Bite. If cfg is nil the
 // default configuration is used.
 func NewBiteDetector(cfg *config.Config, logger *slog.Logger) *BiteDetector {
 	if cfg == nil {
 		cfg = config.DefaultConfig()
 	}
-	return &BiteDetector{cfg: cfg, logger: logger, window: make([]float64, windowSize)}
+	return &BiteDetector{castTimer: newCastTimer(cfg, logger), cfg: cfg, params: cfg.Bite.Normalize(), logger: logger}
 }
 
 // Reset clears internal state and statistics.
 func (b *BiteDetector) Reset() {
-	b.monitoringStarted = time.Now()
-	b.prev, b.ema, b.cur = nil, nil, nil
+	b.startCast()
+	b.dropHistory(len(b.history))
+	b.ema, b.cur = nil, nil
+	b.emaTS = time.Time{}
 	b.w, b.h = 0, 0
-	b.wIdx, b.wCount, b.frameCnt = 0, 0, 0
+	b.window = b.window[:0]
+	b.frameCnt = 0
 	b.triggered = false
 	b.lastSpike = time.Time{}
 	b.lastFrameTS = time.Time{}
 	b.prevCandidate = false
 	b.candidateFrames = 0
+	b.candidateSince = time.Time{}
 	b.statsFrozen = false
 	b.framesCandidateStarted = 0
 	b.framesCandidateAborted = 0
@@ -82,28 +96,98 @@ func (b *BiteDetector) Reset() {
 	b.minDiffBaseMean, b.maxDiffBaseMean = 0, 0
 	b.lastDT, b.lastRatioChanged, b.lastDiffBaseMean = 0, 0, 0
 	b.lastCandidateSpike, b.lastCandidateBaseJump, b.lastCandidateBigImmediate = false, false, false
-	for i := range b.window {
-		b.window[i] = 0
-	}
+	b.last, b.hasLast = BiteFeatures{}, false
+}
+
+// BiteFeatures are the measurements and decisions of one FeedFrame call.
+type BiteFeatures struct {
+	Time time.Time `json:"time"`
+	// Frame counts frames since Reset; frames younger than the change lag
+	// only seed the baselines and have no features.
+	Frame int `json:"frame"`
+	// Lag is the time in milliseconds to the frame the changes are measured
+	// against.
+	Lag float64 `json:"lag_ms"`
+	// DT is the mean absolute luma change rate in levels per second.
+	DT float64 `json:"dt"`
+	// RatioChanged is the share of pixels that changed faster than
+	// PixelRateThreshold.
+	RatioChanged float64 `json:"ratio_changed"`
+	// DiffBaseMean is the mean absolute luma difference to the slow background.
+	DiffBaseMean float64 `json:"diff_base_mean"`
+	// Mean and Std describe DT over the Window samples of the last WindowMs;
+	// Span is the time in milliseconds those samples cover.
+	Mean   float64 `json:"mean"`
+	Std    float64 `json:"std"`
+	Window int     `json:"window"`
+	Span   float64 `json:"span_ms"`
+	// Candidate is true when any of Spike, BaseJump or BigImmediate holds.
+	Spike           bool `json:"spike"`
+	BaseJump        bool `json:"base_jump"`
+	BigImmediate    bool `json:"big_immediate"`
+	Candidate       bool `json:"candidate"`
+	CandidateFrames int  `json:"candidate_frames"`
+	Triggered       bool `json:"triggered"`
 }
 
 // FeedFrame processes one ROI frame sampled at time t and returns true when
-// a bite is detected. Call from a single goroutine.
+// a bite is detected. Frames must arrive in time order; a frame that is not
+// newer than the previous one is ignored. Call from a single goroutine.
 func (b *BiteDetector) FeedFrame(frame *image.RGBA, t time.Time) bool {
+	b.hasLast = false
 	if frame == nil || b.triggered {
 		return false
 	}
+	f, ok := b.measure(frame, t)
+	if !ok {
+		return false
+	}
+	bite := b.decide(&f)
+	b.last, b.hasLast = f, true
+	if bite {
+		if b.logger != nil {
+			b.logger.Info("bite detected", "dt", f.DT, "meanDt", f.Mean, "stdDt", f.Std, "changedRatio", f.RatioChanged, "diffBaseMean", f.DiffBaseMean, "framesInCandidate", f.CandidateFrames)
+		}
+		return true
+	}
+	b.update(f)
+	return false
+}
+
+// LastFeatures returns the features of the most recent FeedFrame call. ok is
+// false when that call measured nothing (warm-up frame, nil frame or already
+// triggered).
+func (b *BiteDetector) LastFeatures() (BiteFeatures, bool) {
+	return b.last, b.hasLast
+}
+
+// measure converts frame to luma and computes its change rates against the
+// past frame whose age is closest to biteChangeLag and its difference to the
+// background, together with the window statistics. Until a frame at least
+// half the lag old exists, frames only seed the history and the background
+// and ok is false.
+func (b *BiteDetector) measure(frame *image.RGBA, t time.Time) (f BiteFeatures, ok bool) {
 	fb := frame.Bounds()
 	w, h := fb.Dx(), fb.Dy()
 	n := w * h
 	if w <= 0 || h <= 0 {
-		return false
+		return f, false
 	}
-	if b.prev == nil || w != b.w || h != b.h {
-		b.prev = make([]byte, n)
-		b.ema = make([]byte, n)
-		b.cur = make([]byte, n)
+	if b.frameCnt > 0 && !t.After(b.lastFrameTS) {
+		return f, false
+	}
+	if b.ema == nil || w != b.w || h != b.h {
+		b.dropHistory(len(b.history))
+		b.free = nil
+		b.ema = make([]float32, n)
 		b.w, b.h = w, h
+		b.frameCnt = 0
+	}
+	if len(b.free) > 0 {
+		b.cur = b.free[len(b.free)-1]
+		b.free = b.free[:len(b.free)-1]
+	} else {
+		b.cur = make([]byte, n)
 	}
 	pix := frame.Pix
 	stride := frame.Stride
@@ -118,35 +202,59 @@ func (b *BiteDetector) FeedFrame(frame *image.RGBA, t time.Time) bool {
 		}
 	}
 	if b.frameCnt == 0 {
-		copy(b.prev, b.cur)
-		copy(b.ema, b.cur)
-		b.frameCnt++
-		b.lastFrameTS = t
-		return false
+		for i, v := range b.cur {
+			b.ema[i] = float32(v)
+		}
+		b.emaTS = t
+		b.keep(t)
+		return f, false
 	}
-	var sumPrev, sumBase int
+	ref := -1
+	for i := len(b.history) - 1; i >= 0; i-- {
+		ref = i
+		if age := t.Sub(b.history[i].t); age >= biteChangeLag {
+			if i+1 < len(b.history) && biteChangeLag-t.Sub(b.history[i+1].t) < age-biteChangeLag {
+				ref = i + 1
+			}
+			break
+		}
+	}
+	if ref < 0 || t.Sub(b.history[ref].t) < biteChangeLag/2 {
+		b.updateEMA(t)
+		b.keep(t)
+		return f, false
+	}
+	b.dropHistory(ref) // later frames compare against ref or newer
+	prev := b.history[0]
+	lag := t.Sub(prev.t).Seconds()
+	pixelThresh := b.params.PixelRateThreshold * lag
+	var sumPrev int
+	var sumBase float64
 	changedPixels := 0
 	for i := 0; i < n; i++ {
-		diffPrev := int(b.cur[i]) - int(b.prev[i])
+		diffPrev := int(b.cur[i]) - int(prev.pix[i])
 		if diffPrev < 0 {
 			diffPrev = -diffPrev
 		}
 		sumPrev += diffPrev
-		if diffPrev > pixelDiffThreshold {
+		if float64(diffPrev) > pixelThresh {
 			changedPixels++
 		}
-		diffBase := int(b.cur[i]) - int(b.ema[i])
+		diffBase := float32(b.cur[i]) - b.ema[i]
 		if diffBase < 0 {
 			diffBase = -diffBase
 		}
-		sumBase += diffBase
+		sumBase += float64(diffBase)
 	}
-	dt := float64(sumPrev) / float64(n)
-	ratioChanged := float64(changedPixels) / float64(n)
-	diffBaseMean := float64(sumBase) / float64(n)
+	f.Time, f.Frame = t, b.frameCnt
+	f.Lag = lag * 1000
+	f.DT = float64(sumPrev) / float64(n) / lag
+	f.RatioChanged = float64(changedPixels) / float64(n)
+	f.DiffBaseMean = sumBase / float64(n)
+	b.trimWindow(t)
 	var mean, m2 float64
-	for i := 0; i < b.wCount; i++ {
-		x := b.window[i]
+	for i, s := range b.window {
+		x := s.v
 		if i == 0 {
 			mean = x
 			continue
@@ -156,101 +264,164 @@ func (b *BiteDetector) FeedFrame(frame *image.RGBA, t time.Time) bool {
 		m2 += delta * (x - mean)
 	}
 	std := 0.0
-	if b.wCount > 1 {
-		std = (m2 / float64(b.wCount-1))
+	if len(b.window) > 1 {
+		std = (m2 / float64(len(b.window)-1))
 		if std > 0 {
 			std = math.Sqrt(std)
 		}
 	}
-	spike := (b.wCount >= minFramesForStats) && (dt > mean+stdDevMultiplier*std) && (ratioChanged > ratioThresholdSpike)
-	baseJump := (diffBaseMean > baselineDiffThresh) && (ratioChanged > ratioThresholdBase)
-	bigImmediate := (b.wCount < minFramesForStats) && (ratioChanged > bigImmediateRatio) && (dt > bigImmediateDiff)
-	candidate := spike || baseJump || bigImmediate
-	if b.frameCnt > 0 {
-		if b.minDT == 0 && b.maxDT == 0 {
-			b.minDT, b.maxDT = dt, dt
-			b.minRatioChanged, b.maxRatioChanged = ratioChanged, ratioChanged
-			b.minDiffBaseMean, b.maxDiffBaseMean = diffBaseMean, diffBaseMean
-		} else {
-			if dt < b.minDT {
-				b.minDT = dt
-			} else if dt > b.maxDT {
-				b.maxDT = dt
-			}
-			if ratioChanged < b.minRatioChanged {
-				b.minRatioChanged = ratioChanged
-			} else if ratioChanged > b.maxRatioChanged {
-				b.maxRatioChanged = ratioChanged
-			}
-			if diffBaseMean < b.minDiffBaseMean {
-				b.minDiffBaseMean = diffBaseMean
-			} else if diffBaseMean > b.maxDiffBaseMean {
-				b.maxDiffBaseMean = diffBaseMean
-			}
-		}
-		b.lastDT = dt
-		b.lastRatioChanged = ratioChanged
-		b.lastDiffBaseMean = diffBaseMean
+	f.Mean, f.Std, f.Window = mean, std, len(b.window)
+	if len(b.window) > 0 {
+		f.Span = float64(t.Sub(b.window[0].t)) / float64(time.Millisecond)
+	}
+	return f, true
+}
+
+// keep appends the current frame, taken at t, to the history.
+func (b *BiteDetector) keep(t time.Time) {
+	b.history = append(b.history, lumaFrame{pix: b.cur, t: t})
+	b.cur = nil
+	b.frameCnt++
+	b.lastFrameTS = t
+}
+
+// dropHistory removes the oldest n frames from the history and recycles
+// their buffers.
+func (b *BiteDetector) dropHistory(n int) {
+	for _, old := range b.history[:n] {
+		b.free = append(b.free, old.pix)
+	}
+	b.history = append(b.history[:0], b.history[n:]...)
+}
+
+// trimWindow drops change rates older than WindowMs before t.
+func (b *BiteDetector) trimWindow(t time.Time) {
+	cut := t.Add(-time.Duration(b.params.WindowMs) * time.Millisecond)
+	i := 0
+	for i < len(b.window) && b.window[i].t.Before(cut) {
+		i++
+	}
+	b.window = append(b.window[:0], b.window[i:]...)
+}
+
+// updateEMA moves the background towards the current frame by the share of
+// the EMA time constant that elapsed since its last update.
+func (b *BiteDetector) updateEMA(t time.Time) {
+	tau := float64(b.params.EMATimeConstantMs) / 1000
+	alpha := float32(1 - math.Exp(-t.Sub(b.emaTS).Seconds()/tau))
+	for i, v := range b.cur {
+		b.ema[i] += (float32(v) - b.ema[i]) * alpha
 	}
-	if candidate {
+	b.emaTS = t
+}
+
+// decide classifies the measured frame and reports whether the debounced
+// candidate is a bite.
+func (b *BiteDetector) decide(f *BiteFeatures) bool {
+	b.classify(f)
+	debounced := f.Time.Sub(b.candidateSince) >= time.Duration(b.params.DebounceMs)*time.Millisecond
+	if f.Candidate && (debounced || (f.BigImmediate && b.candidateFrames == 1)) {
+		b.triggered = true
+		f.Triggered = true
+	}
+	return f.Triggered
+}
+
+// classify fills in the candidate flags of f and tracks the current run of
+// candidate frames.
+func (b *BiteDetector) classify(f *BiteFeatures) {
+	p := b.params
+	ready := f.Window > 0 && f.Span >= float64(p.MinStatsMs)
+	f.Spike = ready && (f.DT > f.Mean+p.StdDevMultiplier*f.Std) && (f.RatioChanged > p.RatioThresholdSpike)
+	f.BaseJump = (f.DiffBaseMean > p.BaselineDiffThresh) && (f.RatioChanged > p.RatioThresholdBase)
+	f.BigImmediate = !ready && (f.RatioChanged > p.BigImmediateRatio) && (f.DT > p.BigImmediateRate)
+	f.Candidate = f.Spike || f.BaseJump || f.BigImmediate
+	if f.Candidate {
 		b.candidateFrames++
 		if !b.prevCandidate {
 			b.statsFrozen = true
+			b.candidateSince = f.Time
 		}
 		if b.candidateFrames > b.maxConsecutiveCandidate {
 			b.maxConsecutiveCandidate = b.candidateFrames
 		}
-		if b.candidateFrames >= frameDebounceNeeded || (bigImmediate && b.candidateFrames == 1) {
-			b.triggered = true
-			if b.logger != nil {
-				b.logger.Info("bite detected", "dt", dt, "meanDt", mean, "stdDt", std, "changedRatio", ratioChanged, "diffBaseMean", diffBaseMean, "framesInCandidate", b.candidateFrames)
-			}
-			return true
-		}
 	} else {
 		b.candidateFrames = 0
 		b.statsFrozen = false
 	}
-	b.prevCandidate = candidate
-	if !b.statsFrozen {
-		b.window[b.wIdx] = dt
-		b.wIdx = (b.wIdx + 1) % windowSize
-		if b.wCount < windowSize {
-			b.wCount++
+	f.CandidateFrames = b.candidateFrames
+}
+
+// update advances the detector state past a frame that was not a bite: the
+// range statistics, the rate window (unless frozen by a candidate), the slow
+// background and the frame history.
+func (b *BiteDetector) update(f BiteFeatures) {
+	dt, ratioChanged, diffBaseMean := f.DT, f.RatioChanged, f.DiffBaseMean
+	if b.minDT == 0 && b.maxDT == 0 {
+		b.minDT, b.maxDT = dt, dt
+		b.minRatioChanged, b.maxRatioChanged = ratioChanged, ratioChanged
+		b.minDiffBaseMean, b.maxDiffBaseMean = diffBaseMean, diffBaseMean
+	} else {
+		if dt < b.minDT {
+			b.minDT = dt
+		} else if dt > b.maxDT {
+			b.maxDT = dt
 		}
-	}
-	if !b.triggered {
-		for i := 0; i < n; i++ {
-			v := int(b.ema[i]) + int(float64(int(b.cur[i])-int(b.ema[i]))*emaAlpha)
-			if v < 0 {
-				v = 0
-			} else if v > 255 {
-				v = 255
-			}
-			b.ema[i] = byte(v)
+		if ratioChanged < b.minRatioChanged {
+			b.minRatioChanged = ratioChanged
+		} else if ratioChanged > b.maxRatioChanged {
+			b.maxRatioChanged = ratioChanged
+		}
+		if diffBaseMean < b.minDiffBaseMean {
+			b.minDiffBaseMean = diffBaseMean
+		} else if diffBaseMean > b.maxDiffBaseMean {
+			b.maxDiffBaseMean = diffBaseMean
 		}
 	}
-	copy(b.prev, b.cur)
-	b.frameCnt++
-	b.lastFrameTS = t
-	return false
+	b.lastDT = dt
+	b.lastRatioChanged = ratioChanged
+	b.lastDiffBaseMean = diffBaseMean
+	b.prevCandidate = f.Candidate
+	if !b.statsFrozen {
+		b.window = append(b.window, rateSample{t: f.Time, v: dt})
+	}
+	b.updateEMA(f.Time)
+	b.keep(f.Time)
 }
 
-// FeedFrame processes a single ROI frame sampled at t and returns true when
-// a bite is detected. FeedFrame is not concurrency-safe and must be called
-// from a single goroutine.
+// castTimer gives up on a cast that outlives cfg.MaxCastDurationSeconds.
+// Bite detectors embed it for their TargetLostHeuristic and call startCast
+// from Reset.
+type castTimer struct {
+	castCfg    *config.Config
+	castLogger *slog.Logger
+	castStart  time.Time
+}
 
-func (b *BiteDetector) TargetLostHeuristic() bool {
-	if b.cfg == nil || b.cfg.MaxCastDurationSeconds <= 0 {
-		return false
+// newCastTimer returns a castTimer reading the limit from cfg, or from the
+// default configuration if cfg is nil.
+func newCastTimer(cfg *config.Config, logger *slog.Logger) castTimer {
+	if cfg == nil {
+		cfg = config.DefaultConfig()
 	}
-	if b.monitoringStarted.IsZero() {
+	return castTimer{castCfg: cfg, castLogger: logger}
+}
+
+// startCast starts timing a new cast.
+func (c *castTimer) startCast() {
+	c.castStart = time.Now()
+}
+
+// TargetLostHeuristic reports whether the cast outlived
+// MaxCastDurationSeconds since Reset.
+func (c *castTimer) TargetLostHeuristic() bool {
+	if c.castCfg == nil || c.castCfg.MaxCastDurationSeconds <= 0 || c.castStart.IsZero() {
 		return false
 	}
-	limit := time.Duration(b.cfg.MaxCastDurationSeconds) * time.Second
-	if time.Since(b.monitoringStarted) >= limit {
-		if b.logger != nil {
-			b.logger.Info("monitoring timeout elapsed; target considered lost", "limitSec", b.cfg.MaxCastDurationSeconds)
+	limit := time.Duration(c.castCfg.MaxCastDurationSeconds) * time.Second
+	if time.Since(c.castStart) >= limit {
+		if c.castLogger != nil {
+			c.castLogger.Info("monitoring timeout elapsed; target considered lost", "limitSec", c.castCfg.MaxCastDurationSeconds)
 		}
 		return true
 	}
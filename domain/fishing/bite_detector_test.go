@@ -2,8 +2,13 @@ package fishing
 
 import (
 	"image"
+	"math"
+	"math/rand"
+	"slices"
 	"testing"
 	"time"
+
+	"github.com/soocke/pixel-bot-go/config"
 )
 
 // synthFrame creates a uniform RGBA image and applies an optional mutate func.
@@ -43,9 +48,9 @@ func applyRegion(px []byte, w, h int, x0, y0, x1, y1 int, lum byte) {
 	}
 }
 
-// feedFrames feeds frames to the BiteDetector at 50ms intervals and
+// feedFrames feeds frames to a bite detector at 50ms intervals and
 // returns the index of the frame that triggered detection, or -1.
-func feedFrames(bd *BiteDetector, frames []*image.RGBA) int {
+func feedFrames(bd BiteDetectorContract, frames []*image.RGBA) int {
 	start := time.Now()
 	for i, f := range frames {
 		t := start.Add(time.Duration(i) * 50 * time.Millisecond)
@@ -148,3 +153,177 @@ func TestBiteDetector_ResetClearsState(t *testing.T) {
 		t.Fatalf("expected second detection after reset")
 	}
 }
+
+// choppyFrame is a frame of rough water: a random ~22% of pixels are
+// slightly brighter than the base level.
+func choppyFrame(rng *rand.Rand, w, h int, base byte) *image.RGBA {
+	return synthFrame(w, h, base, func(px []byte, w, h int) {
+		for i := 0; i < w*h; i++ {
+			if rng.Float64() < 0.22 {
+				v := base + 14
+				px[i*4], px[i*4+1], px[i*4+2] = v, v, v
+			}
+		}
+	})
+}
+
+func TestBiteDetector_ChoppyPresetIgnoresRoughWater(t *testing.T) {
+	w, h := 40, 40
+	base := byte(80)
+	run := func(preset string) int {
+		cfg := config.DefaultConfig()
+		cfg.BitePreset = preset
+		_ = cfg.Validate()
+		bd := NewBiteDetector(cfg, nil)
+		bd.Reset()
+		rng := rand.New(rand.NewSource(3))
+		var frames []*image.RGBA
+		for i := 0; i < 60; i++ {
+			frames = append(frames, choppyFrame(rng, w, h, base))
+		}
+		return feedFrames(bd, frames)
+	}
+	if idx := run(config.BitePresetCalm); idx < 0 {
+		t.Fatalf("calm preset: expected rough water to trigger, got none")
+	}
+	if idx := run(config.BitePresetChoppy); idx >= 0 {
+		t.Fatalf("choppy preset: rough water triggered at frame %d", idx)
+	}
+
+	// A real bite still triggers under the choppy preset.
+	cfg := config.DefaultConfig()
+	cfg.BitePreset = config.BitePresetChoppy
+	_ = cfg.Validate()
+	bd := NewBiteDetector(cfg, nil)
+	bd.Reset()
+	rng := rand.New(rand.NewSource(4))
+	var frames []*image.RGBA
+	for i := 0; i < 20; i++ {
+		frames = append(frames, choppyFrame(rng, w, h, base))
+	}
+	for _, lum := range []byte{160, 20} {
+		frames = append(frames, synthFrame(w, h, base, func(px []byte, w, h int) { applyRegion(px, w, h, 8, 8, 32, 32, lum) }))
+	}
+	if idx := feedFrames(bd, frames); idx != 21 {
+		t.Fatalf("choppy preset: expected bite at frame 21 after debounce, got %d", idx)
+	}
+}
+
+// waterAt renders a 40x40 ROI at time s (seconds): a wave travelling at a
+// fixed speed over water that slowly brightens, and from dip seconds on a
+// bobber region that darkens to 20 levels over 200ms. dip < 0 disables it.
+func waterAt(s, dip float64) *image.RGBA {
+	return synthFrame(40, 40, 0, func(px []byte, w, h int) {
+		depth := 0.0
+		if dip >= 0 && s >= dip {
+			depth = math.Min(1, (s-dip)/0.2)
+		}
+		for y := 0; y < h; y++ {
+			for x := 0; x < w; x++ {
+				v := 80 + 4*s + 6*math.Sin(0.5*float64(x)-4*s)
+				if depth > 0 && x >= 10 && x < 30 && y >= 10 && y < 30 {
+					v += (20 - v) * depth
+				}
+				i := (y*w + x) * 4
+				px[i], px[i+1], px[i+2] = byte(v), byte(v), byte(v)
+			}
+		}
+	})
+}
+
+func TestBiteDetector_FrameRateIndependent(t *testing.T) {
+	const dip = 2.5
+	var latencies []time.Duration
+	for _, fps := range []int{15, 30, 60} {
+		interval := time.Second / time.Duration(fps)
+		for _, withDip := range []bool{false, true} {
+			bd := NewBiteDetector(nil, nil)
+			bd.Reset()
+			start := time.Now()
+			at := time.Duration(-1)
+			for el := time.Duration(0); el < 4*time.Second; el += interval {
+				d := -1.0
+				if withDip {
+					d = dip
+				}
+				if bd.FeedFrame(waterAt(el.Seconds(), d), start.Add(el)) {
+					at = el
+					break
+				}
+			}
+			switch {
+			case !withDip && at >= 0:
+				t.Fatalf("%d fps: waves and drift triggered at %v", fps, at)
+			case withDip && at < 0:
+				t.Fatalf("%d fps: dip not detected", fps)
+			case withDip:
+				latency := at - time.Duration(dip*float64(time.Second))
+				if latency < 0 || latency > 150*time.Millisecond {
+					t.Fatalf("%d fps: dip detected after %v", fps, latency)
+				}
+				latencies = append(latencies, latency)
+			}
+		}
+	}
+	spread := slices.Max(latencies) - slices.Min(latencies)
+	if spread > 70*time.Millisecond {
+		t.Fatalf("latencies differ by frame rate: %v", latencies)
+	}
+}
+
+func TestBiteDetector_DebounceIsTimeBased(t *testing.T) {
+	// Choppy's debounce spans 50ms: two frames at 20 fps, four at ~60 fps.
+	cfg := config.DefaultConfig()
+	cfg.BitePreset = config.BitePresetChoppy
+	_ = cfg.Validate()
+	for _, tc := range []struct {
+		interval time.Duration
+		frames   int
+	}{{50 * time.Millisecond, 2}, {17 * time.Millisecond, 4}} {
+		bd := NewBiteDetector(cfg, nil)
+		bd.Reset()
+		start := time.Now()
+		n := 0
+		for i := 0; i < 60; i++ {
+			f := synthFrame(40, 40, 80, nil)
+			if i >= 30 {
+				lum := byte(160)
+				if n%2 == 1 {
+					lum = 20
+				}
+				n++
+				f = synthFrame(40, 40, 80, func(px []byte, w, h int) { applyRegion(px, w, h, 8, 8, 32, 32, lum) })
+			}
+			if bd.FeedFrame(f, start.Add(time.Duration(i)*tc.interval)) {
+				break
+			}
+		}
+		if n != tc.frames {
+			t.Fatalf("%v frames: triggered after %d changed frames, want %d", tc.interval, n, tc.frames)
+		}
+	}
+}
+
+func TestRegisteredDetectors_TimeOutLongCasts(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.MaxCastDurationSeconds = 1
+	for _, name := range BiteDetectorNames() {
+		d, err := NewBiteDetectorByName(name, cfg, nil)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		d.Reset()
+		if d.TargetLostHeuristic() {
+			t.Fatalf("%s: cast lost right after Reset", name)
+		}
+	}
+	c := newCastTimer(cfg, nil)
+	if c.TargetLostHeuristic() {
+		t.Fatalf("cast lost before it started")
+	}
+	c.startCast()
+	c.castStart = c.castStart.Add(-time.Second)
+	if !c.TargetLostHeuristic() {
+		t.Fatalf("cast not lost after MaxCastDurationSeconds")
+	}
+}
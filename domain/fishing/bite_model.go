@@ -0,0 +1,104 @@
+package fishing
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"slices"
+)
+
+// BiteModelLogistic is the kind of a logistic regression BiteModel.
+const BiteModelLogistic = "logistic"
+
+// BiteModelFeatures names the inputs of a BiteModel in the order returned by
+// ModelInputs. All are derived from the BiteFeatures of the motion detector,
+// so a model can be trained on recorded bite traces.
+var BiteModelFeatures = []string{"dt", "dt_z", "ratio_changed", "diff_base_mean", "spike", "base_jump", "big_immediate"}
+
+// ModelInputs returns the model inputs of f in BiteModelFeatures order. dt_z
+// is the change rate in standard deviations above the window mean (0 until
+// the window has two samples); the candidate flags are 0 or 1 and follow the
+// bite preset in force, so train and fish with the same preset.
+func ModelInputs(f BiteFeatures) []float64 {
+	z := 0.0
+	if f.Window > 1 {
+		z = (f.DT - f.Mean) / (f.Std + 1)
+	}
+	flag := func(b bool) float64 {
+		if b {
+			return 1
+		}
+		return 0
+	}
+	return []float64{f.DT, z, f.RatioChanged, f.DiffBaseMean, flag(f.Spike), flag(f.BaseJump), flag(f.BigImmediate)}
+}
+
+// BiteModel is a trained bite classifier. Inputs are standardised with Mean
+// and Scale, and the bite probability is the logistic of their weighted sum
+// plus Bias. A frame scoring at least Threshold is a bite.
+type BiteModel struct {
+	Kind      string    `json:"kind"`
+	Features  []string  `json:"features"`
+	Mean      []float64 `json:"mean"`
+	Scale     []float64 `json:"scale"`
+	Weights   []float64 `json:"weights"`
+	Bias      float64   `json:"bias"`
+	Threshold float64   `json:"threshold"`
+}
+
+// Validate reports whether m can score the inputs of ModelInputs.
+func (m *BiteModel) Validate() error {
+	if m.Kind != BiteModelLogistic {
+		return fmt.Errorf("unsupported bite model kind %q", m.Kind)
+	}
+	if !slices.Equal(m.Features, BiteModelFeatures) {
+		return fmt.Errorf("bite model features %v, want %v", m.Features, BiteModelFeatures)
+	}
+	n := len(BiteModelFeatures)
+	if len(m.Mean) != n || len(m.Scale) != n || len(m.Weights) != n {
+		return fmt.Errorf("bite model needs %d means, scales and weights", n)
+	}
+	if m.Threshold <= 0 || m.Threshold >= 1 {
+		return fmt.Errorf("bite model threshold %v outside (0, 1)", m.Threshold)
+	}
+	return nil
+}
+
+// Score returns the bite probability of the inputs x.
+func (m *BiteModel) Score(x []float64) float64 {
+	s := m.Bias
+	for i, v := range x {
+		scale := m.Scale[i]
+		if scale == 0 {
+			scale = 1
+		}
+		s += m.Weights[i] * (v - m.Mean[i]) / scale
+	}
+	return 1 / (1 + math.Exp(-s))
+}
+
+// LoadBiteModel reads and validates the JSON model at path.
+func LoadBiteModel(path string) (*BiteModel, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var m BiteModel
+	if err := json.Unmarshal(raw, &m); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if err := m.Validate(); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return &m, nil
+}
+
+// Save writes m as indented JSON to path.
+func (m *BiteModel) Save(path string) error {
+	raw, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(raw, '\n'), 0o644)
+}
@@ -0,0 +1,406 @@
+package fishing
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Cast outcomes recorded by the FSM when monitoring of a cast ends.
+const (
+	OutcomeReel = "reel" // a bite was detected and the bobber clicked
+	OutcomeLost = "lost" // the cast timed out or the target was lost; recast
+	OutcomeHalt = "halt" // the bot was stopped while monitoring
+)
+
+// Trace file formats accepted by NewTraceWriter.
+const (
+	TraceJSONL = "jsonl"
+	TraceCSV   = "csv"
+)
+
+// FeatureSource is implemented by bite detectors that expose the features of
+// their most recent frame.
+type FeatureSource interface {
+	LastFeatures() (BiteFeatures, bool)
+}
+
+// BiteTrace receives the bite detector features of every monitored frame and
+// the outcome of every cast. Casts are numbered from 1 in FSM order; a trace
+// file appended to by several runs renumbers them (see OpenTrace).
+type BiteTrace interface {
+	Frame(cast int, f BiteFeatures)
+	Outcome(cast int, outcome string, t time.Time)
+}
+
+// ROIRecorder receives the ROI frames of monitored casts, before they reach
+// the bite detector, and the outcome of every cast, numbered like BiteTrace.
+// Calls come from the FSM goroutine and must not block it.
+type ROIRecorder interface {
+	ROIFrame(cast int, roi *image.RGBA, t time.Time)
+	Outcome(cast int, outcome string, t time.Time)
+}
+
+// TraceWriter writes a BiteTrace as JSON lines or CSV. Frame records carry
+// the features of one frame, outcome records end a cast. It is safe for
+// concurrent use; the first write error is kept and reported by Err and
+// Close.
+type TraceWriter struct {
+	mu     sync.Mutex
+	buf    *bufio.Writer
+	csv    *csv.Writer // nil for JSON lines
+	closer io.Closer
+	err    error
+	base   int // added to cast numbers so they follow earlier runs' casts
+}
+
+// csvHeader lists the CSV columns. Feature columns are empty on outcome rows.
+var csvHeader = []string{
+	"cast", "kind", "time", "frame", "lag_ms", "dt", "ratio_changed", "diff_base_mean",
+	"mean", "std", "window", "span_ms", "spike", "base_jump", "big_immediate",
+	"candidate", "candidate_frames", "triggered", "outcome",
+}
+
+// NewTraceWriter writes a trace to w in format (TraceJSONL or TraceCSV;
+// anything else is treated as JSON lines).
+func NewTraceWriter(w io.Writer, format string) *TraceWriter {
+	return newTraceWriter(w, format, true)
+}
+
+func newTraceWriter(w io.Writer, format string, header bool) *TraceWriter {
+	t := &TraceWriter{buf: bufio.NewWriter(w)}
+	if c, ok := w.(io.Closer); ok {
+		t.closer = c
+	}
+	if strings.EqualFold(format, TraceCSV) {
+		t.csv = csv.NewWriter(t.buf)
+		if header {
+			t.err = t.csv.Write(csvHeader)
+		}
+	}
+	return t
+}
+
+// OpenTrace opens the trace file at path for appending, creating it when
+// missing. A ".csv" extension selects CSV, anything else JSON lines; the CSV
+// header is only written to an empty file. Cast numbers continue after the
+// highest cast already in the file, so every run's casts stay distinct for
+// labelling and training.
+func OpenTrace(path string) (*TraceWriter, error) {
+	format := TraceJSONL
+	if strings.EqualFold(filepath.Ext(path), ".csv") {
+		format = TraceCSV
+	}
+	last, partial, err := lastTracedCast(path, format)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	st, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	t := newTraceWriter(f, format, st.Size() == 0)
+	t.base = last
+	if partial {
+		// A run that crashed mid-record left an unterminated line.
+		_, t.err = t.buf.WriteString("\n")
+	}
+	return t, nil
+}
+
+// lastTracedCast returns the highest cast number in the trace at path (0 for
+// a missing or empty file) and whether the file ends without a newline.
+// Unreadable records, such as one cut short by a crash, are skipped.
+func lastTracedCast(path, format string) (last int, partial bool, err error) {
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, err
+	}
+	defer f.Close()
+	r := bufio.NewReader(f)
+	for {
+		line, err := r.ReadString('\n')
+		if line != "" {
+			partial = !strings.HasSuffix(line, "\n")
+			var cast int
+			if format == TraceCSV {
+				field, _, _ := strings.Cut(line, ",")
+				cast, _ = strconv.Atoi(field)
+			} else {
+				var head struct {
+					Cast int `json:"cast"`
+				}
+				if json.Unmarshal([]byte(line), &head) == nil {
+					cast = head.Cast
+				}
+			}
+			last = max(last, cast)
+		}
+		if err == io.EOF {
+			return last, partial, nil
+		}
+		if err != nil {
+			return 0, false, err
+		}
+	}
+}
+
+type traceFrame struct {
+	Cast int    `json:"cast"`
+	Kind string `json:"kind"`
+	BiteFeatures
+}
+
+type traceOutcome struct {
+	Cast    int       `json:"cast"`
+	Kind    string    `json:"kind"`
+	Time    time.Time `json:"time"`
+	Outcome string    `json:"outcome"`
+}
+
+// Frame records the features of one monitored frame of cast.
+func (t *TraceWriter) Frame(cast int, f BiteFeatures) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.err != nil {
+		return
+	}
+	cast += t.base
+	if t.csv == nil {
+		t.writeJSON(traceFrame{Cast: cast, Kind: "frame", BiteFeatures: f})
+		return
+	}
+	ftoa := func(v float64) string { return strconv.FormatFloat(v, 'f', 4, 64) }
+	t.err = t.csv.Write([]string{
+		strconv.Itoa(cast), "frame", f.Time.Format(time.RFC3339Nano), strconv.Itoa(f.Frame), ftoa(f.Lag),
+		ftoa(f.DT), ftoa(f.RatioChanged), ftoa(f.DiffBaseMean), ftoa(f.Mean), ftoa(f.Std), strconv.Itoa(f.Window), ftoa(f.Span),
+		strconv.FormatBool(f.Spike), strconv.FormatBool(f.BaseJump), strconv.FormatBool(f.BigImmediate),
+		strconv.FormatBool(f.Candidate), strconv.Itoa(f.CandidateFrames), strconv.FormatBool(f.Triggered), "",
+	})
+}
+
+// Outcome records how cast ended and flushes the trace, so every finished
+// cast is on disk.
+func (t *TraceWriter) Outcome(cast int, outcome string, at time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.err != nil {
+		return
+	}
+	cast += t.base
+	if t.csv == nil {
+		t.writeJSON(traceOutcome{Cast: cast, Kind: "outcome", Time: at, Outcome: outcome})
+	} else {
+		row := make([]string, len(csvHeader))
+		row[0], row[1], row[2], row[len(row)-1] = strconv.Itoa(cast), "outcome", at.Format(time.RFC3339Nano), outcome
+		t.err = t.csv.Write(row)
+	}
+	t.flush()
+}
+
+func (t *TraceWriter) writeJSON(v any) {
+	b, err := json.Marshal(v)
+	if err == nil {
+		b = append(b, '\n')
+		_, err = t.buf.Write(b)
+	}
+	t.err = err
+}
+
+func (t *TraceWriter) flush() {
+	if t.err != nil {
+		return
+	}
+	if t.csv != nil {
+		t.csv.Flush()
+		t.err = t.csv.Error()
+	}
+	if t.err == nil {
+		t.err = t.buf.Flush()
+	}
+}
+
+// Err returns the first write error.
+func (t *TraceWriter) Err() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.err
+}
+
+// Close flushes the trace and closes the underlying writer when it is a
+// closer. Later records are dropped.
+func (t *TraceWriter) Close() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.flush()
+	if t.closer != nil {
+		if err := t.closer.Close(); t.err == nil {
+			t.err = err
+		}
+		t.closer = nil
+	}
+	err := t.err
+	if t.err == nil {
+		t.err = os.ErrClosed
+	}
+	return err
+}
+
+var _ BiteTrace = (*TraceWriter)(nil)
+
+// TracedCast is one cast read back from a bite trace.
+type TracedCast struct {
+	Cast    int
+	Frames  []BiteFeatures
+	Outcome string // empty when the trace ends during the cast
+}
+
+// ReadTrace reads a trace written by TraceWriter, in the format OpenTrace
+// picks for path, and returns its casts in trace order. Records cut short by
+// a crash of the recording run are skipped.
+func ReadTrace(path string) ([]TracedCast, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var casts []TracedCast
+	at := map[int]int{} // cast number -> index in casts
+	get := func(cast int) *TracedCast {
+		i, ok := at[cast]
+		if !ok {
+			i = len(casts)
+			at[cast] = i
+			casts = append(casts, TracedCast{Cast: cast})
+		}
+		return &casts[i]
+	}
+	if strings.EqualFold(filepath.Ext(path), ".csv") {
+		err = readTraceCSV(f, get)
+	} else {
+		err = readTraceJSON(f, get)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return casts, nil
+}
+
+func readTraceJSON(r io.Reader, get func(int) *TracedCast) error {
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 64*1024), 1024*1024)
+	for line := 1; sc.Scan(); line++ {
+		raw := sc.Bytes()
+		if len(strings.TrimSpace(string(raw))) == 0 {
+			continue
+		}
+		var head struct {
+			Kind string `json:"kind"`
+		}
+		if err := json.Unmarshal(raw, &head); err != nil {
+			var syntax *json.SyntaxError
+			if errors.As(err, &syntax) {
+				continue
+			}
+			return fmt.Errorf("line %d: %w", line, err)
+		}
+		switch head.Kind {
+		case "frame":
+			var fr traceFrame
+			if err := json.Unmarshal(raw, &fr); err != nil {
+				return fmt.Errorf("line %d: %w", line, err)
+			}
+			c := get(fr.Cast)
+			c.Frames = append(c.Frames, fr.BiteFeatures)
+		case "outcome":
+			var out traceOutcome
+			if err := json.Unmarshal(raw, &out); err != nil {
+				return fmt.Errorf("line %d: %w", line, err)
+			}
+			get(out.Cast).Outcome = out.Outcome
+		}
+	}
+	return sc.Err()
+}
+
+func readTraceCSV(r io.Reader, get func(int) *TracedCast) error {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	rows, err := cr.ReadAll()
+	if err != nil {
+		return err
+	}
+	if len(rows) == 0 {
+		return nil
+	}
+	col := map[string]int{}
+	for i, name := range rows[0] {
+		col[name] = i
+	}
+	for _, name := range csvHeader {
+		if _, ok := col[name]; !ok {
+			return fmt.Errorf("missing column %q", name)
+		}
+	}
+	for n, row := range rows[1:] {
+		if len(row) != len(rows[0]) {
+			continue
+		}
+		var perr error
+		field := func(name string) string { return row[col[name]] }
+		num := func(name string) float64 {
+			v, err := strconv.ParseFloat(field(name), 64)
+			if err != nil && perr == nil {
+				perr = err
+			}
+			return v
+		}
+		count := func(name string) int { return int(num(name)) }
+		flag := func(name string) bool { return field(name) == "true" }
+		cast := count("cast")
+		switch field("kind") {
+		case "frame":
+			f := BiteFeatures{
+				Frame: count("frame"), Lag: num("lag_ms"), DT: num("dt"),
+				RatioChanged: num("ratio_changed"), DiffBaseMean: num("diff_base_mean"),
+				Mean: num("mean"), Std: num("std"), Window: count("window"), Span: num("span_ms"),
+				Spike: flag("spike"), BaseJump: flag("base_jump"), BigImmediate: flag("big_immediate"),
+				Candidate: flag("candidate"), CandidateFrames: count("candidate_frames"), Triggered: flag("triggered"),
+			}
+			f.Time, err = time.Parse(time.RFC3339Nano, field("time"))
+			if err != nil && perr == nil {
+				perr = err
+			}
+			if perr == nil {
+				c := get(cast)
+				c.Frames = append(c.Frames, f)
+			}
+		case "outcome":
+			if perr == nil {
+				get(cast).Outcome = field("outcome")
+			}
+		}
+		if perr != nil {
+			return fmt.Errorf("row %d: %w", n+2, perr)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,229 @@
+package fishing
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"image"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestTraceWriter_Formats(t *testing.T) {
+	at := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	feat := BiteFeatures{Time: at, Frame: 3, DT: 1.5, RatioChanged: 0.2, Window: 2, Spike: true, Candidate: true, CandidateFrames: 1}
+
+	var jl bytes.Buffer
+	tw := NewTraceWriter(&jl, TraceJSONL)
+	tw.Frame(1, feat)
+	tw.Outcome(1, OutcomeReel, at)
+	if err := tw.Close(); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(jl.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 JSON lines, got %q", jl.String())
+	}
+	var frame map[string]any
+	if err := json.Unmarshal([]byte(lines[0]), &frame); err != nil {
+		t.Fatalf("frame line: %v", err)
+	}
+	if frame["kind"] != "frame" || frame["cast"] != 1.0 || frame["dt"] != 1.5 || frame["spike"] != true {
+		t.Fatalf("unexpected frame record %v", frame)
+	}
+	var outcome map[string]any
+	if err := json.Unmarshal([]byte(lines[1]), &outcome); err != nil {
+		t.Fatalf("outcome line: %v", err)
+	}
+	if outcome["kind"] != "outcome" || outcome["outcome"] != OutcomeReel {
+		t.Fatalf("unexpected outcome record %v", outcome)
+	}
+
+	var cb bytes.Buffer
+	tw = NewTraceWriter(&cb, TraceCSV)
+	tw.Frame(2, feat)
+	tw.Outcome(2, OutcomeLost, at)
+	if err := tw.Close(); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+	rows, err := csv.NewReader(&cb).ReadAll()
+	if err != nil {
+		t.Fatalf("csv: %v", err)
+	}
+	if len(rows) != 3 || rows[0][0] != "cast" {
+		t.Fatalf("expected header and 2 rows, got %v", rows)
+	}
+	if rows[1][1] != "frame" || rows[1][5] != "1.5000" || rows[1][12] != "true" {
+		t.Fatalf("unexpected frame row %v", rows[1])
+	}
+	if last := rows[2][len(rows[2])-1]; rows[2][1] != "outcome" || last != OutcomeLost {
+		t.Fatalf("unexpected outcome row %v", rows[2])
+	}
+}
+
+func TestReadTrace_RoundTrip(t *testing.T) {
+	at := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	frames := []BiteFeatures{
+		{Time: at, Frame: 1, Lag: 50, DT: 12.5, RatioChanged: 0.1, DiffBaseMean: 3, Mean: 10, Std: 2, Window: 4, Span: 150},
+		{Time: at.Add(50 * time.Millisecond), Frame: 2, Lag: 50, DT: 900, RatioChanged: 0.4, Spike: true, Candidate: true, CandidateFrames: 1, Triggered: true},
+	}
+	for _, name := range []string{"trace.jsonl", "trace.csv"} {
+		path := filepath.Join(t.TempDir(), name)
+		tw, err := OpenTrace(path)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		tw.Frame(1, frames[0])
+		tw.Outcome(1, OutcomeLost, at)
+		tw.Frame(2, frames[1])
+		if err := tw.Close(); err != nil {
+			t.Fatalf("%s: close: %v", name, err)
+		}
+		casts, err := ReadTrace(path)
+		if err != nil {
+			t.Fatalf("%s: read: %v", name, err)
+		}
+		want := []TracedCast{
+			{Cast: 1, Frames: frames[:1], Outcome: OutcomeLost},
+			{Cast: 2, Frames: frames[1:]},
+		}
+		if !reflect.DeepEqual(casts, want) {
+			t.Fatalf("%s: read back %+v, want %+v", name, casts, want)
+		}
+	}
+}
+
+func TestOpenTrace_AppendsRunsWithDistinctCasts(t *testing.T) {
+	at := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	for _, name := range []string{"trace.jsonl", "trace.csv"} {
+		path := filepath.Join(t.TempDir(), name)
+		for run := 0; run < 2; run++ {
+			tw, err := OpenTrace(path)
+			if err != nil {
+				t.Fatalf("%s: run %d: %v", name, run, err)
+			}
+			// Every run numbers its casts from 1.
+			for cast := 1; cast <= 2; cast++ {
+				tw.Frame(cast, BiteFeatures{Time: at, Frame: run})
+				tw.Outcome(cast, OutcomeLost, at)
+			}
+			if err := tw.Close(); err != nil {
+				t.Fatalf("%s: close: %v", name, err)
+			}
+		}
+		// A crash leaves a record cut short; the next run starts a new line.
+		f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		cut := `{"cast":4,"ki`
+		if name == "trace.csv" {
+			cut = `4,fra`
+		}
+		f.WriteString(cut)
+		f.Close()
+		tw, err := OpenTrace(path)
+		if err != nil {
+			t.Fatalf("%s: reopen after crash: %v", name, err)
+		}
+		tw.Outcome(1, OutcomeHalt, at)
+		if err := tw.Close(); err != nil {
+			t.Fatalf("%s: close: %v", name, err)
+		}
+		raw, _ := os.ReadFile(path)
+		if n := strings.Count(string(raw), "candidate_frames"); name == "trace.csv" && n != 1 {
+			t.Fatalf("%s: %d CSV headers", name, n)
+		}
+		casts, err := ReadTrace(path)
+		if err != nil {
+			t.Fatalf("%s: read: %v", name, err)
+		}
+		var got []int
+		for _, c := range casts {
+			got = append(got, c.Cast)
+		}
+		if want := []int{1, 2, 3, 4, 5}; !reflect.DeepEqual(got, want) {
+			t.Fatalf("%s: casts %v, want %v", name, got, want)
+		}
+		if casts[2].Frames[0].Frame != 1 || casts[4].Outcome != OutcomeHalt {
+			t.Fatalf("%s: unexpected casts %+v", name, casts)
+		}
+	}
+}
+
+// recordingTrace collects trace calls for assertions.
+type recordingTrace struct {
+	mu       sync.Mutex
+	frames   []int // cast id per frame record
+	outcomes []string
+}
+
+func (r *recordingTrace) Frame(cast int, f BiteFeatures) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.frames = append(r.frames, cast)
+}
+
+func (r *recordingTrace) Outcome(cast int, outcome string, t time.Time) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.outcomes = append(r.outcomes, outcome)
+}
+
+// recordingROI counts recorded ROI frames and collects outcomes.
+type recordingROI struct {
+	recordingTrace
+	rois int
+}
+
+func (r *recordingROI) ROIFrame(cast int, roi *image.RGBA, t time.Time) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.rois++
+}
+
+func TestFishingFSM_TracesFramesAndOutcome(t *testing.T) {
+	m := newTestFSM()
+	rec := &recordingTrace{}
+	m.SetTrace(rec)
+	roi := &recordingROI{}
+	m.SetRecorder(roi)
+	m.EventAwaitFocus()
+	m.EventFocusAcquired()
+	m.EventTargetAcquiredAt(1, 2)
+	waitForState(t, m, StateMonitoring, 200*time.Millisecond)
+	now := time.Now()
+	for i := 0; i < 6; i++ {
+		m.ProcessMonitoringFrame(synthFrame(40, 40, 80, nil), now.Add(time.Duration(i)*50*time.Millisecond))
+	}
+	m.ProcessMonitoringFrame(synthFrame(40, 40, 80, func(px []byte, w, h int) { applyRegion(px, w, h, 10, 10, 30, 30, 140) }), now.Add(350*time.Millisecond))
+	waitForState(t, m, StateCooldown, 200*time.Millisecond)
+	m.EventHalt()
+	waitForState(t, m, StateHalt, 200*time.Millisecond)
+
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	// The first frame only seeds the detector; the other 6 are traced.
+	if len(rec.frames) != 6 {
+		t.Fatalf("expected 6 traced frames, got %d", len(rec.frames))
+	}
+	for _, c := range rec.frames {
+		if c != 1 {
+			t.Fatalf("frame traced for cast %d, want 1", c)
+		}
+	}
+	if len(rec.outcomes) != 1 || rec.outcomes[0] != OutcomeReel {
+		t.Fatalf("expected a single reel outcome, got %v", rec.outcomes)
+	}
+	roi.mu.Lock()
+	defer roi.mu.Unlock()
+	// The recorder gets every monitored frame, including the seeding one.
+	if roi.rois != 7 || len(roi.outcomes) != 1 || roi.outcomes[0] != OutcomeReel {
+		t.Fatalf("recorder got %d frames and outcomes %v, want 7 and [reel]", roi.rois, roi.outcomes)
+	}
+}
@@ -0,0 +1,227 @@
+package fishing
+
+import (
+	"image"
+	"log/slog"
+	"math"
+	"time"
+
+	"github.com/soocke/pixel-bot-go/config"
+)
+
+const (
+	// dispSettleFrames seed the bobber's rest position after Reset.
+	dispSettleFrames = 5
+	// dispNoiseK scales the median absolute deviation of the ROI's colour
+	// deviations into the contrast a pixel needs to count as bobber;
+	// dispMinContrast is the floor (sum of RGB differences).
+	dispNoiseK      = 4.0
+	dispMinContrast = 36.0
+	// dispWindow is the half size, as a share of the ROI, of the window
+	// around the rest position that is tracked; waves and splashes at the
+	// ROI edges are ignored.
+	dispWindow = 0.35
+	// dispPlungeFrac is the downward displacement, as a share of the ROI
+	// height, that counts as a plunge when reached within dispPlungeFrames.
+	dispPlungeFrac   = 0.08
+	dispPlungeFrames = 3
+	// dispVanishRatio is the share of the rest mass, and of the previous
+	// frame's mass, below which the bobber counts as pulled under. It only
+	// counts if the bobber was within dispCentreFrac of the window's half
+	// size from the rest position the frame before; a bobber that leaves
+	// through the window edge drifted away and is searched for again.
+	dispVanishRatio = 0.3
+	dispCentreFrac  = 0.5
+	// dispDisturbRatio is the multiple of the rest mass above which a frame
+	// is dominated by something else (a wave crest, a splash) and ignored.
+	dispDisturbRatio = 3.0
+	// dispRestAlpha is the adaptation rate of the rest position and mass, so
+	// slow drift and gentle bobbing are followed.
+	dispRestAlpha = 0.1
+)
+
+// DisplacementDetector detects bites from the bobber's vertical movement.
+// Every frame it locates the bobber as the centroid of the pixels whose
+// colour stands out from the ROI's median colour, weighted by how much they
+// stand out. A bite is a sharp downward plunge of that centroid, or the
+// bobber vanishing, relative to a slowly adapting rest position. Changes of
+// the water alone (waves, glints, brightness) move the median rather than
+// the centroid and are ignored.
+// Not safe for concurrent use; call FeedFrame from a single goroutine.
+type DisplacementDetector struct {
+	castTimer
+	logger       *slog.Logger
+	dev          []float64 // per-pixel colour deviation scratch
+	frames       int       // frames with a visible bobber since Reset
+	restX, restY float64
+	restMass     float64
+	lastX, lastY float64 // centroid of the previous tracked frame
+	lastMass     float64
+	recent       [dispPlungeFrames]float64 // centroid y of the last frames, oldest at rIdx
+	rIdx         int
+	triggered    bool
+}
+
+// NewDisplacementDetector returns a DisplacementDetector. Its thresholds are
+// fixed, relative to the ROI size and noise; cfg only limits the cast
+// duration.
+func NewDisplacementDetector(cfg *config.Config, logger *slog.Logger) *DisplacementDetector {
+	return &DisplacementDetector{castTimer: newCastTimer(cfg, logger), logger: logger}
+}
+
+// Reset clears the rest position and starts a new cast.
+func (d *DisplacementDetector) Reset() {
+	d.startCast()
+	d.frames = 0
+	d.restX, d.restY, d.restMass = 0, 0, 0
+	d.lastX, d.lastY, d.lastMass = 0, 0, 0
+	d.recent = [dispPlungeFrames]float64{}
+	d.rIdx = 0
+	d.triggered = false
+}
+
+// FeedFrame processes one ROI frame and returns true on a plunge.
+func (d *DisplacementDetector) FeedFrame(frame *image.RGBA, t time.Time) bool {
+	if frame == nil || d.triggered {
+		return false
+	}
+	b := frame.Bounds()
+	w, h := b.Dx(), b.Dy()
+	if w <= 0 || h <= 0 {
+		return false
+	}
+	var win image.Rectangle
+	hw, hh := int(dispWindow*float64(w)), int(dispWindow*float64(h))
+	if d.frames >= dispSettleFrames {
+		cx, cy := int(d.restX), int(d.restY)
+		win = image.Rect(cx-hw, cy-hh, cx+hw, cy+hh).Intersect(image.Rect(0, 0, w, h))
+	} else {
+		win = image.Rect(0, 0, w, h)
+	}
+	cx, cy, mass := d.locate(frame, win)
+	minMass := dispMinContrast * float64(w*h) * 0.002
+	if d.frames < dispSettleFrames {
+		if mass < minMass {
+			return false // no bobber in view yet
+		}
+		n := float64(d.frames)
+		d.restX = (d.restX*n + cx) / (n + 1)
+		d.restY = (d.restY*n + cy) / (n + 1)
+		d.restMass = (d.restMass*n + mass) / (n + 1)
+		d.track(cx, cy, mass)
+		return false
+	}
+	plunge, vanished := false, false
+	lost := mass < dispVanishRatio*d.restMass
+	disturbed := mass > dispDisturbRatio*d.restMass
+	if lost {
+		centred := math.Abs(d.lastX-d.restX) <= dispCentreFrac*float64(hw) && math.Abs(d.lastY-d.restY) <= dispCentreFrac*float64(hh)
+		vanished = centred && mass < dispVanishRatio*d.lastMass
+		if !vanished {
+			d.frames = 0 // drifted out of the window: settle on it anew
+			return false
+		}
+	}
+	if !vanished && !disturbed {
+		need := dispPlungeFrac * float64(h)
+		plunge = cy-d.restY > need && cy-d.recent[d.rIdx] > need
+	}
+	if plunge || vanished {
+		d.triggered = true
+		if d.logger != nil {
+			d.logger.Info("bite detected", "detector", config.BiteDetectorDisplacement, "dy", cy-d.restY, "mass", mass, "restMass", d.restMass, "vanished", vanished)
+		}
+		return true
+	}
+	if !disturbed {
+		d.restX += dispRestAlpha * (cx - d.restX)
+		d.restY += dispRestAlpha * (cy - d.restY)
+		d.restMass += dispRestAlpha * (mass - d.restMass)
+		d.track(cx, cy, mass)
+	}
+	return false
+}
+
+// track records the bobber found in an accepted frame.
+func (d *DisplacementDetector) track(cx, cy, mass float64) {
+	d.lastX, d.lastY, d.lastMass = cx, cy, mass
+	d.push(cy)
+	d.frames++
+}
+
+// push records the centroid y of the latest frame.
+func (d *DisplacementDetector) push(cy float64) {
+	if d.frames == 0 {
+		for i := range d.recent {
+			d.recent[i] = cy
+		}
+		return
+	}
+	d.recent[d.rIdx] = cy
+	d.rIdx = (d.rIdx + 1) % len(d.recent)
+}
+
+// locate returns the deviation weighted centroid (relative to the frame
+// bounds) and total weight of the bobber pixels inside win. A pixel's
+// deviation is its summed RGB distance to the median colour of the whole
+// ROI; only deviations well above the typical one count.
+func (d *DisplacementDetector) locate(frame *image.RGBA, win image.Rectangle) (cx, cy, mass float64) {
+	b := frame.Bounds()
+	w, h := b.Dx(), b.Dy()
+	var hist [3][256]int
+	for y := 0; y < h; y++ {
+		row := frame.Pix[y*frame.Stride : y*frame.Stride+w*4]
+		for x := 0; x < w; x++ {
+			hist[0][row[x*4]]++
+			hist[1][row[x*4+1]]++
+			hist[2][row[x*4+2]]++
+		}
+	}
+	var med [3]float64
+	for c := range med {
+		med[c] = float64(histMedian(hist[c][:], w*h))
+	}
+	if cap(d.dev) < w*h {
+		d.dev = make([]float64, w*h)
+	}
+	dev := d.dev[:w*h]
+	var devHist [766]int
+	for y := 0; y < h; y++ {
+		row := frame.Pix[y*frame.Stride : y*frame.Stride+w*4]
+		for x := 0; x < w; x++ {
+			v := math.Abs(float64(row[x*4])-med[0]) + math.Abs(float64(row[x*4+1])-med[1]) + math.Abs(float64(row[x*4+2])-med[2])
+			dev[y*w+x] = v
+			devHist[int(v)]++
+		}
+	}
+	floor := math.Max(dispMinContrast, dispNoiseK*float64(histMedian(devHist[:], w*h)))
+	var sx, sy float64
+	for y := win.Min.Y; y < win.Max.Y; y++ {
+		for x := win.Min.X; x < win.Max.X; x++ {
+			if v := dev[y*w+x] - floor; v > 0 {
+				sx += v * (float64(x) + 0.5)
+				sy += v * (float64(y) + 0.5)
+				mass += v
+			}
+		}
+	}
+	if mass == 0 {
+		return d.restX, d.restY, 0
+	}
+	return sx / mass, sy / mass, mass
+}
+
+// histMedian returns the median bin of a histogram holding n samples.
+func histMedian(hist []int, n int) int {
+	acc := 0
+	for i, c := range hist {
+		acc += c
+		if 2*acc >= n {
+			return i
+		}
+	}
+	return len(hist) - 1
+}
+
+// compile-time check that DisplacementDetector implements BiteDetectorContract.
+var _ BiteDetectorContract = (*DisplacementDetector)(nil)
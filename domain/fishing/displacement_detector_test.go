@@ -0,0 +1,145 @@
+package fishing
+
+import (
+	"image"
+	"math"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/soocke/pixel-bot-go/config"
+)
+
+// bobberScene renders an 80x80 ROI of rippling blue water with a red and
+// white bobber centred at (40, bobY); glint brightens the whole water.
+func bobberScene(rng *rand.Rand, frame int, bobY float64, glint float64) *image.RGBA {
+	return bobberSceneAt(rng, frame, 40, bobY, glint)
+}
+
+// bobberSceneAt is bobberScene with the bobber centred at (bobX, bobY).
+func bobberSceneAt(rng *rand.Rand, frame int, bobX, bobY float64, glint float64) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, 80, 80))
+	for y := 0; y < 80; y++ {
+		for x := 0; x < 80; x++ {
+			wave := 12*math.Sin(0.45*float64(y)+0.3*float64(x)-0.8*float64(frame)) + float64(rng.Intn(9)) - 4 + glint
+			r, g, b := 25+wave*0.5, 85+wave, 140+wave
+			dx, dy := float64(x)-bobX, float64(y)-bobY
+			if dx*dx+dy*dy <= 36 {
+				r, g, b = 210, 40, 40
+				if dy > 2 {
+					r, g, b = 235, 235, 220
+				}
+			}
+			i := img.PixOffset(x, y)
+			img.Pix[i], img.Pix[i+1], img.Pix[i+2], img.Pix[i+3] = clampByte(r), clampByte(g), clampByte(b), 255
+		}
+	}
+	return img
+}
+
+func clampByte(v float64) uint8 {
+	return uint8(math.Max(0, math.Min(255, math.Round(v))))
+}
+
+// bobbing returns the rest position of a gently bobbing bobber at frame i.
+func bobbing(i int) float64 { return 40 + 2*math.Sin(0.3*float64(i)) }
+
+func TestDisplacementDetector_TriggersOnPlunge(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	d := NewDisplacementDetector(nil, nil)
+	d.Reset()
+	start := time.Now()
+	for i := 0; i < 60; i++ {
+		if d.FeedFrame(bobberScene(rng, i, bobbing(i), 0), start.Add(time.Duration(i)*50*time.Millisecond)) {
+			t.Fatalf("bobbing triggered at frame %d", i)
+		}
+	}
+	for k, dy := range []float64{4, 10} {
+		i := 60 + k
+		if d.FeedFrame(bobberScene(rng, i, bobbing(i)+dy, 0), start.Add(time.Duration(i)*50*time.Millisecond)) {
+			if dy < 10 {
+				t.Fatalf("triggered before the plunge was deep enough (dy %.0f)", dy)
+			}
+			return
+		}
+	}
+	t.Fatalf("plunge not detected")
+}
+
+func TestDisplacementDetector_TriggersWhenPulledUnder(t *testing.T) {
+	rng := rand.New(rand.NewSource(3))
+	d := NewDisplacementDetector(nil, nil)
+	d.Reset()
+	start := time.Now()
+	for i := 0; i < 40; i++ {
+		if d.FeedFrame(bobberScene(rng, i, bobbing(i), 0), start.Add(time.Duration(i)*50*time.Millisecond)) {
+			t.Fatalf("bobbing triggered at frame %d", i)
+		}
+	}
+	if !d.FeedFrame(bobberScene(rng, 40, -100, 0), start.Add(40*50*time.Millisecond)) {
+		t.Fatalf("vanished bobber not detected")
+	}
+}
+
+func TestDisplacementDetector_IgnoresBobberDriftingAway(t *testing.T) {
+	rng := rand.New(rand.NewSource(4))
+	d := NewDisplacementDetector(nil, nil)
+	d.Reset()
+	start := time.Now()
+	for i := 0; i < 60; i++ {
+		x := 40.0
+		if i >= 20 {
+			x += 3 * float64(i-20) // drifts sideways faster than the rest position follows
+		}
+		if d.FeedFrame(bobberSceneAt(rng, i, x, bobbing(i), 0), start.Add(time.Duration(i)*50*time.Millisecond)) {
+			t.Fatalf("drift triggered at frame %d (bobber x %.0f)", i, x)
+		}
+	}
+}
+
+func TestDisplacementDetector_IgnoresWaterChangesThatFoolMotion(t *testing.T) {
+	motion := NewBiteDetector(nil, nil)
+	disp := NewDisplacementDetector(nil, nil)
+	motion.Reset()
+	disp.Reset()
+	rng := rand.New(rand.NewSource(2))
+	start := time.Now()
+	motionFired := false
+	for i := 0; i < 80; i++ {
+		glint := 0.0
+		if i >= 40 && i < 43 {
+			glint = 60 // a bright wave crest sweeps over the ROI
+		}
+		frame := bobberScene(rng, i, bobbing(i), glint)
+		at := start.Add(time.Duration(i) * 50 * time.Millisecond)
+		if motion.FeedFrame(frame, at) {
+			motionFired = true
+		}
+		if disp.FeedFrame(frame, at) {
+			t.Fatalf("displacement detector triggered at frame %d", i)
+		}
+	}
+	if !motionFired {
+		t.Fatalf("expected the glint to trigger the motion detector")
+	}
+}
+
+func TestConfiguredDetector_SelectsByName(t *testing.T) {
+	cfg := config.DefaultConfig()
+	if _, ok := ConfiguredDetector(cfg, nil).(*BiteDetector); !ok {
+		t.Fatalf("default should be the motion detector")
+	}
+	cfg.BiteDetector = config.BiteDetectorDisplacement
+	if _, ok := ConfiguredDetector(cfg, nil).(*DisplacementDetector); !ok {
+		t.Fatalf("expected the displacement detector")
+	}
+	cfg.BiteDetector = "bogus"
+	if _, ok := ConfiguredDetector(cfg, nil).(*BiteDetector); !ok {
+		t.Fatalf("unknown names should fall back to motion")
+	}
+	for _, want := range []string{config.BiteDetectorMotion, config.BiteDetectorDisplacement, config.BiteDetectorSplash} {
+		if _, err := NewBiteDetectorByName(want, nil, nil); err != nil {
+			t.Fatalf("%s not registered (have %v)", want, BiteDetectorNames())
+		}
+	}
+}
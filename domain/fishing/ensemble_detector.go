@@ -0,0 +1,153 @@
+package fishing
+
+import (
+	"fmt"
+	"image"
+	"log/slog"
+	"time"
+
+	"github.com/soocke/pixel-bot-go/config"
+)
+
+// ensembleMember is one detector of an EnsembleDetector.
+type ensembleMember struct {
+	name     string
+	det      BiteDetectorContract
+	weight   float64
+	lastVote int // frame number of the member's latest vote, -1 for none
+}
+
+// EnsembleDetector runs several bite detectors on the same ROI frames and
+// combines their votes with the strategy of cfg.Ensemble. A member that
+// fires without the ensemble firing is reset, so it can vote again once it
+// has re-learned the water.
+// Not safe for concurrent use; call FeedFrame from a single goroutine.
+type EnsembleDetector struct {
+	castTimer
+	logger    *slog.Logger
+	ens       config.EnsembleConfig
+	members   []*ensembleMember
+	frame     int
+	triggered bool
+}
+
+// NewEnsembleDetector builds the members named in cfg.Ensemble from the
+// registry, each with the same cfg; members that are unknown or fail to
+// build are skipped, and an ensemble left without members is an error. The
+// ensemble times the cast itself, so a member reset after a lone vote does
+// not extend it.
+func NewEnsembleDetector(cfg *config.Config, logger *slog.Logger) (*EnsembleDetector, error) {
+	if cfg == nil {
+		cfg = config.DefaultConfig()
+	}
+	e := &EnsembleDetector{castTimer: newCastTimer(cfg, logger), logger: logger, ens: cfg.Ensemble.Normalize()}
+	for i, name := range e.ens.Members {
+		det, err := NewBiteDetectorByName(name, cfg, logger)
+		if err != nil {
+			if logger != nil {
+				logger.Warn("ensemble member skipped", "error", err)
+			}
+			continue
+		}
+		e.members = append(e.members, &ensembleMember{name: name, det: det, weight: e.ens.Weights[i], lastVote: -1})
+	}
+	if len(e.members) == 0 {
+		return nil, fmt.Errorf("ensemble: none of the members %v is available", e.ens.Members)
+	}
+	return e, nil
+}
+
+// Reset resets all members and starts a new cast.
+func (e *EnsembleDetector) Reset() {
+	e.startCast()
+	e.frame = 0
+	e.triggered = false
+	for _, m := range e.members {
+		m.det.Reset()
+		m.lastVote = -1
+	}
+}
+
+// FeedFrame feeds frame to every member and returns true when their votes
+// satisfy the strategy.
+func (e *EnsembleDetector) FeedFrame(frame *image.RGBA, t time.Time) bool {
+	if frame == nil || e.triggered || len(e.members) == 0 {
+		return false
+	}
+	var fired []*ensembleMember
+	for _, m := range e.members {
+		if m.det.FeedFrame(frame, t) {
+			m.lastVote = e.frame
+			fired = append(fired, m)
+		}
+	}
+	voters, score := e.vote()
+	e.frame++
+	if voters != nil {
+		e.triggered = true
+		if e.logger != nil {
+			names := make([]string, len(voters))
+			for i, m := range voters {
+				names[i] = m.name
+			}
+			e.logger.Info("bite detected", "detector", config.BiteDetectorEnsemble, "strategy", e.ens.Strategy, "voters", names, "score", score)
+		}
+		return true
+	}
+	for _, m := range fired {
+		m.det.Reset()
+	}
+	return false
+}
+
+// vote returns the members whose votes count for the current frame and the
+// combined score when the strategy is satisfied, nil otherwise.
+func (e *EnsembleDetector) vote() ([]*ensembleMember, float64) {
+	window := 1
+	if e.ens.Strategy == config.EnsembleWindow {
+		window = e.ens.WindowFrames
+	}
+	var voters []*ensembleMember
+	var weight, total float64
+	for _, m := range e.members {
+		total += m.weight
+		if m.lastVote >= 0 && e.frame-m.lastVote < window {
+			voters = append(voters, m)
+			weight += m.weight
+		}
+	}
+	if len(voters) == 0 {
+		return nil, 0
+	}
+	switch e.ens.Strategy {
+	case config.EnsembleAny:
+		return voters, float64(len(voters))
+	case config.EnsembleMajority:
+		if 2*len(voters) > len(e.members) {
+			return voters, float64(len(voters))
+		}
+	case config.EnsembleWeighted:
+		if total > 0 && weight >= e.ens.Threshold*total {
+			return voters, weight / total
+		}
+	case config.EnsembleWindow:
+		if len(voters) >= min(e.ens.MinVotes, len(e.members)) {
+			return voters, float64(len(voters))
+		}
+	}
+	return nil, 0
+}
+
+// LastFeatures forwards the features of the first member that exposes them,
+// so bite traces keep working with an ensemble.
+func (e *EnsembleDetector) LastFeatures() (BiteFeatures, bool) {
+	for _, m := range e.members {
+		if src, ok := m.det.(FeatureSource); ok {
+			return src.LastFeatures()
+		}
+	}
+	return BiteFeatures{}, false
+}
+
+// compile-time check that EnsembleDetector implements BiteDetectorContract.
+var _ BiteDetectorContract = (*EnsembleDetector)(nil)
@@ -0,0 +1,143 @@
+package fishing
+
+import (
+	"bytes"
+	"image"
+	"log/slog"
+	"math/rand"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/soocke/pixel-bot-go/config"
+)
+
+// scriptedDetector fires on the given frame numbers. Reset only counts, so
+// frame numbers refer to the whole cast.
+type scriptedDetector struct {
+	fireAt map[int]bool
+	frame  int
+	resets int
+}
+
+func (s *scriptedDetector) FeedFrame(*image.RGBA, time.Time) bool {
+	s.frame++
+	return s.fireAt[s.frame-1]
+}
+func (s *scriptedDetector) TargetLostHeuristic() bool { return false }
+func (s *scriptedDetector) Reset()                    { s.resets++ }
+
+// scriptedEnsemble builds an ensemble of scripted members firing on the
+// given frame numbers.
+func scriptedEnsemble(ens config.EnsembleConfig, logger *slog.Logger, fires ...[]int) (*EnsembleDetector, []*scriptedDetector) {
+	e := &EnsembleDetector{logger: logger, ens: ens}
+	var dets []*scriptedDetector
+	for i, f := range fires {
+		d := &scriptedDetector{fireAt: map[int]bool{}}
+		for _, n := range f {
+			d.fireAt[n] = true
+		}
+		dets = append(dets, d)
+		e.members = append(e.members, &ensembleMember{name: ens.Members[i], det: d, weight: ens.Weights[i], lastVote: -1})
+	}
+	e.Reset()
+	return e, dets
+}
+
+// firstTrigger feeds n frames and returns the frame that triggered, or -1.
+func firstTrigger(e *EnsembleDetector, n int) int {
+	img := image.NewRGBA(image.Rect(0, 0, 4, 4))
+	for i := 0; i < n; i++ {
+		if e.FeedFrame(img, time.Now()) {
+			return i
+		}
+	}
+	return -1
+}
+
+func TestEnsembleDetector_Strategies(t *testing.T) {
+	// Member a fires on 3 and 8, b on 5 and 8, c on 6.
+	fires := [][]int{{3, 8}, {5, 8}, {6}}
+	cases := []struct {
+		ens  config.EnsembleConfig
+		want int
+	}{
+		{config.EnsembleConfig{Strategy: config.EnsembleAny}, 3},
+		{config.EnsembleConfig{Strategy: config.EnsembleMajority}, 8},
+		{config.EnsembleConfig{Strategy: config.EnsembleWeighted, Weights: []float64{1, 1, 3}, Threshold: 0.6}, 6},
+		{config.EnsembleConfig{Strategy: config.EnsembleWeighted, Weights: []float64{1, 1, 1}, Threshold: 0.9}, -1},
+		{config.EnsembleConfig{Strategy: config.EnsembleWindow, WindowFrames: 3, MinVotes: 2}, 5},
+		{config.EnsembleConfig{Strategy: config.EnsembleWindow, WindowFrames: 3, MinVotes: 3}, 8},
+		{config.EnsembleConfig{Strategy: config.EnsembleWindow, WindowFrames: 4, MinVotes: 3}, 6},
+		{config.EnsembleConfig{Strategy: config.EnsembleWindow, WindowFrames: 2, MinVotes: 3}, -1},
+	}
+	for _, c := range cases {
+		c.ens.Members = []string{"a", "b", "c"}
+		ens := c.ens.Normalize()
+		e, _ := scriptedEnsemble(ens, nil, fires...)
+		if got := firstTrigger(e, 12); got != c.want {
+			t.Errorf("%s %v: triggered at %d, want %d", ens.Strategy, ens.Weights, got, c.want)
+		}
+	}
+}
+
+func TestEnsembleDetector_ResetsLoneVotersAndLogsVoters(t *testing.T) {
+	var logs bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&logs, nil))
+	ens := config.EnsembleConfig{Members: []string{"motion", "splash"}, Strategy: config.EnsembleMajority}.Normalize()
+	e, dets := scriptedEnsemble(ens, logger, []int{2, 4}, []int{4})
+	if got := firstTrigger(e, 10); got != 4 {
+		t.Fatalf("triggered at %d, want 4", got)
+	}
+	// Reset once by e.Reset, once after the lone vote on frame 2.
+	if dets[0].resets != 2 || dets[1].resets != 1 {
+		t.Fatalf("resets a=%d b=%d, want 2 and 1", dets[0].resets, dets[1].resets)
+	}
+	if out := logs.String(); !strings.Contains(out, "voters=\"[motion splash]\"") {
+		t.Fatalf("trigger log lacks the voters: %s", out)
+	}
+}
+
+func TestEnsembleDetector_DefaultMembersAgreeOnBite(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.BiteDetector = config.BiteDetectorEnsemble
+	d, ok := ConfiguredDetector(cfg, nil).(*EnsembleDetector)
+	if !ok || len(d.members) != 3 {
+		t.Fatalf("expected an ensemble of the three detectors")
+	}
+	d.Reset()
+	start := time.Now()
+	at := func(i int) time.Time { return start.Add(time.Duration(i) * 50 * time.Millisecond) }
+	for i := 0; i < 40; i++ {
+		if d.FeedFrame(bobberScene(rand.New(rand.NewSource(int64(i))), i, bobbing(i), 0), at(i)) {
+			t.Fatalf("calm water triggered at frame %d", i)
+		}
+	}
+	// The bobber plunges and throws up foam.
+	for k, dy := range []float64{10, 14, 16} {
+		i := 40 + k
+		f := bobberScene(rand.New(rand.NewSource(int64(i))), i, bobbing(i)+dy, 0)
+		addSplash(f, rand.New(rand.NewSource(int64(100+i))), 12+6*float64(k), 0.5)
+		if d.FeedFrame(f, at(i)) {
+			return
+		}
+	}
+	t.Fatalf("bite not detected by the ensemble")
+}
+
+func TestConfiguredDetector_EnsembleWithoutMembersFallsBackToMotion(t *testing.T) {
+	var logs bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&logs, nil))
+	cfg := config.DefaultConfig()
+	cfg.BiteDetector = config.BiteDetectorEnsemble
+	cfg.Ensemble.Members = []string{"bogus", "typo"}
+	if _, err := NewBiteDetectorByName(config.BiteDetectorEnsemble, cfg, logger); err == nil {
+		t.Fatalf("expected an error for an ensemble without usable members")
+	}
+	if _, ok := ConfiguredDetector(cfg, logger).(*BiteDetector); !ok {
+		t.Fatalf("expected the motion detector as fallback")
+	}
+	if out := logs.String(); !strings.Contains(out, "bite detector unavailable; using motion") {
+		t.Fatalf("fallback not logged: %s", out)
+	}
+}
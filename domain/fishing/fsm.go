@@ -27,6 +27,9 @@ type FishingFSM struct {
 	detectorCtor     DetectorFactory
 	events           chan interface{}
 	listeners        []FishingStateListener
+	trace            BiteTrace
+	recorder         ROIRecorder
+	castID           int // number of the current (or last) monitored cast
 }
 
 // NewFSM creates and starts a FishingFSM. The FSM starts in StateHalt.
@@ -57,6 +60,10 @@ func (f *FishingFSM) loop() {
 		case FishingStateListener: // unlikely direct send, ignore
 		case evtAddListener:
 			f.listeners = append(f.listeners, e.l)
+		case evtSetTrace:
+			f.trace = e.t
+		case evtSetRecorder:
+			f.recorder = e.r
 		case evtTargetAcquired:
 			if f.state == StateSearching {
 				f.transition(StateMonitoring)
@@ -83,7 +90,12 @@ func (f *FishingFSM) loop() {
 			}
 		case evtMonitoringFrame:
 			if f.state == StateMonitoring && f.biteDetector != nil && e.roi != nil {
-				if f.biteDetector.FeedFrame(e.roi, e.now) {
+				if f.recorder != nil {
+					f.recorder.ROIFrame(f.castID, e.roi, e.now)
+				}
+				bite := f.biteDetector.FeedFrame(e.roi, e.now)
+				f.traceFrame()
+				if bite {
 					f.transition(StateReeling)
 				} else if f.biteDetector.TargetLostHeuristic() {
 					f.transition(StateCasting)
@@ -119,6 +131,8 @@ type (
 	evtAwaitFocus       struct{}
 	evtForceCast        struct{}
 	evtAddListener      struct{ l FishingStateListener }
+	evtSetTrace         struct{ t BiteTrace }
+	evtSetRecorder      struct{ r ROIRecorder }
 	evtCancel           struct{}
 	evtMonitoringFrame  struct {
 		roi *image.RGBA
@@ -131,6 +145,24 @@ func (f *FishingFSM) transition(next FishingState) {
 	if prev == next {
 		return
 	}
+	if prev == StateMonitoring && (f.trace != nil || f.recorder != nil) {
+		outcome := next.String()
+		switch next {
+		case StateReeling:
+			outcome = OutcomeReel
+		case StateCasting:
+			outcome = OutcomeLost
+		case StateHalt:
+			outcome = OutcomeHalt
+		}
+		now := time.Now()
+		if f.trace != nil {
+			f.trace.Outcome(f.castID, outcome, now)
+		}
+		if f.recorder != nil {
+			f.recorder.Outcome(f.castID, outcome, now)
+		}
+	}
 	// stop search timer when leaving StateSearching
 	if prev == StateSearching && next != StateSearching && f.searchTimer != nil {
 		f.searchTimer.Stop()
@@ -208,6 +240,7 @@ func (f *FishingFSM) transition(next FishingState) {
 			}
 		})
 	case StateMonitoring:
+		f.castID++
 		if f.coordSet && f.actions.MoveCursor != nil {
 			cx, cy := f.coordX, f.coordY
 			go func(x, y int) {
@@ -220,9 +253,9 @@ func (f *FishingFSM) transition(next FishingState) {
 			}(cx, cy)
 		}
 		if f.detectorCtor != nil {
-			f.biteDetector = f.detectorCtor(nil, f.logger)
+			f.biteDetector = f.detectorCtor(f.cfg, f.logger)
 		} else {
-			f.biteDetector = NewBiteDetector(nil, f.logger)
+			f.biteDetector = NewBiteDetector(f.cfg, f.logger)
 		}
 		if f.biteDetector != nil {
 			f.biteDetector.Reset()
@@ -275,6 +308,14 @@ func (f *FishingFSM) EventAwaitFocus()                   { f.events <- evtAwaitF
 func (f *FishingFSM) ForceCast()                         { f.events <- evtForceCast{} }
 func (f *FishingFSM) Cancel()                            { f.events <- evtCancel{} }
 
+// SetTrace records the bite detector features of every monitored frame and
+// the outcome of every cast to t; nil stops tracing.
+func (f *FishingFSM) SetTrace(t BiteTrace) { f.events <- evtSetTrace{t: t} }
+
+// SetRecorder passes the ROI frames of every monitored cast and the cast
+// outcomes to r; nil stops recording.
+func (f *FishingFSM) SetRecorder(r ROIRecorder) { f.events <- evtSetRecorder{r: r} }
+
 // Tick is deprecated and is a no-op (retained for backward compatibility).
 func (f *FishingFSM) Tick(now time.Time) {}
 func (f *FishingFSM) ProcessMonitoringFrame(roi *image.RGBA, now time.Time) {
@@ -303,6 +344,19 @@ func (f *FishingFSM) Close() {
 	close(f.events)
 }
 
+// traceFrame passes the features of the frame just fed to the bite detector
+// to the trace, if one is set and the detector exposes them.
+func (f *FishingFSM) traceFrame() {
+	if f.trace == nil {
+		return
+	}
+	if src, ok := f.biteDetector.(FeatureSource); ok {
+		if feat, ok := src.LastFeatures(); ok {
+			f.trace.Frame(f.castID, feat)
+		}
+	}
+}
+
 // recoverLog recovers from a panic and logs the error if a logger is available.
 func recoverLog(logger *slog.Logger, msg string) {
 	if r := recover(); r != nil {
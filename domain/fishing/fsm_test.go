@@ -5,6 +5,8 @@ import (
 	"sync"
 	"testing"
 	"time"
+
+	"github.com/soocke/pixel-bot-go/config"
 )
 
 // Functional transition tests.
@@ -159,3 +161,28 @@ func TestFishingFSM_InvalidEventNoTransition(t *testing.T) {
 		t.Fatalf("unexpected change on invalid bite event: %v", m.Current())
 	}
 }
+
+func TestFishingFSM_DetectorReceivesConfig(t *testing.T) {
+	cfg := config.DefaultConfig()
+	got := make(chan *config.Config, 1)
+	m := NewFSM(discardLogger, cfg, ActionCallbacks{
+		PressKey:   func(byte) {},
+		MoveCursor: func(int, int) {},
+		ClickRight: func() {},
+		ParseVK:    func(string) byte { return 0 },
+	}, func(c *config.Config, l *slog.Logger) BiteDetectorContract {
+		got <- c
+		return NewBiteDetector(c, l)
+	})
+	m.EventAwaitFocus()
+	m.EventFocusAcquired()
+	m.EventTargetAcquiredAt(1, 2)
+	select {
+	case c := <-got:
+		if c != cfg {
+			t.Fatalf("detector built with %p, want the FSM config %p", c, cfg)
+		}
+	case <-time.After(200 * time.Millisecond):
+		t.Fatalf("detector was not constructed")
+	}
+}
@@ -0,0 +1,101 @@
+package fishing
+
+import (
+	"image"
+	"log/slog"
+	"time"
+
+	"github.com/soocke/pixel-bot-go/config"
+)
+
+// ModelDetector detects bites with a trained BiteModel. It measures each
+// frame like BiteDetector and, instead of the hand-tuned thresholds, scores
+// the frame's features with the model loaded from cfg.BiteModel. When the
+// model cannot be loaded it logs a warning and behaves as BiteDetector.
+// Like the motion thresholds, the model's verdict is debounced: the bite
+// probability must stay at or above the model threshold for Bite.DebounceMs.
+// Not safe for concurrent use; call FeedFrame from a single goroutine.
+type ModelDetector struct {
+	logger     *slog.Logger
+	feat       *BiteDetector
+	model      *BiteModel
+	debounce   time.Duration
+	aboveSince time.Time // first frame of the current run at or above the threshold
+	above      bool
+	triggered  bool
+}
+
+// NewModelDetector returns a ModelDetector using the model at cfg.BiteModel.
+// cfg.Bite tunes the measured features and the debounce, as for BiteDetector.
+func NewModelDetector(cfg *config.Config, logger *slog.Logger) *ModelDetector {
+	if cfg == nil {
+		cfg = config.DefaultConfig()
+	}
+	d := &ModelDetector{logger: logger, feat: NewBiteDetector(cfg, logger)}
+	d.debounce = time.Duration(d.feat.params.DebounceMs) * time.Millisecond
+	model, err := LoadBiteModel(cfg.BiteModel)
+	if err != nil {
+		if logger != nil {
+			logger.Warn("bite model unavailable; using motion thresholds", "error", err)
+		}
+		return d
+	}
+	d.model = model
+	return d
+}
+
+// Reset clears the feature state and starts a new cast.
+func (d *ModelDetector) Reset() {
+	d.feat.Reset()
+	d.above, d.aboveSince = false, time.Time{}
+	d.triggered = false
+}
+
+// FeedFrame processes one ROI frame sampled at time t and returns true once
+// the model has scored the frames since DebounceMs ago as a bite.
+func (d *ModelDetector) FeedFrame(frame *image.RGBA, t time.Time) bool {
+	if d.model == nil {
+		return d.feat.FeedFrame(frame, t)
+	}
+	b := d.feat
+	b.hasLast = false
+	if frame == nil || d.triggered {
+		return false
+	}
+	f, ok := b.measure(frame, t)
+	if !ok {
+		return false
+	}
+	b.classify(&f)
+	p := d.model.Score(ModelInputs(f))
+	if p < d.model.Threshold {
+		d.above = false
+	} else if !d.above {
+		d.above, d.aboveSince = true, f.Time
+	}
+	f.Triggered = d.above && f.Time.Sub(d.aboveSince) >= d.debounce
+	b.last, b.hasLast = f, true
+	if f.Triggered {
+		d.triggered = true
+		if d.logger != nil {
+			d.logger.Info("bite detected", "detector", config.BiteDetectorModel, "probability", p, "threshold", d.model.Threshold, "dt", f.DT, "changedRatio", f.RatioChanged)
+		}
+		return true
+	}
+	b.update(f)
+	return false
+}
+
+// LastFeatures returns the features of the most recent frame.
+func (d *ModelDetector) LastFeatures() (BiteFeatures, bool) {
+	return d.feat.LastFeatures()
+}
+
+// TargetLostHeuristic defers to the cast timer of the feature detector,
+// which Reset restarts.
+func (d *ModelDetector) TargetLostHeuristic() bool {
+	return d.feat.TargetLostHeuristic()
+}
+
+// compile-time check that ModelDetector implements BiteDetectorContract.
+var _ BiteDetectorContract = (*ModelDetector)(nil)
@@ -0,0 +1,104 @@
+package fishing
+
+import (
+	"image"
+	"path/filepath"
+	"testing"
+
+	"github.com/soocke/pixel-bot-go/config"
+)
+
+// spikeFrames are five still frames followed by a bright square.
+func spikeFrames() []*image.RGBA {
+	var frames []*image.RGBA
+	for i := 0; i < 5; i++ {
+		frames = append(frames, synthFrame(40, 40, 80, nil))
+	}
+	return append(frames, synthFrame(40, 40, 80, func(px []byte, w, h int) { applyRegion(px, w, h, 10, 10, 30, 30, 140) }))
+}
+
+func TestModelDetector_ScoresFramesWithModel(t *testing.T) {
+	n := len(BiteModelFeatures)
+	m := &BiteModel{
+		Kind: BiteModelLogistic, Features: BiteModelFeatures,
+		Mean: make([]float64, n), Scale: make([]float64, n), Weights: make([]float64, n),
+		Bias: -5, Threshold: 0.5,
+	}
+	m.Weights[2] = 40 // ratio_changed: fires once an eighth of the ROI changes
+	cfg := config.DefaultConfig()
+	cfg.BiteModel = filepath.Join(t.TempDir(), "model.json")
+	if err := m.Save(cfg.BiteModel); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+	cfg.BiteDetector = config.BiteDetectorModel
+	d, ok := ConfiguredDetector(cfg, nil).(*ModelDetector)
+	if !ok || d.model == nil {
+		t.Fatalf("expected a model detector with a loaded model")
+	}
+	d.Reset()
+	if idx := feedFrames(d, spikeFrames()); idx != 5 {
+		t.Fatalf("expected the model to fire at frame 5, got %d", idx)
+	}
+	if f, ok := d.LastFeatures(); !ok || !f.Triggered {
+		t.Fatalf("expected the triggering frame's features, got %+v", f)
+	}
+
+	// Without a model file the detector falls back to the motion thresholds.
+	cfg.BiteModel = filepath.Join(t.TempDir(), "missing.json")
+	fallback := NewModelDetector(cfg, nil)
+	if fallback.model != nil {
+		t.Fatalf("missing model loaded")
+	}
+	fallback.Reset()
+	if idx := feedFrames(fallback, spikeFrames()); idx != 5 {
+		t.Fatalf("fallback: expected trigger at frame 5, got %d", idx)
+	}
+}
+
+func TestLoadBiteModel_RejectsMismatchedFeatures(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "model.json")
+	m := &BiteModel{Kind: BiteModelLogistic, Features: []string{"dt"}, Mean: []float64{0}, Scale: []float64{1}, Weights: []float64{1}, Threshold: 0.5}
+	if err := m.Save(path); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+	if _, err := LoadBiteModel(path); err == nil {
+		t.Fatalf("expected an error for a model with other features")
+	}
+}
+
+func TestModelDetector_DebouncesProbability(t *testing.T) {
+	n := len(BiteModelFeatures)
+	m := &BiteModel{
+		Kind: BiteModelLogistic, Features: BiteModelFeatures,
+		Mean: make([]float64, n), Scale: make([]float64, n), Weights: make([]float64, n),
+		Bias: -5, Threshold: 0.5,
+	}
+	m.Weights[2] = 40
+	cfg := config.DefaultConfig()
+	cfg.BitePreset = config.BitePresetCustom
+	cfg.Bite.DebounceMs = 100
+	cfg.BiteModel = filepath.Join(t.TempDir(), "model.json")
+	if err := m.Save(cfg.BiteModel); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+	d := NewModelDetector(cfg, nil)
+	d.Reset()
+	if idx := feedFrames(d, append(spikeFrames(), synthFrame(40, 40, 80, nil))); idx >= 0 {
+		t.Fatalf("single spike frame fired at %d despite the debounce", idx)
+	}
+
+	// A square flickering every frame keeps the probability up; frames 5 and
+	// 6 span 50ms, frame 7 reaches the 100ms debounce.
+	frames := spikeFrames()
+	for i := 0; i < 4; i++ {
+		v := byte(80)
+		if i%2 == 1 {
+			v = 140
+		}
+		frames = append(frames, synthFrame(40, 40, 80, func(px []byte, w, h int) { applyRegion(px, w, h, 10, 10, 30, 30, v) }))
+	}
+	d.Reset()
+	if idx := feedFrames(d, frames); idx != 7 {
+		t.Fatalf("expected the sustained bite to fire at frame 7, got %d", idx)
+	}
+}
@@ -0,0 +1,247 @@
+package fishing
+
+import (
+	"image"
+	"log/slog"
+	"math"
+	"time"
+
+	"github.com/soocke/pixel-bot-go/config"
+)
+
+const (
+	// periodStep is the sampling interval the bobber's motion is resampled
+	// to, so the model does not depend on the capture rate.
+	periodStep = 50 * time.Millisecond
+	// periodLearn is how long the idle motion is observed before the model
+	// is fitted.
+	periodLearn = 3 * time.Second
+	// periodMinLag and periodMaxLag bound the bob periods searched.
+	periodMinLag = 250 * time.Millisecond
+	periodMaxLag = 2 * time.Second
+	// periodMinCorr is the normalised autocorrelation a lag needs to count
+	// as the rhythm; idle motion without it is modelled by its mean.
+	periodMinCorr = 0.5
+	// periodResidualK scales the deviation of the model's residuals into the
+	// departure that counts as a bite; periodMinDrop is its floor as a share
+	// of the ROI height.
+	periodResidualK = 5.0
+	periodMinDrop   = 0.06
+	// periodAlpha is the per-sample adaptation rate of the residual
+	// deviation, the mean and the rest position after learning.
+	periodAlpha = 0.02
+)
+
+// PeriodicDetector detects bites from a break in the bobber's idle rhythm.
+// It follows the bobber's vertical position like DisplacementDetector,
+// resampled every periodStep. Over the first periodLearn of a cast it
+// estimates the dominant bob period by autocorrelation and its amplitude
+// with the Goertzel algorithm. From then on each sample is predicted by the
+// sample one period earlier (or by the mean when the bobbing has no rhythm)
+// and a bite is a downward departure well beyond the residuals seen while
+// idle, or the bobber vanishing. Rhythmic bobbing of any amplitude is
+// ignored; while learning, only plunges of twice the floor count.
+// Not safe for concurrent use; call FeedFrame from a single goroutine.
+type PeriodicDetector struct {
+	castTimer
+	logger       *slog.Logger
+	loc          DisplacementDetector // bobber locator
+	h            int
+	started      bool
+	lastT        time.Time
+	lastY        float64
+	next         time.Time // time of the next resampled point
+	signal       []float64 // resampled centroid y, oldest first
+	restX, restY float64
+	restMass     float64
+	learned      bool
+	lag          int     // bob period in samples; 0 when there is no rhythm
+	amplitude    float64 // bob amplitude in pixels
+	mean         float64
+	resVar       float64 // variance of the prediction residuals
+	triggered    bool
+}
+
+// NewPeriodicDetector returns a PeriodicDetector. The bob period and the
+// residual noise are learned anew each cast, so cfg only limits the cast
+// duration.
+func NewPeriodicDetector(cfg *config.Config, logger *slog.Logger) *PeriodicDetector {
+	return &PeriodicDetector{castTimer: newCastTimer(cfg, logger), logger: logger}
+}
+
+// Reset forgets the learned rhythm and starts a new cast.
+func (p *PeriodicDetector) Reset() {
+	p.startCast()
+	p.started, p.learned, p.triggered = false, false, false
+	p.signal = p.signal[:0]
+	p.restX, p.restY, p.restMass = 0, 0, 0
+	p.lag, p.amplitude, p.mean, p.resVar = 0, 0, 0, 0
+}
+
+// FeedFrame processes one ROI frame sampled at time t and returns true when
+// the bobber breaks its rhythm.
+func (p *PeriodicDetector) FeedFrame(frame *image.RGBA, t time.Time) bool {
+	if frame == nil || p.triggered {
+		return false
+	}
+	b := frame.Bounds()
+	w, h := b.Dx(), b.Dy()
+	if w <= 0 || h <= 0 {
+		return false
+	}
+	p.h = h
+	win := image.Rect(0, 0, w, h)
+	if p.started {
+		hw, hh := int(dispWindow*float64(w)), int(dispWindow*float64(h))
+		cx, cy := int(p.restX), int(p.restY)
+		win = image.Rect(cx-hw, cy-hh, cx+hw, cy+hh).Intersect(win)
+	}
+	cx, cy, mass := p.loc.locate(frame, win)
+	if !p.started {
+		if mass < dispMinContrast*float64(w*h)*0.002 {
+			return false // no bobber in view yet
+		}
+		p.started = true
+		p.restX, p.restY, p.restMass = cx, cy, mass
+		p.lastT, p.lastY, p.next = t, cy, t.Add(periodStep)
+		p.signal = append(p.signal, cy)
+		return false
+	}
+	if !t.After(p.lastT) || mass > dispDisturbRatio*p.restMass {
+		return false // out of order, or a wave crest or splash dominates
+	}
+	if mass < dispVanishRatio*p.restMass {
+		p.triggered = true
+		if p.logger != nil {
+			p.logger.Info("bite detected", "detector", config.BiteDetectorPeriodic, "vanished", true, "mass", mass, "restMass", p.restMass)
+		}
+		return true
+	}
+	span := t.Sub(p.lastT).Seconds()
+	for ; !p.next.After(t); p.next = p.next.Add(periodStep) {
+		y := p.lastY + (cy-p.lastY)*p.next.Sub(p.lastT).Seconds()/span
+		if r, need, bite := p.sample(y); bite {
+			p.triggered = true
+			if p.logger != nil {
+				p.logger.Info("bite detected", "detector", config.BiteDetectorPeriodic, "residual", r, "need", need, "period", time.Duration(p.lag)*periodStep, "amplitude", p.amplitude)
+			}
+			return true
+		}
+		p.restX += periodAlpha * (cx - p.restX)
+		p.restY += periodAlpha * (y - p.restY)
+		p.restMass += periodAlpha * (mass - p.restMass)
+	}
+	p.lastT, p.lastY = t, cy
+	return false
+}
+
+// sample adds one resampled position and reports whether it departs from
+// the model, together with the residual and the departure needed.
+func (p *PeriodicDetector) sample(y float64) (r, need float64, bite bool) {
+	learnN := int(periodLearn / periodStep)
+	p.signal = append(p.signal, y)
+	if keep := learnN + int(periodMaxLag/periodStep); len(p.signal) > keep {
+		p.signal = append(p.signal[:0], p.signal[len(p.signal)-keep:]...)
+	}
+	floor := periodMinDrop * float64(p.h)
+	if !p.learned {
+		var sum float64
+		for _, v := range p.signal {
+			sum += v
+		}
+		r = y - sum/float64(len(p.signal))
+		if r > 2*floor {
+			return r, 2 * floor, true
+		}
+		if len(p.signal) >= learnN {
+			p.learn(p.signal[len(p.signal)-learnN:])
+		}
+		return r, 2 * floor, false
+	}
+	pred := p.mean
+	if p.lag > 0 {
+		pred = p.signal[len(p.signal)-1-p.lag]
+	}
+	r = y - pred
+	need = math.Max(floor, periodResidualK*math.Sqrt(p.resVar))
+	if r > need {
+		return r, need, true
+	}
+	p.resVar += periodAlpha * (r*r - p.resVar)
+	p.mean += periodAlpha * (y - p.mean)
+	return r, need, false
+}
+
+// learn fits the idle model to x: the mean, the period with the strongest
+// normalised autocorrelation (first local maximum above periodMinCorr), the
+// Goertzel amplitude at that period and the variance of the residuals of
+// predicting each sample by the one a period earlier.
+func (p *PeriodicDetector) learn(x []float64) {
+	n := len(x)
+	var mean, v float64
+	for _, s := range x {
+		mean += s
+	}
+	mean /= float64(n)
+	for _, s := range x {
+		v += (s - mean) * (s - mean)
+	}
+	v /= float64(n)
+	p.learned, p.mean, p.lag, p.amplitude, p.resVar = true, mean, 0, 0, v
+	corr := func(lag int) float64 {
+		var c float64
+		for i := lag; i < n; i++ {
+			c += (x[i] - mean) * (x[i-lag] - mean)
+		}
+		return c / float64(n-lag) / v
+	}
+	best := 0.0
+	if v > 0.25 { // below half a pixel of deviation there is nothing to model
+		lo, hi := int(periodMinLag/periodStep), min(int(periodMaxLag/periodStep), n-2)
+		prev, cur := corr(lo-1), corr(lo)
+		for lag := lo; lag <= hi; lag++ {
+			next := corr(lag + 1)
+			if cur >= periodMinCorr && cur >= prev && cur >= next {
+				p.lag, best = lag, cur
+				break
+			}
+			prev, cur = cur, next
+		}
+	}
+	if p.lag > 0 {
+		p.amplitude = goertzelAmplitude(x, mean, 1/float64(p.lag))
+		var rv float64
+		for i := p.lag; i < n; i++ {
+			d := x[i] - x[i-p.lag]
+			rv += d * d
+		}
+		p.resVar = rv / float64(n-p.lag)
+	}
+	p.resVar = math.Max(p.resVar, 0.25)
+	if p.logger != nil {
+		p.logger.Info("idle bob learned", "detector", config.BiteDetectorPeriodic, "period", time.Duration(p.lag)*periodStep, "amplitude", p.amplitude, "corr", best, "residualStd", math.Sqrt(p.resVar))
+	}
+}
+
+// goertzelAmplitude returns the amplitude of the sinusoid of freq cycles per
+// sample in x after subtracting mean.
+func goertzelAmplitude(x []float64, mean, freq float64) float64 {
+	coeff := 2 * math.Cos(2*math.Pi*freq)
+	var s1, s2 float64
+	for _, v := range x {
+		s0 := v - mean + coeff*s1 - s2
+		s2, s1 = s1, s0
+	}
+	power := s1*s1 + s2*s2 - coeff*s1*s2
+	return 2 * math.Sqrt(math.Max(power, 0)) / float64(len(x))
+}
+
+// Rhythm returns the learned bob period and amplitude in pixels. ok is false
+// until the model is learned; period is 0 when the idle motion has no
+// rhythm.
+func (p *PeriodicDetector) Rhythm() (period time.Duration, amplitude float64, ok bool) {
+	return time.Duration(p.lag) * periodStep, p.amplitude, p.learned
+}
+
+// compile-time check that PeriodicDetector implements BiteDetectorContract.
+var _ BiteDetectorContract = (*PeriodicDetector)(nil)
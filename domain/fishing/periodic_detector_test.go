@@ -0,0 +1,85 @@
+package fishing
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/soocke/pixel-bot-go/config"
+)
+
+// bob returns the bobber position at s seconds of a bob with the given
+// amplitude (pixels) and period (seconds).
+func bob(s, amplitude, period float64) float64 {
+	return 40 + amplitude*math.Sin(2*math.Pi*s/period)
+}
+
+func TestPeriodicDetector_LearnsAndIgnoresIdleBob(t *testing.T) {
+	for _, fps := range []int{20, 30} {
+		rng := rand.New(rand.NewSource(1))
+		d := NewPeriodicDetector(nil, nil)
+		d.Reset()
+		start := time.Now()
+		for i := 0; i < 8*fps; i++ {
+			s := float64(i) / float64(fps)
+			// A strong bob: 10 px from crest to trough.
+			if d.FeedFrame(bobberScene(rng, i, bob(s, 5, 1.2), 0), start.Add(time.Duration(s*float64(time.Second)))) {
+				t.Fatalf("%d fps: idle bob triggered at %.2fs", fps, s)
+			}
+		}
+		period, amp, ok := d.Rhythm()
+		if !ok {
+			t.Fatalf("%d fps: rhythm not learned", fps)
+		}
+		if period < 1100*time.Millisecond || period > 1300*time.Millisecond {
+			t.Fatalf("%d fps: learned period %v, want ~1.2s", fps, period)
+		}
+		if amp < 3.5 || amp > 6.5 {
+			t.Fatalf("%d fps: learned amplitude %.2f, want ~5", fps, amp)
+		}
+	}
+}
+
+func TestPeriodicDetector_TriggersWhenRhythmBreaks(t *testing.T) {
+	for _, fps := range []int{20, 30} {
+		rng := rand.New(rand.NewSource(2))
+		d := NewPeriodicDetector(nil, nil)
+		d.Reset()
+		start := time.Now()
+		const pull = 5.0
+		var at float64 = -1
+		for i := 0; i < 7*fps; i++ {
+			s := float64(i) / float64(fps)
+			y := bob(s, 3, 0.9)
+			if s >= pull {
+				y += math.Min(1, (s-pull)/0.1) * 12 // pulled under within 100ms
+			}
+			if d.FeedFrame(bobberScene(rng, i, y, 0), start.Add(time.Duration(s*float64(time.Second)))) {
+				at = s
+				break
+			}
+		}
+		if at < pull || at > pull+0.2 {
+			t.Fatalf("%d fps: bite at %.2fs, want within 200ms of %.1fs", fps, at, pull)
+		}
+	}
+}
+
+func TestPeriodicDetector_TriggersWhenBobberVanishes(t *testing.T) {
+	rng := rand.New(rand.NewSource(3))
+	d := NewPeriodicDetector(nil, nil)
+	d.Reset()
+	start := time.Now()
+	for i := 0; i < 30; i++ {
+		if d.FeedFrame(bobberScene(rng, i, bobbing(i), 0), start.Add(time.Duration(i)*50*time.Millisecond)) {
+			t.Fatalf("triggered at frame %d", i)
+		}
+	}
+	if !d.FeedFrame(bobberScene(rng, 30, -100, 0), start.Add(30*50*time.Millisecond)) {
+		t.Fatalf("vanished bobber not detected")
+	}
+	if _, err := NewBiteDetectorByName(config.BiteDetectorPeriodic, nil, nil); err != nil {
+		t.Fatalf("periodic detector not registered: %v", err)
+	}
+}
@@ -0,0 +1,110 @@
+package fishing
+
+import (
+	"fmt"
+	"log/slog"
+	"sort"
+	"sync"
+
+	"github.com/soocke/pixel-bot-go/config"
+)
+
+// detectorBuilder builds a registered bite detector; detectors whose
+// settings can leave them unusable report it as an error.
+type detectorBuilder func(cfg *config.Config, l *slog.Logger) (BiteDetectorContract, error)
+
+var (
+	biteDetectorsMu sync.RWMutex
+	biteDetectors   = map[string]detectorBuilder{}
+)
+
+// RegisterBiteDetector makes a bite detector available under name.
+// Registering the same name twice panics.
+func RegisterBiteDetector(name string, factory DetectorFactory) {
+	registerBiteDetector(name, func(cfg *config.Config, l *slog.Logger) (BiteDetectorContract, error) {
+		return factory(cfg, l), nil
+	})
+}
+
+func registerBiteDetector(name string, build detectorBuilder) {
+	biteDetectorsMu.Lock()
+	defer biteDetectorsMu.Unlock()
+	if _, dup := biteDetectors[name]; dup {
+		panic("fishing: bite detector registered twice: " + name)
+	}
+	biteDetectors[name] = build
+}
+
+// BiteDetectorNames lists registered bite detector names in sorted order.
+func BiteDetectorNames() []string {
+	biteDetectorsMu.RLock()
+	defer biteDetectorsMu.RUnlock()
+	names := make([]string, 0, len(biteDetectors))
+	for name := range biteDetectors {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// NewBiteDetectorByName builds the bite detector registered under name. It
+// fails for unknown names and for detectors that cannot be built from cfg.
+func NewBiteDetectorByName(name string, cfg *config.Config, logger *slog.Logger) (BiteDetectorContract, error) {
+	biteDetectorsMu.RLock()
+	build := biteDetectors[name]
+	biteDetectorsMu.RUnlock()
+	if build == nil {
+		return nil, fmt.Errorf("unknown bite detector %q", name)
+	}
+	if cfg == nil {
+		cfg = config.DefaultConfig()
+	}
+	return build(cfg, logger)
+}
+
+// ConfiguredDetector is a DetectorFactory for NewFSM that builds the bite
+// detector named by cfg.BiteDetector, so a changed setting applies from the
+// next cast. Unknown names and detectors that fail to build fall back to
+// the motion detector.
+func ConfiguredDetector(cfg *config.Config, logger *slog.Logger) BiteDetectorContract {
+	name := config.BiteDetectorMotion
+	if cfg != nil && cfg.BiteDetector != "" {
+		name = cfg.BiteDetector
+	}
+	d, err := NewBiteDetectorByName(name, cfg, logger)
+	if err != nil {
+		if logger != nil {
+			logger.Warn("bite detector unavailable; using motion", "error", err)
+		}
+		return NewBiteDetector(cfg, logger)
+	}
+	return d
+}
+
+func init() {
+	RegisterBiteDetector(config.BiteDetectorMotion, func(cfg *config.Config, l *slog.Logger) BiteDetectorContract {
+		return NewBiteDetector(cfg, l)
+	})
+	RegisterBiteDetector(config.BiteDetectorDisplacement, func(cfg *config.Config, l *slog.Logger) BiteDetectorContract {
+		return NewDisplacementDetector(cfg, l)
+	})
+	RegisterBiteDetector(config.BiteDetectorSplash, func(cfg *config.Config, l *slog.Logger) BiteDetectorContract {
+		return NewSplashDetector(cfg, l)
+	})
+	RegisterBiteDetector(config.BiteDetectorPeriodic, func(cfg *config.Config, l *slog.Logger) BiteDetectorContract {
+		return NewPeriodicDetector(cfg, l)
+	})
+	RegisterBiteDetector(config.BiteDetectorSSIM, func(cfg *config.Config, l *slog.Logger) BiteDetectorContract {
+		return NewSSIMDetector(cfg, l)
+	})
+	RegisterBiteDetector(config.BiteDetectorModel, func(cfg *config.Config, l *slog.Logger) BiteDetectorContract {
+		return NewModelDetector(cfg, l)
+	})
+	registerBiteDetector(config.BiteDetectorEnsemble, func(cfg *config.Config, l *slog.Logger) (BiteDetectorContract, error) {
+		e, err := NewEnsembleDetector(cfg, l)
+		if err != nil {
+			return nil, err
+		}
+		return e, nil
+	})
+}
@@ -0,0 +1,131 @@
+package fishing
+
+import (
+	"image"
+	"log/slog"
+	"math"
+	"time"
+
+	"github.com/soocke/pixel-bot-go/config"
+)
+
+const (
+	// splashSettleFrames seed the baselines after Reset.
+	splashSettleFrames = 5
+	// splashWhiteLuma and splashWhiteSpread define foam: bright pixels whose
+	// RGB channels differ by at most splashWhiteSpread, so bright but
+	// coloured water (glints of blue sky) does not count.
+	splashWhiteLuma   = 190
+	splashWhiteSpread = 60
+	// splashFracJump is the minimum rise of the white pixel share over its
+	// baseline; splashSigmaK scales the baseline's deviation into the rise
+	// required on noisy water.
+	splashFracJump = 0.03
+	splashSigmaK   = 4.0
+	// splashContrastRise is the factor by which local contrast must exceed
+	// its baseline: foam is speckled, a uniform brightening is not.
+	splashContrastRise = 1.2
+	// splashBaseAlpha is the adaptation rate of the rolling baselines.
+	splashBaseAlpha = 0.1
+)
+
+// SplashDetector detects bites from the white splash around the bobber. It
+// models two ROI features against rolling baselines: the share of white
+// (bright, unsaturated) pixels and the local contrast (mean luma gradient).
+// A bite is the onset of a splash: the white share jumps well above its
+// baseline while local contrast rises.
+// Not safe for concurrent use; call FeedFrame from a single goroutine.
+type SplashDetector struct {
+	castTimer
+	logger          *slog.Logger
+	luma            []float64
+	frames          int
+	white, whiteVar float64 // baseline white share and its variance
+	contrast        float64 // baseline mean gradient
+	triggered       bool
+}
+
+// NewSplashDetector returns a SplashDetector. Foam is judged against the
+// ROI's own baselines, so cfg only limits the cast duration.
+func NewSplashDetector(cfg *config.Config, logger *slog.Logger) *SplashDetector {
+	return &SplashDetector{castTimer: newCastTimer(cfg, logger), logger: logger}
+}
+
+// Reset clears the baselines and starts a new cast.
+func (s *SplashDetector) Reset() {
+	s.startCast()
+	s.frames = 0
+	s.white, s.whiteVar, s.contrast = 0, 0, 0
+	s.triggered = false
+}
+
+// FeedFrame processes one ROI frame and returns true on splash onset.
+func (s *SplashDetector) FeedFrame(frame *image.RGBA, t time.Time) bool {
+	if frame == nil || s.triggered {
+		return false
+	}
+	white, contrast, ok := s.measure(frame)
+	if !ok {
+		return false
+	}
+	if s.frames < splashSettleFrames {
+		n := float64(s.frames)
+		mean := (s.white*n + white) / (n + 1)
+		s.whiteVar = (s.whiteVar*n + (white-s.white)*(white-mean)) / (n + 1)
+		s.white = mean
+		s.contrast = (s.contrast*n + contrast) / (n + 1)
+		s.frames++
+		return false
+	}
+	need := math.Max(splashFracJump, splashSigmaK*math.Sqrt(s.whiteVar))
+	if white-s.white > need && contrast > splashContrastRise*s.contrast {
+		s.triggered = true
+		if s.logger != nil {
+			s.logger.Info("bite detected", "detector", config.BiteDetectorSplash, "white", white, "baseWhite", s.white, "contrast", contrast, "baseContrast", s.contrast)
+		}
+		return true
+	}
+	d := white - s.white
+	s.white += splashBaseAlpha * d
+	s.whiteVar = (1 - splashBaseAlpha) * (s.whiteVar + splashBaseAlpha*d*d)
+	s.contrast += splashBaseAlpha * (contrast - s.contrast)
+	s.frames++
+	return false
+}
+
+// measure returns the share of white pixels and the mean absolute luma
+// gradient of frame.
+func (s *SplashDetector) measure(frame *image.RGBA) (white, contrast float64, ok bool) {
+	b := frame.Bounds()
+	w, h := b.Dx(), b.Dy()
+	if w < 2 || h < 2 {
+		return 0, 0, false
+	}
+	if cap(s.luma) < w*h {
+		s.luma = make([]float64, w*h)
+	}
+	l := s.luma[:w*h]
+	whites := 0
+	for y := 0; y < h; y++ {
+		row := frame.Pix[y*frame.Stride : y*frame.Stride+w*4]
+		for x := 0; x < w; x++ {
+			r, g, bb := row[x*4], row[x*4+1], row[x*4+2]
+			v := (77*uint32(r) + 150*uint32(g) + 29*uint32(bb)) >> 8
+			l[y*w+x] = float64(v)
+			if v >= splashWhiteLuma && int(max(r, g, bb))-int(min(r, g, bb)) <= splashWhiteSpread {
+				whites++
+			}
+		}
+	}
+	var grad float64
+	for y := 0; y < h-1; y++ {
+		for x := 0; x < w-1; x++ {
+			v := l[y*w+x]
+			grad += math.Abs(l[y*w+x+1]-v) + math.Abs(l[(y+1)*w+x]-v)
+		}
+	}
+	return float64(whites) / float64(w*h), grad / float64((w-1)*(h-1)), true
+}
+
+// compile-time check that SplashDetector implements BiteDetectorContract.
+var _ BiteDetectorContract = (*SplashDetector)(nil)
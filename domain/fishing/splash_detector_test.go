@@ -0,0 +1,88 @@
+package fishing
+
+import (
+	"image"
+	"math/rand"
+	"testing"
+	"time"
+)
+
+// addSplash sprinkles white foam specks over a disc of the given radius
+// around (40, 40); density is the share of covered pixels.
+func addSplash(img *image.RGBA, rng *rand.Rand, radius, density float64) {
+	for y := 0; y < 80; y++ {
+		for x := 0; x < 80; x++ {
+			dx, dy := float64(x)-40, float64(y)-40
+			if dx*dx+dy*dy > radius*radius || rng.Float64() >= density {
+				continue
+			}
+			i := img.PixOffset(x, y)
+			img.Pix[i], img.Pix[i+1], img.Pix[i+2] = 245, 248, 250
+		}
+	}
+}
+
+func TestSplashDetector_FiresOnSplashOnset(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	d := NewSplashDetector(nil, nil)
+	d.Reset()
+	start := time.Now()
+	for i := 0; i < 40; i++ {
+		if d.FeedFrame(bobberScene(rng, i, bobbing(i), 0), start.Add(time.Duration(i)*50*time.Millisecond)) {
+			t.Fatalf("calm water triggered at frame %d", i)
+		}
+	}
+	// The splash grows over a few frames; onset must be caught early.
+	for k, radius := range []float64{8, 14, 20, 24} {
+		i := 40 + k
+		f := bobberScene(rng, i, bobbing(i), 0)
+		addSplash(f, rng, radius, 0.5)
+		if d.FeedFrame(f, start.Add(time.Duration(i)*50*time.Millisecond)) {
+			if k > 1 {
+				t.Fatalf("splash detected late, at growth step %d", k)
+			}
+			return
+		}
+	}
+	t.Fatalf("splash not detected")
+}
+
+func TestSplashDetector_IgnoresGlintAndBobbing(t *testing.T) {
+	rng := rand.New(rand.NewSource(2))
+	d := NewSplashDetector(nil, nil)
+	d.Reset()
+	start := time.Now()
+	for i := 0; i < 80; i++ {
+		glint := 0.0
+		if i >= 40 && i < 45 {
+			glint = 80 // bright, but still blue water
+		}
+		if d.FeedFrame(bobberScene(rng, i, bobbing(i)+float64(i%7), glint), start.Add(time.Duration(i)*50*time.Millisecond)) {
+			t.Fatalf("triggered at frame %d without a splash", i)
+		}
+	}
+}
+
+func TestSplashDetector_AdaptsToPersistentFoam(t *testing.T) {
+	// Choppy water with steady whitecaps: the baseline absorbs them, and a
+	// real splash on top still stands out.
+	rng := rand.New(rand.NewSource(3))
+	d := NewSplashDetector(nil, nil)
+	d.Reset()
+	start := time.Now()
+	foam := func(i int) *image.RGBA {
+		f := bobberScene(rng, i, bobbing(i), 0)
+		addSplash(f, rng, 60, 0.03)
+		return f
+	}
+	for i := 0; i < 40; i++ {
+		if d.FeedFrame(foam(i), start.Add(time.Duration(i)*50*time.Millisecond)) {
+			t.Fatalf("whitecaps triggered at frame %d", i)
+		}
+	}
+	f := foam(40)
+	addSplash(f, rng, 16, 0.6)
+	if !d.FeedFrame(f, start.Add(40*50*time.Millisecond)) {
+		t.Fatalf("splash over whitecaps not detected")
+	}
+}
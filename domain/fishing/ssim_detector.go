@@ -0,0 +1,197 @@
+package fishing
+
+import (
+	"image"
+	"log/slog"
+	"math"
+	"slices"
+	"time"
+
+	"github.com/soocke/pixel-bot-go/config"
+)
+
+const (
+	// ssimWin and ssimStride are the size and spacing in pixels of the
+	// windows SSIM is computed over.
+	ssimWin    = 8
+	ssimStride = 4
+	// ssimC2 stabilises the contrast-structure term for flat windows
+	// ((0.03*255)^2, as in the SSIM paper).
+	ssimC2 = 58.5225
+	// ssimWorstShare is the share of windows, the least similar ones, whose
+	// mean makes up the frame score, so a change confined to the bobber is
+	// not diluted by the untouched water.
+	ssimWorstShare = 0.1
+	// ssimRefTau is the time constant of the reference ROI and of the score
+	// baseline while settling; ssimBaseTau that of the baseline afterwards.
+	ssimRefTau  = 150 * time.Millisecond
+	ssimBaseTau = 2 * time.Second
+	// ssimSettle seeds the baseline after Reset before bites count.
+	ssimSettle = 500 * time.Millisecond
+	// ssimMinRise is the rise of the structural drop over its baseline a
+	// bite needs at least; ssimSigmaK scales the baseline's deviation into
+	// the rise required on noisy water.
+	ssimMinRise = 0.15
+	ssimSigmaK  = 4.0
+)
+
+// SSIMDetector detects bites from structural change of the ROI. Every frame
+// it computes windowed SSIM against a reference ROI that follows the last
+// ~150ms of frames. Only the contrast-structure term of SSIM is used, so
+// uniform brightness shifts (lighting flicker, day-night fades) leave the
+// score untouched where the absolute differences of BiteDetector jump. A
+// bite is a drop of the least similar windows well below their rolling
+// baseline. Like BiteDetector, all adaptation is by elapsed time.
+// Not safe for concurrent use; call FeedFrame from a single goroutine.
+type SSIMDetector struct {
+	castTimer
+	logger        *slog.Logger
+	w, h          int
+	cur, ref      []float64
+	sums          [5][]float64 // integral images of x, y, x², y² and xy
+	cs            []float64    // per-window scratch
+	first, lastT  time.Time
+	samples       int
+	base, baseVar float64 // score baseline and its variance
+	triggered     bool
+}
+
+// NewSSIMDetector returns an SSIMDetector. The score baseline adapts to each
+// cast's water, so cfg only limits the cast duration.
+func NewSSIMDetector(cfg *config.Config, logger *slog.Logger) *SSIMDetector {
+	return &SSIMDetector{castTimer: newCastTimer(cfg, logger), logger: logger}
+}
+
+// Reset clears the reference and baseline and starts a new cast.
+func (s *SSIMDetector) Reset() {
+	s.startCast()
+	s.ref = nil
+	s.samples = 0
+	s.base, s.baseVar = 0, 0
+	s.triggered = false
+}
+
+// FeedFrame processes one ROI frame sampled at time t and returns true on a
+// structural drop.
+func (s *SSIMDetector) FeedFrame(frame *image.RGBA, t time.Time) bool {
+	if frame == nil || s.triggered {
+		return false
+	}
+	b := frame.Bounds()
+	w, h := b.Dx(), b.Dy()
+	if w < ssimWin || h < ssimWin {
+		return false
+	}
+	if s.ref == nil || w != s.w || h != s.h {
+		s.alloc(w, h)
+		s.luma(frame)
+		copy(s.ref, s.cur)
+		s.first, s.lastT = t, t
+		return false
+	}
+	if !t.After(s.lastT) {
+		return false
+	}
+	s.luma(frame)
+	score := s.score()
+	dt := t.Sub(s.lastT)
+	settling := t.Sub(s.first) < ssimSettle
+	if !settling {
+		need := s.base + math.Max(ssimMinRise, ssimSigmaK*math.Sqrt(s.baseVar))
+		if score > need {
+			s.triggered = true
+			if s.logger != nil {
+				s.logger.Info("bite detected", "detector", config.BiteDetectorSSIM, "drop", score, "need", need, "baseDrop", s.base)
+			}
+			return true
+		}
+	}
+	if s.samples == 0 {
+		s.base = score
+	}
+	tau := ssimBaseTau
+	if settling {
+		tau = ssimRefTau // forget the transient of the fresh reference quickly
+	}
+	a := 1 - math.Exp(-dt.Seconds()/tau.Seconds())
+	d := score - s.base
+	s.base += a * d
+	s.baseVar = (1 - a) * (s.baseVar + a*d*d)
+	s.samples++
+	ra := 1 - math.Exp(-dt.Seconds()/ssimRefTau.Seconds())
+	for i, v := range s.cur {
+		s.ref[i] += (v - s.ref[i]) * ra
+	}
+	s.lastT = t
+	return false
+}
+
+// alloc sizes the buffers for a w x h ROI.
+func (s *SSIMDetector) alloc(w, h int) {
+	s.w, s.h = w, h
+	s.cur = make([]float64, w*h)
+	s.ref = make([]float64, w*h)
+	for i := range s.sums {
+		s.sums[i] = make([]float64, (w+1)*(h+1))
+	}
+	nx, ny := (w-ssimWin)/ssimStride+1, (h-ssimWin)/ssimStride+1
+	s.cs = make([]float64, nx*ny)
+}
+
+// luma converts frame into s.cur.
+func (s *SSIMDetector) luma(frame *image.RGBA) {
+	for y := 0; y < s.h; y++ {
+		row := frame.Pix[y*frame.Stride : y*frame.Stride+s.w*4]
+		for x := 0; x < s.w; x++ {
+			r, g, bb := row[x*4], row[x*4+1], row[x*4+2]
+			s.cur[y*s.w+x] = float64((77*uint32(r) + 150*uint32(g) + 29*uint32(bb)) >> 8)
+		}
+	}
+}
+
+// score returns the structural drop of the current frame against the
+// reference: one minus the mean contrast-structure similarity of the
+// ssimWorstShare least similar windows.
+func (s *SSIMDetector) score() float64 {
+	w1 := s.w + 1
+	sx, sy, sxx, syy, sxy := s.sums[0], s.sums[1], s.sums[2], s.sums[3], s.sums[4]
+	for y := 0; y < s.h; y++ {
+		var rx, ry, rxx, ryy, rxy float64
+		for x := 0; x < s.w; x++ {
+			a, b := s.cur[y*s.w+x], s.ref[y*s.w+x]
+			rx += a
+			ry += b
+			rxx += a * a
+			ryy += b * b
+			rxy += a * b
+			i, up := (y+1)*w1+x+1, y*w1+x+1
+			sx[i], sy[i], sxx[i], syy[i], sxy[i] = sx[up]+rx, sy[up]+ry, sxx[up]+rxx, syy[up]+ryy, sxy[up]+rxy
+		}
+	}
+	box := func(t []float64, x0, y0 int) float64 {
+		x1, y1 := x0+ssimWin, y0+ssimWin
+		return t[y1*w1+x1] - t[y0*w1+x1] - t[y1*w1+x0] + t[y0*w1+x0]
+	}
+	const n = ssimWin * ssimWin
+	k := 0
+	for y := 0; y+ssimWin <= s.h; y += ssimStride {
+		for x := 0; x+ssimWin <= s.w; x += ssimStride {
+			mx, my := box(sx, x, y)/n, box(sy, x, y)/n
+			vx := box(sxx, x, y)/n - mx*mx
+			vy := box(syy, x, y)/n - my*my
+			cxy := box(sxy, x, y)/n - mx*my
+			s.cs[k] = (2*cxy + ssimC2) / (vx + vy + ssimC2)
+			k++
+		}
+	}
+	slices.Sort(s.cs)
+	worst := max(1, int(ssimWorstShare*float64(len(s.cs))))
+	var sum float64
+	for _, v := range s.cs[:worst] {
+		sum += v
+	}
+	return 1 - sum/float64(worst)
+}
+
+// compile-time check that SSIMDetector implements BiteDetectorContract.
+var _ BiteDetectorContract = (*SSIMDetector)(nil)
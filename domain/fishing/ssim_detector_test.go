@@ -0,0 +1,97 @@
+package fishing
+
+import (
+	"fmt"
+	"image"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/soocke/pixel-bot-go/config"
+)
+
+func TestSSIMDetector_IgnoresLightingFlicker(t *testing.T) {
+	motion := NewBiteDetector(nil, nil)
+	ssim := NewSSIMDetector(nil, nil)
+	motion.Reset()
+	ssim.Reset()
+	rng := rand.New(rand.NewSource(1))
+	start := time.Now()
+	motionFired := false
+	for i := 0; i < 100; i++ {
+		glint := 0.0
+		if i >= 40 && i%6 < 2 {
+			glint = 45 // the whole ROI brightens for two frames
+		}
+		frame := bobberScene(rng, i, bobbing(i), glint)
+		at := start.Add(time.Duration(i) * 50 * time.Millisecond)
+		if motion.FeedFrame(frame, at) {
+			motionFired = true
+		}
+		if ssim.FeedFrame(frame, at) {
+			t.Fatalf("ssim detector triggered by flicker at frame %d", i)
+		}
+	}
+	if !motionFired {
+		t.Fatalf("expected the flicker to trigger the motion detector")
+	}
+}
+
+func TestSSIMDetector_TriggersOnPlunge(t *testing.T) {
+	for _, fps := range []int{20, 60} {
+		rng := rand.New(rand.NewSource(2))
+		d := NewSSIMDetector(nil, nil)
+		d.Reset()
+		start := time.Now()
+		interval := time.Second / time.Duration(fps)
+		n := 3 * fps
+		for i := 0; i < n; i++ {
+			if d.FeedFrame(bobberScene(rng, i, bobbing(i), 0), start.Add(time.Duration(i)*interval)) {
+				t.Fatalf("%d fps: bobbing triggered at frame %d", fps, i)
+			}
+		}
+		hit := -1
+		for k := 0; k < fps/5; k++ {
+			i := n + k
+			if d.FeedFrame(bobberScene(rng, i, bobbing(i)+14, 0), start.Add(time.Duration(i)*interval)) {
+				hit = k
+				break
+			}
+		}
+		if hit < 0 {
+			t.Fatalf("%d fps: plunge not detected within 200ms", fps)
+		}
+	}
+	if _, err := NewBiteDetectorByName(config.BiteDetectorSSIM, nil, nil); err != nil {
+		t.Fatalf("ssim detector not registered: %v", err)
+	}
+}
+
+// BenchmarkSSIMDetector_FeedFrame measures one monitored frame. The UI hands
+// the bite detector a frame per 33ms tick, shared with capture display and
+// the FSM, so FeedFrame should stay around a millisecond for usual ROIs.
+func BenchmarkSSIMDetector_FeedFrame(b *testing.B) {
+	for _, size := range []int{80, 160} {
+		b.Run(fmt.Sprintf("%dx%d", size, size), func(b *testing.B) {
+			rng := rand.New(rand.NewSource(1))
+			frames := make([]*image.RGBA, 16)
+			for i := range frames {
+				frames[i] = synthFrame(size, size, 80, func(px []byte, w, h int) {
+					for j := 0; j < w*h; j++ {
+						v := byte(70 + rng.Intn(20))
+						px[j*4], px[j*4+1], px[j*4+2] = v, v, v
+					}
+				})
+			}
+			d := NewSSIMDetector(nil, nil)
+			d.Reset()
+			start := time.Now()
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				d.FeedFrame(frames[i%len(frames)], start.Add(time.Duration(i)*33*time.Millisecond))
+				d.triggered = false
+			}
+		})
+	}
+}
@@ -85,6 +85,11 @@ type FishingCasting interface {
 	Cancel()
 }
 
+type FishingTracing interface {
+	SetTrace(BiteTrace)
+	SetRecorder(ROIRecorder)
+}
+
 // FishingFSMContract aggregates the FSM API.
 type FishingFSMContract interface {
 	FishingStateSource
@@ -93,5 +98,6 @@ type FishingFSMContract interface {
 	FishingFocusControl
 	FishingLifecycle
 	FishingCasting
+	FishingTracing
 	AddListener(FishingStateListener)
 }
@@ -0,0 +1,289 @@
+package preprocess
+
+import (
+	"image"
+	"math"
+)
+
+// gaussianBlur is a separable gaussian blur. Pixels are weighted by alpha, so
+// transparent template pixels do not bleed into the opaque ones.
+type gaussianBlur struct {
+	kernel []float32 // taps from -radius to +radius
+}
+
+func newGaussianBlur(sigma float64) gaussianBlur {
+	radius := int(math.Ceil(3 * sigma))
+	k := make([]float32, 2*radius+1)
+	var sum float64
+	for i := range k {
+		d := float64(i - radius)
+		w := math.Exp(-d * d / (2 * sigma * sigma))
+		k[i] = float32(w)
+		sum += w
+	}
+	for i := range k {
+		k[i] /= float32(sum)
+	}
+	return gaussianBlur{kernel: k}
+}
+
+func (gaussianBlur) global() bool { return false }
+
+func (g gaussianBlur) apply(img *image.RGBA) {
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	if w == 0 || h == 0 {
+		return
+	}
+	radius := len(g.kernel) / 2
+	// Premultiplied RGB and alpha, 4 values per pixel.
+	src := make([]float32, w*h*4)
+	for y := 0; y < h; y++ {
+		row := img.Pix[y*img.Stride : y*img.Stride+w*4]
+		for i, v := range row {
+			src[y*w*4+i] = float32(v)
+		}
+	}
+	tmp := make([]float32, len(src))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			var acc [4]float32
+			for i, kw := range g.kernel {
+				sx := min(max(x+i-radius, 0), w-1)
+				o := (y*w + sx) * 4
+				for c := 0; c < 4; c++ {
+					acc[c] += kw * src[o+c]
+				}
+			}
+			copy(tmp[(y*w+x)*4:], acc[:])
+		}
+	}
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			var acc [4]float32
+			for i, kw := range g.kernel {
+				sy := min(max(y+i-radius, 0), h-1)
+				o := (sy*w + x) * 4
+				for c := 0; c < 4; c++ {
+					acc[c] += kw * tmp[o+c]
+				}
+			}
+			p := y*img.Stride + x*4
+			alpha := float32(img.Pix[p+3])
+			if acc[3] <= 0 || alpha == 0 {
+				continue
+			}
+			// Unpremultiply by the blurred alpha, premultiply by the pixel's own.
+			for c := 0; c < 3; c++ {
+				img.Pix[p+c] = clampByte(acc[c] / acc[3] * alpha)
+			}
+		}
+	}
+}
+
+// gamma applies out = 255 * (in/255)^gamma to every colour channel.
+type gamma struct {
+	lut [256]uint8
+}
+
+func newGamma(g float64) gamma {
+	var f gamma
+	for i := range f.lut {
+		f.lut[i] = uint8(math.Round(255 * math.Pow(float64(i)/255, g)))
+	}
+	return f
+}
+
+func (gamma) global() bool { return false }
+
+func (f gamma) apply(img *image.RGBA) {
+	forEachPixel(img, func(px []uint8) {
+		px[0], px[1], px[2] = f.lut[px[0]], f.lut[px[1]], f.lut[px[2]]
+	})
+}
+
+// channel replaces the colour of every pixel by a gray level taken from one
+// channel ("r", "g", "b") or from luma.
+type channel string
+
+func (channel) global() bool { return false }
+
+func (c channel) apply(img *image.RGBA) {
+	forEachPixel(img, func(px []uint8) {
+		var v uint8
+		switch c {
+		case "r":
+			v = px[0]
+		case "g":
+			v = px[1]
+		case "b":
+			v = px[2]
+		default:
+			v = luma(px[0], px[1], px[2])
+		}
+		px[0], px[1], px[2] = v, v, v
+	})
+}
+
+// equalize spreads the luma histogram over the full range.
+type equalize struct{}
+
+func (equalize) global() bool { return true }
+
+func (equalize) apply(img *image.RGBA) {
+	var hist [256]int
+	forEachPixel(img, func(px []uint8) { hist[luma(px[0], px[1], px[2])]++ })
+	lut := equalizeLUT(hist[:], 0)
+	remapLuma(img, func(_, _ int, y uint8) float64 { return float64(lut[y]) })
+}
+
+// clahe is contrast limited adaptive histogram equalisation of luma: every
+// tile of a tiles x tiles grid gets its own clipped equalisation curve, and
+// pixels interpolate bilinearly between the curves of the nearest tiles.
+type clahe struct {
+	clip  float64 // histogram clip limit as a multiple of the mean bin count
+	tiles int
+}
+
+func (clahe) global() bool { return true }
+
+func (f clahe) apply(img *image.RGBA) {
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	if w == 0 || h == 0 {
+		return
+	}
+	nx, ny := min(f.tiles, w), min(f.tiles, h)
+	tw, th := (w+nx-1)/nx, (h+ny-1)/ny
+	luts := make([][256]uint8, nx*ny)
+	for ty := 0; ty < ny; ty++ {
+		for tx := 0; tx < nx; tx++ {
+			var hist [256]int
+			n := 0
+			for y := ty * th; y < min((ty+1)*th, h); y++ {
+				for x := tx * tw; x < min((tx+1)*tw, w); x++ {
+					p := y*img.Stride + x*4
+					hist[luma(img.Pix[p], img.Pix[p+1], img.Pix[p+2])]++
+					n++
+				}
+			}
+			if n > 0 {
+				luts[ty*nx+tx] = equalizeLUT(hist[:], int(math.Max(1, f.clip*float64(n)/256)))
+			}
+		}
+	}
+	// tile returns the lower tile index and weight of the upper one for a
+	// pixel coordinate, measured between tile centres.
+	tile := func(v, size, count int) (int, int, float64) {
+		fv := (float64(v)+0.5)/float64(size) - 0.5
+		i0 := int(math.Floor(fv))
+		if i0 < 0 {
+			return 0, 0, 0
+		}
+		if i0 >= count-1 {
+			return count - 1, count - 1, 0
+		}
+		return i0, i0 + 1, fv - float64(i0)
+	}
+	remapLuma(img, func(x, y int, v uint8) float64 {
+		x0, x1, wx := tile(x, tw, nx)
+		y0, y1, wy := tile(y, th, ny)
+		top := (1-wx)*float64(luts[y0*nx+x0][v]) + wx*float64(luts[y0*nx+x1][v])
+		bottom := (1-wx)*float64(luts[y1*nx+x0][v]) + wx*float64(luts[y1*nx+x1][v])
+		return (1-wy)*top + wy*bottom
+	})
+}
+
+// equalizeLUT returns the equalisation curve of hist. When clip > 0 bins are
+// first clipped to clip and the excess is spread evenly over all bins.
+func equalizeLUT(hist []int, clip int) [256]uint8 {
+	var lut [256]uint8
+	counts := make([]float64, 256)
+	total := 0.0
+	for i, c := range hist {
+		counts[i] = float64(c)
+		total += float64(c)
+	}
+	if total == 0 {
+		return lut
+	}
+	if clip > 0 {
+		excess := 0.0
+		for i, c := range counts {
+			if c > float64(clip) {
+				excess += c - float64(clip)
+				counts[i] = float64(clip)
+			}
+		}
+		for i := range counts {
+			counts[i] += excess / 256
+		}
+	}
+	cdfMin := 0.0
+	for _, c := range counts {
+		if c > 0 {
+			cdfMin = c
+			break
+		}
+	}
+	cdf := 0.0
+	for i, c := range counts {
+		cdf += c
+		if total > cdfMin {
+			lut[i] = clampByte(float32(math.Round((cdf - cdfMin) / (total - cdfMin) * 255)))
+		} else {
+			lut[i] = uint8(i)
+		}
+	}
+	return lut
+}
+
+// remapLuma replaces each pixel's luma with mapped(x, y, luma), scaling the
+// colour channels by the same factor so hue is kept. x and y are relative to
+// the image bounds.
+func remapLuma(img *image.RGBA, mapped func(x, y int, v uint8) float64) {
+	b := img.Bounds()
+	for y := 0; y < b.Dy(); y++ {
+		row := img.Pix[y*img.Stride : y*img.Stride+b.Dx()*4]
+		for x := 0; x < b.Dx(); x++ {
+			px := row[x*4 : x*4+4]
+			v := luma(px[0], px[1], px[2])
+			nv := mapped(x, y, v)
+			if v == 0 {
+				g := clampByte(float32(nv))
+				px[0], px[1], px[2] = g, g, g
+				continue
+			}
+			k := float32(nv / float64(v))
+			for c := 0; c < 3; c++ {
+				px[c] = clampByte(float32(px[c]) * k)
+			}
+		}
+	}
+}
+
+// forEachPixel calls fn with the 4 bytes of every pixel of img.
+func forEachPixel(img *image.RGBA, fn func(px []uint8)) {
+	b := img.Bounds()
+	for y := 0; y < b.Dy(); y++ {
+		row := img.Pix[y*img.Stride : y*img.Stride+b.Dx()*4]
+		for x := 0; x < len(row); x += 4 {
+			fn(row[x : x+4])
+		}
+	}
+}
+
+// luma returns the integer Rec. 601 luma used by the bite detector.
+func luma(r, g, b uint8) uint8 {
+	return uint8((77*uint32(r) + 150*uint32(g) + 29*uint32(b)) >> 8)
+}
+
+func clampByte(v float32) uint8 {
+	if v <= 0 {
+		return 0
+	}
+	if v >= 255 {
+		return 255
+	}
+	return uint8(v + 0.5)
+}
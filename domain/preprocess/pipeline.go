@@ -0,0 +1,109 @@
+// Package preprocess implements the configurable filter chain applied to
+// frames before target search and to ROIs before bite detection.
+package preprocess
+
+import (
+	"image"
+	"image/draw"
+	"time"
+
+	"github.com/soocke/pixel-bot-go/config"
+)
+
+// Timing is the run time of one stage for one image.
+type Timing struct {
+	Stage    string
+	Duration time.Duration
+}
+
+// filter transforms img in place.
+type filter interface {
+	apply(img *image.RGBA)
+	// global reports a filter whose output depends on statistics of the
+	// whole image, such as histogram equalisation.
+	global() bool
+}
+
+// Pipeline applies a chain of filters. It holds no per-image state and is
+// safe for concurrent use.
+type Pipeline struct {
+	names   []string
+	filters []filter
+}
+
+// New builds a pipeline from stages after normalising them with
+// config.NormalizeStages (unknown filters are skipped).
+func New(stages []config.FilterStage) *Pipeline {
+	p := &Pipeline{}
+	for _, st := range config.NormalizeStages(stages) {
+		var f filter
+		switch st.Filter {
+		case config.FilterBlur:
+			f = newGaussianBlur(st.Sigma)
+		case config.FilterEqualize:
+			f = equalize{}
+		case config.FilterCLAHE:
+			f = clahe{clip: st.ClipLimit, tiles: st.Tiles}
+		case config.FilterGamma:
+			f = newGamma(st.Gamma)
+		case config.FilterChannel:
+			f = channel(st.Channel)
+		}
+		p.names = append(p.names, st.Filter)
+		p.filters = append(p.filters, f)
+	}
+	return p
+}
+
+// Empty reports whether the pipeline has no stages.
+func (p *Pipeline) Empty() bool { return p == nil || len(p.filters) == 0 }
+
+// Apply runs all stages on a copy of img and returns the copy, which keeps
+// img's bounds so coordinates stay valid, together with the run time of
+// every stage. img itself is returned when the pipeline is empty.
+func (p *Pipeline) Apply(img *image.RGBA) (*image.RGBA, []Timing) {
+	if p.Empty() || img == nil {
+		return img, nil
+	}
+	out := clone(img)
+	timings := make([]Timing, len(p.filters))
+	for i, f := range p.filters {
+		start := time.Now()
+		f.apply(out)
+		timings[i] = Timing{Stage: p.names[i], Duration: time.Since(start)}
+	}
+	return out, timings
+}
+
+// ApplyTemplate runs the stages that act on pixels and their neighbourhood
+// (blur, gamma, channel) on a copy of tmpl, so a template keeps looking like
+// its target in processed frames. Equalisation stages are skipped: their
+// mapping comes from the whole frame and does not carry over to a small
+// crop. Transparent pixels stay transparent. tmpl is returned unchanged when
+// no stage applies.
+func (p *Pipeline) ApplyTemplate(tmpl image.Image) image.Image {
+	if p.Empty() || tmpl == nil {
+		return tmpl
+	}
+	var out *image.RGBA
+	for _, f := range p.filters {
+		if f.global() {
+			continue
+		}
+		if out == nil {
+			out = clone(tmpl)
+		}
+		f.apply(out)
+	}
+	if out == nil {
+		return tmpl
+	}
+	return out
+}
+
+// clone copies img into a new RGBA image with the same bounds.
+func clone(img image.Image) *image.RGBA {
+	out := image.NewRGBA(img.Bounds())
+	draw.Draw(out, out.Bounds(), img, img.Bounds().Min, draw.Src)
+	return out
+}
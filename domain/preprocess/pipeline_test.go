@@ -0,0 +1,137 @@
+package preprocess
+
+import (
+	"image"
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/soocke/pixel-bot-go/config"
+)
+
+func grayFrame(w, h int, level func(x, y int) uint8) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			v := level(x, y)
+			i := img.PixOffset(x, y)
+			img.Pix[i], img.Pix[i+1], img.Pix[i+2], img.Pix[i+3] = v, v, v, 255
+		}
+	}
+	return img
+}
+
+// lumaStats returns mean and standard deviation of luma over r.
+func lumaStats(img *image.RGBA, r image.Rectangle) (mean, std float64) {
+	var sum, sq, n float64
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			i := img.PixOffset(x, y)
+			v := float64(luma(img.Pix[i], img.Pix[i+1], img.Pix[i+2]))
+			sum += v
+			sq += v * v
+			n++
+		}
+	}
+	mean = sum / n
+	return mean, math.Sqrt(sq/n - mean*mean)
+}
+
+func TestPipeline_ApplyKeepsInputAndBounds(t *testing.T) {
+	full := grayFrame(64, 48, func(x, y int) uint8 { return uint8(100 + x%16) })
+	sub := full.SubImage(image.Rect(8, 8, 40, 40)).(*image.RGBA)
+	before := append([]uint8(nil), full.Pix...)
+	p := New([]config.FilterStage{{Filter: "blur"}, {Filter: "EQUALIZE"}, {Filter: "bogus"}, {Filter: "gamma", Gamma: 0.5}})
+	out, timings := p.Apply(sub)
+	if out == sub || out.Bounds() != sub.Bounds() {
+		t.Fatalf("expected a copy with bounds %v, got %v", sub.Bounds(), out.Bounds())
+	}
+	if string(before) != string(full.Pix) {
+		t.Fatalf("input was modified")
+	}
+	want := []string{config.FilterBlur, config.FilterEqualize, config.FilterGamma}
+	if len(timings) != len(want) {
+		t.Fatalf("timings %+v, want stages %v", timings, want)
+	}
+	for i, tm := range timings {
+		if tm.Stage != want[i] {
+			t.Fatalf("stage %d = %q, want %q", i, tm.Stage, want[i])
+		}
+	}
+	if img, tm := New(nil).Apply(sub); img != sub || tm != nil {
+		t.Fatalf("empty pipeline must return the input")
+	}
+}
+
+func TestEqualize_StretchesLowContrast(t *testing.T) {
+	img := grayFrame(64, 64, func(x, y int) uint8 { return uint8(100 + (x+y)/4) })
+	_, before := lumaStats(img, img.Bounds())
+	out, _ := New([]config.FilterStage{{Filter: config.FilterEqualize}}).Apply(img)
+	_, after := lumaStats(out, out.Bounds())
+	if after < 4*before {
+		t.Fatalf("contrast %.1f -> %.1f, want a strong stretch", before, after)
+	}
+}
+
+func TestCLAHE_RaisesLocalContrastInDarkRegion(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	// Dim, low-contrast texture on the left; bright, contrasty texture on the right.
+	img := grayFrame(128, 64, func(x, y int) uint8 {
+		if x < 64 {
+			return uint8(20 + rng.Intn(8))
+		}
+		return uint8(120 + rng.Intn(100))
+	})
+	dark := image.Rect(8, 8, 56, 56)
+	_, before := lumaStats(img, dark)
+	out, _ := New([]config.FilterStage{{Filter: config.FilterCLAHE, ClipLimit: 3, Tiles: 4}}).Apply(img)
+	_, after := lumaStats(out, dark)
+	if after < 3*before {
+		t.Fatalf("dark region contrast %.1f -> %.1f, want at least 3x", before, after)
+	}
+}
+
+func TestBlur_SmoothsNoiseAndKeepsTemplateTransparency(t *testing.T) {
+	rng := rand.New(rand.NewSource(2))
+	img := grayFrame(64, 64, func(x, y int) uint8 { return uint8(96 + rng.Intn(64)) })
+	mean, before := lumaStats(img, img.Bounds())
+	out, _ := New([]config.FilterStage{{Filter: config.FilterBlur, Sigma: 1.5}}).Apply(img)
+	blurMean, after := lumaStats(out, out.Bounds())
+	if after > before/2 || math.Abs(blurMean-mean) > 1 {
+		t.Fatalf("blur: std %.1f -> %.1f, mean %.1f -> %.1f", before, after, mean, blurMean)
+	}
+
+	// A bright disc on a transparent background must not darken at its rim.
+	tmpl := image.NewRGBA(image.Rect(0, 0, 21, 21))
+	for y := 0; y < 21; y++ {
+		for x := 0; x < 21; x++ {
+			if (x-10)*(x-10)+(y-10)*(y-10) <= 64 {
+				i := tmpl.PixOffset(x, y)
+				tmpl.Pix[i], tmpl.Pix[i+1], tmpl.Pix[i+2], tmpl.Pix[i+3] = 200, 200, 200, 255
+			}
+		}
+	}
+	p := New([]config.FilterStage{{Filter: config.FilterBlur, Sigma: 2}, {Filter: config.FilterEqualize}})
+	got := p.ApplyTemplate(tmpl).(*image.RGBA)
+	for i := 0; i < len(got.Pix); i += 4 {
+		if got.Pix[i+3] != tmpl.Pix[i+3] {
+			t.Fatalf("alpha changed at byte %d", i)
+		}
+		if got.Pix[i+3] == 255 && got.Pix[i] != 200 {
+			t.Fatalf("opaque pixel changed to %d; transparent pixels bled in or equalisation ran", got.Pix[i])
+		}
+	}
+}
+
+func TestGammaAndChannel(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 1, 1))
+	copy(img.Pix, []uint8{64, 128, 255, 255})
+	out, _ := New([]config.FilterStage{{Filter: config.FilterGamma, Gamma: 0.5}}).Apply(img)
+	if out.Pix[0] != 128 || out.Pix[2] != 255 {
+		t.Fatalf("gamma 0.5: got %v", out.Pix[:3])
+	}
+	out, _ = New([]config.FilterStage{{Filter: config.FilterChannel, Channel: "b"}}).Apply(img)
+	if out.Pix[0] != 255 || out.Pix[1] != 255 || out.Pix[2] != 255 {
+		t.Fatalf("channel b: got %v", out.Pix[:3])
+	}
+}
@@ -0,0 +1,214 @@
+package tuning
+
+import (
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/png"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/soocke/pixel-bot-go/domain/fishing"
+)
+
+// recordQueue is the number of frames a SequenceRecorder buffers while its
+// writer is busy; frames beyond it are dropped and left out of frame_ms.
+const recordQueue = 64
+
+// SequenceRecorder writes the ROI frames of every monitored cast as a
+// sequence directory below its root: cast-0001, cast-0002, ... numbered
+// after those already there. Each directory gets the PNG frames and a
+// SequenceFile with the frame times, the cast outcome and, for reeled casts,
+// the reel time as bite, to be checked before tuning. It implements
+// fishing.ROIRecorder.
+type SequenceRecorder struct {
+	root   string
+	logger *slog.Logger
+	jobs   chan recordJob
+	done   chan struct{}
+
+	mu      sync.Mutex // guards closed through dropped
+	closed  bool
+	next    int    // number of the next cast directory
+	cast    int    // FSM cast number being recorded, 0 if none
+	dir     string // directory of the cast being recorded
+	start   time.Time
+	times   []int // ms after start of every queued frame
+	dropped int
+
+	errMu sync.Mutex
+	err   error
+}
+
+type recordJob struct {
+	path   string
+	img    *image.RGBA
+	labels *sequenceLabels
+}
+
+// NewSequenceRecorder creates root if needed and starts the writer.
+func NewSequenceRecorder(root string, logger *slog.Logger) (*SequenceRecorder, error) {
+	if err := os.MkdirAll(root, 0o755); err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		return nil, err
+	}
+	last := 0
+	for _, e := range entries {
+		var n int
+		if e.IsDir() && strings.HasPrefix(e.Name(), "cast-") {
+			if _, err := fmt.Sscanf(e.Name(), "cast-%d", &n); err == nil && n > last {
+				last = n
+			}
+		}
+	}
+	r := &SequenceRecorder{
+		root:   root,
+		logger: logger,
+		jobs:   make(chan recordJob, recordQueue),
+		done:   make(chan struct{}),
+		next:   last + 1,
+	}
+	go r.write()
+	return r, nil
+}
+
+var _ fishing.ROIRecorder = (*SequenceRecorder)(nil)
+
+// ROIFrame queues a copy of roi as the next frame of cast, starting a new
+// sequence directory when the cast changes.
+func (r *SequenceRecorder) ROIFrame(cast int, roi *image.RGBA, t time.Time) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.closed {
+		return
+	}
+	if cast != r.cast {
+		r.finish(fishing.OutcomeHalt)
+		r.cast = cast
+		r.dir = filepath.Join(r.root, fmt.Sprintf("cast-%04d", r.next))
+		r.next++
+		r.start = t
+	}
+	img := &image.RGBA{
+		Pix:    append([]uint8(nil), roi.Pix...),
+		Stride: roi.Stride,
+		Rect:   roi.Rect,
+	}
+	job := recordJob{path: filepath.Join(r.dir, fmt.Sprintf("frame-%05d.png", len(r.times))), img: img}
+	select {
+	case r.jobs <- job:
+		r.times = append(r.times, int(t.Sub(r.start)/time.Millisecond))
+	default:
+		r.dropped++
+	}
+}
+
+// Outcome writes the labels of cast once its monitoring ends.
+func (r *SequenceRecorder) Outcome(cast int, outcome string, t time.Time) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.closed || cast != r.cast {
+		return
+	}
+	if outcome == fishing.OutcomeReel {
+		r.finishAt(outcome, []int{int(t.Sub(r.start) / time.Millisecond)})
+		return
+	}
+	r.finish(outcome)
+}
+
+// Close writes the labels of an unfinished cast, waits for the queued frames
+// and returns the first write error. Later frames and outcomes are ignored.
+func (r *SequenceRecorder) Close() error {
+	r.mu.Lock()
+	if r.closed {
+		r.mu.Unlock()
+		return nil
+	}
+	r.finish(fishing.OutcomeHalt)
+	r.closed = true
+	close(r.jobs)
+	dropped := r.dropped
+	r.mu.Unlock()
+	<-r.done
+	if dropped > 0 && r.logger != nil {
+		r.logger.Warn("bite recording dropped frames", "frames", dropped)
+	}
+	r.errMu.Lock()
+	defer r.errMu.Unlock()
+	return r.err
+}
+
+func (r *SequenceRecorder) finish(outcome string) { r.finishAt(outcome, []int{}) }
+
+// finishAt queues the labels of the current cast, if it has frames. r.mu
+// must be held.
+func (r *SequenceRecorder) finishAt(outcome string, bites []int) {
+	if r.cast != 0 && len(r.times) > 0 {
+		labels := &sequenceLabels{
+			IntervalMs: medianInterval(r.times),
+			FrameMs:    r.times,
+			BiteMs:     bites,
+			Outcome:    outcome,
+		}
+		r.jobs <- recordJob{path: filepath.Join(r.dir, SequenceFile), labels: labels}
+	}
+	r.cast, r.dir, r.times = 0, "", nil
+}
+
+// write runs the queued jobs until the queue is closed.
+func (r *SequenceRecorder) write() {
+	defer close(r.done)
+	for job := range r.jobs {
+		if err := job.run(); err != nil {
+			r.errMu.Lock()
+			if r.err == nil {
+				r.err = err
+			}
+			r.errMu.Unlock()
+		}
+	}
+}
+
+func (j recordJob) run() error {
+	if err := os.MkdirAll(filepath.Dir(j.path), 0o755); err != nil {
+		return err
+	}
+	if j.labels != nil {
+		raw, err := json.MarshalIndent(j.labels, "", "  ")
+		if err != nil {
+			return err
+		}
+		return os.WriteFile(j.path, raw, 0o644)
+	}
+	f, err := os.Create(j.path)
+	if err != nil {
+		return err
+	}
+	if err := png.Encode(f, j.img); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// medianInterval returns the median gap between the frame times, at least 1.
+func medianInterval(times []int) int {
+	if len(times) < 2 {
+		return 1
+	}
+	gaps := make([]int, 0, len(times)-1)
+	for i := 1; i < len(times); i++ {
+		gaps = append(gaps, times[i]-times[i-1])
+	}
+	sort.Ints(gaps)
+	return max(gaps[len(gaps)/2], 1)
+}
@@ -0,0 +1,64 @@
+package tuning
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/soocke/pixel-bot-go/domain/fishing"
+)
+
+func TestSequenceRecorder_WritesLoadableSequences(t *testing.T) {
+	root := t.TempDir()
+	if err := os.Mkdir(filepath.Join(root, "cast-0007"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	rec, err := NewSequenceRecorder(root, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Now()
+	at := func(ms int) time.Time { return start.Add(time.Duration(ms) * time.Millisecond) }
+	// cast 1 reels, cast 2 times out, cast 3 is cut off by Close
+	for i, ms := range []int{0, 50, 110, 160} {
+		rec.ROIFrame(1, roi(nil, i == 3), at(ms))
+	}
+	rec.Outcome(1, fishing.OutcomeReel, at(170))
+	rec.ROIFrame(2, roi(nil, false), at(1000))
+	rec.ROIFrame(2, roi(nil, false), at(1050))
+	rec.Outcome(2, fishing.OutcomeLost, at(1100))
+	rec.ROIFrame(3, roi(nil, false), at(2000))
+	if err := rec.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	reeled, err := LoadSequence(filepath.Join(root, "cast-0008"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []time.Duration
+	for _, f := range reeled.Frames {
+		got = append(got, f.At)
+	}
+	want := []time.Duration{0, 50 * time.Millisecond, 110 * time.Millisecond, 160 * time.Millisecond}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("frame times %v, want %v", got, want)
+	}
+	if !reflect.DeepEqual(reeled.Bites, []time.Duration{170 * time.Millisecond}) {
+		t.Fatalf("reeled cast bites %v, want the reel time", reeled.Bites)
+	}
+	if reeled.Frames[3].Img.Pix[reeled.Frames[3].Img.PixOffset(20, 20)] != 160 {
+		t.Fatal("frame pixels not preserved")
+	}
+	for _, name := range []string{"cast-0009", "cast-0010"} {
+		seq, err := LoadSequence(filepath.Join(root, name))
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if len(seq.Bites) != 0 {
+			t.Fatalf("%s: unreeled cast labelled with bites %v", name, seq.Bites)
+		}
+	}
+}
@@ -0,0 +1,204 @@
+package tuning
+
+import (
+	"math"
+	"math/rand"
+	"runtime"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/soocke/pixel-bot-go/config"
+	"github.com/soocke/pixel-bot-go/domain/fishing"
+)
+
+// Window bounds how far a detection may be from a true bite to count as a
+// hit: up to Early before it (labels are placed by hand) and up to Late
+// after it.
+type Window struct {
+	Early, Late time.Duration
+}
+
+// DefaultWindow accepts detections from 100ms before to 1s after a bite.
+var DefaultWindow = Window{Early: 100 * time.Millisecond, Late: time.Second}
+
+// Metrics summarise a detector over a set of sequences.
+type Metrics struct {
+	Bites          int // labelled bites
+	Detections     int // detector triggers
+	TruePositives  int // triggers matched to a bite
+	FalsePositives int // triggers outside every bite window
+	Precision      float64
+	Recall         float64
+	F1             float64
+	MeanLatency    time.Duration // mean delay of matched triggers after their bite
+	MaxLatency     time.Duration
+}
+
+// Better reports whether m ranks above o: higher F1, then lower mean latency.
+func (m Metrics) Better(o Metrics) bool {
+	if m.F1 != o.F1 {
+		return m.F1 > o.F1
+	}
+	return m.MeanLatency < o.MeanLatency
+}
+
+// Evaluate replays every sequence through a fresh detector from newDetector.
+// After a trigger the detector is reset, as for a new cast, and replay
+// continues. Each bite matches at most one trigger inside win.
+func Evaluate(seqs []*Sequence, newDetector func() fishing.BiteDetectorContract, win Window) Metrics {
+	var m Metrics
+	var latency time.Duration
+	start := time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+	for _, seq := range seqs {
+		det := newDetector()
+		det.Reset()
+		matched := make([]bool, len(seq.Bites))
+		m.Bites += len(seq.Bites)
+		for _, fr := range seq.Frames {
+			if !det.FeedFrame(fr.Img, start.Add(fr.At)) {
+				continue
+			}
+			m.Detections++
+			det.Reset()
+			for i, bite := range seq.Bites {
+				if matched[i] || fr.At < bite-win.Early || fr.At > bite+win.Late {
+					continue
+				}
+				matched[i] = true
+				m.TruePositives++
+				d := max(fr.At-bite, 0)
+				latency += d
+				m.MaxLatency = max(m.MaxLatency, d)
+				break
+			}
+		}
+	}
+	m.FalsePositives = m.Detections - m.TruePositives
+	if m.Detections > 0 {
+		m.Precision = float64(m.TruePositives) / float64(m.Detections)
+	}
+	if m.Bites > 0 {
+		m.Recall = float64(m.TruePositives) / float64(m.Bites)
+	} else if m.Detections == 0 {
+		m.Precision, m.Recall = 1, 1 // nothing to find and nothing found
+	}
+	if m.Precision+m.Recall > 0 {
+		m.F1 = 2 * m.Precision * m.Recall / (m.Precision + m.Recall)
+	}
+	if m.TruePositives > 0 {
+		m.MeanLatency = latency / time.Duration(m.TruePositives)
+	}
+	return m
+}
+
+// Dim is one searched bite parameter. Grid search tries Steps evenly spaced
+// values from Min to Max; random search samples uniformly between them.
+type Dim struct {
+	Name     string
+	Min, Max float64
+	Steps    int
+	Int      bool
+	set      func(p *config.BiteParams, v float64)
+}
+
+// DefaultSpace is the search space of the tuning command. Parameters not
+// listed keep their base value.
+func DefaultSpace() []Dim {
+	return []Dim{
+		{Name: "pixel_rate_threshold", Min: 120, Max: 360, Steps: 4, Int: true, set: func(p *config.BiteParams, v float64) { p.PixelRateThreshold = v }},
+		{Name: "ratio_threshold_spike", Min: 0.10, Max: 0.30, Steps: 5, set: func(p *config.BiteParams, v float64) { p.RatioThresholdSpike = v }},
+		{Name: "ratio_threshold_base", Min: 0.08, Max: 0.24, Steps: 3, set: func(p *config.BiteParams, v float64) { p.RatioThresholdBase = v }},
+		{Name: "baseline_diff_thresh", Min: 8, Max: 24, Steps: 3, set: func(p *config.BiteParams, v float64) { p.BaselineDiffThresh = v }},
+		{Name: "std_dev_multiplier", Min: 1.5, Max: 3.5, Steps: 3, set: func(p *config.BiteParams, v float64) { p.StdDevMultiplier = v }},
+		{Name: "debounce_ms", Min: 0, Max: 50, Steps: 2, Int: true, set: func(p *config.BiteParams, v float64) { p.DebounceMs = int(v) }},
+	}
+}
+
+// value returns step i of Steps values from Min to Max.
+func (d Dim) value(i int) float64 {
+	if d.Steps <= 1 {
+		return d.Min
+	}
+	v := d.Min + (d.Max-d.Min)*float64(i)/float64(d.Steps-1)
+	if d.Int {
+		v = math.Round(v)
+	}
+	return v
+}
+
+// Grid returns every combination of the dimensions' step values applied to
+// base.
+func Grid(base config.BiteParams, space []Dim) []config.BiteParams {
+	sets := []config.BiteParams{base}
+	for _, d := range space {
+		next := make([]config.BiteParams, 0, len(sets)*max(d.Steps, 1))
+		for _, p := range sets {
+			for i := 0; i < max(d.Steps, 1); i++ {
+				q := p
+				d.set(&q, d.value(i))
+				next = append(next, q)
+			}
+		}
+		sets = next
+	}
+	return sets
+}
+
+// Random returns n parameter sets sampled uniformly from space around base.
+func Random(base config.BiteParams, space []Dim, n int, rng *rand.Rand) []config.BiteParams {
+	sets := make([]config.BiteParams, n)
+	for i := range sets {
+		p := base
+		for _, d := range space {
+			v := d.Min + rng.Float64()*(d.Max-d.Min)
+			if d.Int {
+				v = math.Round(v)
+			}
+			d.set(&p, v)
+		}
+		sets[i] = p
+	}
+	return sets
+}
+
+// Result is the evaluation of one parameter set.
+type Result struct {
+	Params  config.BiteParams
+	Metrics Metrics
+}
+
+// Search evaluates every candidate with the default BiteDetector on all CPUs
+// and returns the results best first. Candidates are normalised as
+// Config.Validate would; duplicates after normalisation are evaluated once.
+func Search(seqs []*Sequence, candidates []config.BiteParams, win Window) []Result {
+	seen := make(map[config.BiteParams]bool, len(candidates))
+	var results []Result
+	for _, p := range candidates {
+		p = p.Normalize()
+		if !seen[p] {
+			seen[p] = true
+			results = append(results, Result{Params: p})
+		}
+	}
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < runtime.NumCPU(); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				cfg := config.DefaultConfig()
+				cfg.BitePreset, cfg.Bite = config.BitePresetCustom, results[i].Params
+				results[i].Metrics = Evaluate(seqs, func() fishing.BiteDetectorContract { return fishing.NewBiteDetector(cfg, nil) }, win)
+			}
+		}()
+	}
+	for i := range results {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+	sort.SliceStable(results, func(i, j int) bool { return results[i].Metrics.Better(results[j].Metrics) })
+	return results
+}
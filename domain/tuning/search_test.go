@@ -0,0 +1,136 @@
+package tuning
+
+import (
+	"image"
+	"image/png"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/soocke/pixel-bot-go/config"
+	"github.com/soocke/pixel-bot-go/domain/fishing"
+)
+
+const frameInterval = 50 * time.Millisecond
+
+// roi is a 40x40 gray frame; when rng is set ~22% of the pixels ripple.
+func roi(rng *rand.Rand, bite bool) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, 40, 40))
+	for y := 0; y < 40; y++ {
+		for x := 0; x < 40; x++ {
+			v := uint8(80)
+			if rng != nil && rng.Float64() < 0.22 {
+				v += 14
+			}
+			if bite && x >= 8 && x < 32 && y >= 8 && y < 32 {
+				v = 160
+			}
+			i := img.PixOffset(x, y)
+			img.Pix[i], img.Pix[i+1], img.Pix[i+2], img.Pix[i+3] = v, v, v, 255
+		}
+	}
+	return img
+}
+
+// sequence builds n frames with the bobber plunging at frame biteAt (<0 for
+// none); waves adds rippling water.
+func sequence(n, biteAt int, waves bool, seed int64) *Sequence {
+	var rng *rand.Rand
+	if waves {
+		rng = rand.New(rand.NewSource(seed))
+	}
+	seq := &Sequence{Name: "synthetic"}
+	for i := 0; i < n; i++ {
+		bite := biteAt >= 0 && (i == biteAt || i == biteAt+1)
+		if bite && i == biteAt+1 {
+			// keep splashing so debounced detectors confirm the bite
+			seq.Frames = append(seq.Frames, Frame{Img: roi(nil, false), At: time.Duration(i) * frameInterval})
+			continue
+		}
+		seq.Frames = append(seq.Frames, Frame{Img: roi(rng, bite), At: time.Duration(i) * frameInterval})
+	}
+	if biteAt >= 0 {
+		seq.Bites = []time.Duration{time.Duration(biteAt) * frameInterval}
+	}
+	return seq
+}
+
+func calmDetector() fishing.BiteDetectorContract { return fishing.NewBiteDetector(nil, nil) }
+
+func TestEvaluate_CountsHitsFalsePositivesAndLatency(t *testing.T) {
+	seqs := []*Sequence{sequence(40, 20, false, 0), sequence(40, -1, false, 0)}
+	// A plunge in the unlabelled sequence is a false positive.
+	seqs[1].Frames[25].Img = roi(nil, true)
+	m := Evaluate(seqs, calmDetector, DefaultWindow)
+	if m.Bites != 1 || m.TruePositives != 1 || m.FalsePositives != 1 {
+		t.Fatalf("unexpected counts %+v", m)
+	}
+	if m.Precision != 0.5 || m.Recall != 1 || m.MeanLatency != 0 {
+		t.Fatalf("unexpected rates %+v", m)
+	}
+
+	// A trigger long after the bite does not count.
+	late := sequence(60, 30, false, 0)
+	late.Bites = []time.Duration{0}
+	if m := Evaluate([]*Sequence{late}, calmDetector, DefaultWindow); m.TruePositives != 0 || m.Recall != 0 {
+		t.Fatalf("late trigger counted as hit: %+v", m)
+	}
+}
+
+func TestSearch_PrefersParametersThatIgnoreWaves(t *testing.T) {
+	var seqs []*Sequence
+	for i := int64(0); i < 3; i++ {
+		seqs = append(seqs, sequence(60, 45, true, i), sequence(60, -1, true, 10+i))
+	}
+	calm, _ := config.BitePresetParams(config.BitePresetCalm)
+	choppy, _ := config.BitePresetParams(config.BitePresetChoppy)
+	results := Search(seqs, []config.BiteParams{calm, choppy, calm}, DefaultWindow)
+	if len(results) != 2 {
+		t.Fatalf("expected duplicates removed, got %d results", len(results))
+	}
+	best := results[0]
+	if best.Params != choppy {
+		t.Fatalf("expected choppy parameters to win, got %+v (%+v)", best.Params, best.Metrics)
+	}
+	if best.Metrics.F1 != 1 || results[1].Metrics.FalsePositives == 0 {
+		t.Fatalf("unexpected metrics best %+v, other %+v", best.Metrics, results[1].Metrics)
+	}
+	if n := len(Grid(calm, DefaultSpace())); n != 4*5*3*3*3*2 {
+		t.Fatalf("grid has %d sets", n)
+	}
+}
+
+func TestLoadSequences_ReadsFramesAndLabels(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "cast1")
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for i, name := range []string{"002.png", "000.png", "001.png"} {
+		f, err := os.Create(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		img := image.NewGray(image.Rect(0, 0, 8, 8))
+		img.Pix[0] = uint8(100 + i)
+		if err := png.Encode(f, img); err != nil {
+			t.Fatal(err)
+		}
+		f.Close()
+	}
+	if err := os.WriteFile(filepath.Join(dir, SequenceFile), []byte(`{"interval_ms": 40, "bite_ms": [80]}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	seqs, err := LoadSequences(filepath.Dir(dir))
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	s := seqs[0]
+	if s.Name != "cast1" || len(s.Frames) != 3 || len(s.Bites) != 1 || s.Bites[0] != 80*time.Millisecond {
+		t.Fatalf("unexpected sequence %+v", s)
+	}
+	if s.Frames[2].At != 80*time.Millisecond || s.Frames[0].Img.Pix[0] != 101 {
+		t.Fatalf("frames not ordered by name: at %v, first pixel %d", s.Frames[2].At, s.Frames[0].Img.Pix[0])
+	}
+}
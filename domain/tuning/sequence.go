@@ -0,0 +1,148 @@
+// Package tuning replays recorded bite ROI sequences through bite detectors
+// and searches detector parameters offline.
+package tuning
+
+import (
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/png"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// SequenceFile is the label file of a sequence directory.
+const SequenceFile = "sequence.json"
+
+// Frame is one ROI frame at offset At from the start of its sequence.
+type Frame struct {
+	Img *image.RGBA
+	At  time.Duration
+}
+
+// Sequence is a recorded cast: ROI frames in time order and the true bite
+// times (offsets from the first frame). A sequence without bites is a cast
+// that must not trigger.
+type Sequence struct {
+	Name   string
+	Frames []Frame
+	Bites  []time.Duration
+}
+
+// sequenceLabels is the content of SequenceFile.
+type sequenceLabels struct {
+	// IntervalMs is the time between consecutive frames.
+	IntervalMs int `json:"interval_ms"`
+	// FrameMs, if set, gives each frame's time in ms after the first frame
+	// and overrides IntervalMs. SequenceRecorder writes it.
+	FrameMs []int `json:"frame_ms,omitempty"`
+	// BiteMs lists the true bite times in ms after the first frame.
+	BiteMs []int `json:"bite_ms"`
+	// Outcome is how the recorded cast ended (fishing.OutcomeReel, ...);
+	// informational only.
+	Outcome string `json:"outcome,omitempty"`
+}
+
+// LoadSequence reads a sequence directory: PNG frames, ordered by file name,
+// and SequenceFile with the frame interval (or the time of every frame in
+// "frame_ms") and bite times, e.g.
+//
+//	{"interval_ms": 50, "bite_ms": [4200]}
+func LoadSequence(dir string) (*Sequence, error) {
+	raw, err := os.ReadFile(filepath.Join(dir, SequenceFile))
+	if err != nil {
+		return nil, err
+	}
+	var labels sequenceLabels
+	if err := json.Unmarshal(raw, &labels); err != nil {
+		return nil, fmt.Errorf("%s: %w", SequenceFile, err)
+	}
+	if labels.IntervalMs <= 0 && labels.FrameMs == nil {
+		return nil, fmt.Errorf("%s: interval_ms must be positive", SequenceFile)
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, e := range entries {
+		if !e.IsDir() && strings.EqualFold(filepath.Ext(e.Name()), ".png") {
+			names = append(names, e.Name())
+		}
+	}
+	sort.Strings(names)
+	if len(names) == 0 {
+		return nil, fmt.Errorf("%s: no PNG frames", dir)
+	}
+	if labels.FrameMs != nil && len(labels.FrameMs) != len(names) {
+		return nil, fmt.Errorf("%s: frame_ms has %d times for %d frames", SequenceFile, len(labels.FrameMs), len(names))
+	}
+	seq := &Sequence{Name: filepath.Base(dir)}
+	interval := time.Duration(labels.IntervalMs) * time.Millisecond
+	for i, name := range names {
+		img, err := loadRGBA(filepath.Join(dir, name))
+		if err != nil {
+			return nil, err
+		}
+		at := time.Duration(i) * interval
+		if labels.FrameMs != nil {
+			at = time.Duration(labels.FrameMs[i]) * time.Millisecond
+		}
+		seq.Frames = append(seq.Frames, Frame{Img: img, At: at})
+	}
+	for _, ms := range labels.BiteMs {
+		seq.Bites = append(seq.Bites, time.Duration(ms)*time.Millisecond)
+	}
+	sort.Slice(seq.Bites, func(i, j int) bool { return seq.Bites[i] < seq.Bites[j] })
+	return seq, nil
+}
+
+// LoadSequences loads every sequence directory below root, i.e. every
+// directory containing SequenceFile.
+func LoadSequences(root string) ([]*Sequence, error) {
+	var seqs []*Sequence
+	err := filepath.WalkDir(root, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || d.Name() != SequenceFile {
+			return nil
+		}
+		seq, err := LoadSequence(filepath.Dir(path))
+		if err != nil {
+			return err
+		}
+		seqs = append(seqs, seq)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(seqs) == 0 {
+		return nil, fmt.Errorf("no %s found below %s", SequenceFile, root)
+	}
+	return seqs, nil
+}
+
+func loadRGBA(path string) (*image.RGBA, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	img, err := png.Decode(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if rgba, ok := img.(*image.RGBA); ok {
+		return rgba, nil
+	}
+	b := img.Bounds()
+	rgba := image.NewRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	draw.Draw(rgba, rgba.Bounds(), img, b.Min, draw.Src)
+	return rgba, nil
+}
@@ -0,0 +1,250 @@
+package tuning
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/soocke/pixel-bot-go/domain/fishing"
+)
+
+// LabelsSuffix is appended to a trace's name without its extension to find
+// the trace's labels, e.g. bites.jsonl -> bites.labels.json.
+const LabelsSuffix = ".labels.json"
+
+// DefaultLabelWindow marks frames from 100ms before to 300ms after a
+// labelled bite as bite frames for training.
+var DefaultLabelWindow = Window{Early: 100 * time.Millisecond, Late: 300 * time.Millisecond}
+
+// Sample is one labelled model input.
+type Sample struct {
+	X    []float64
+	Bite bool
+}
+
+// LabelsPath returns the labels file belonging to the trace at path.
+func LabelsPath(path string) string {
+	return strings.TrimSuffix(path, filepath.Ext(path)) + LabelsSuffix
+}
+
+// LoadLabels reads a labels file: a JSON object mapping cast numbers to the
+// bite times in ms after the cast's first traced frame, e.g.
+// {"1": [4200], "2": []}. Casts without an entry are unlabelled.
+func LoadLabels(path string) (map[int][]time.Duration, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var in map[string][]int
+	if err := json.Unmarshal(raw, &in); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	out := make(map[int][]time.Duration, len(in))
+	for k, ms := range in {
+		cast, err := strconv.Atoi(k)
+		if err != nil {
+			return nil, fmt.Errorf("%s: cast %q is not a number", path, k)
+		}
+		bites := make([]time.Duration, len(ms))
+		for i, v := range ms {
+			bites[i] = time.Duration(v) * time.Millisecond
+		}
+		out[cast] = bites
+	}
+	return out, nil
+}
+
+// LabelCasts turns the frames of labelled casts into samples. A frame is a
+// bite frame when it lies within win of one of its cast's bites; frames of
+// casts missing from labels are skipped.
+func LabelCasts(casts []fishing.TracedCast, labels map[int][]time.Duration, win Window) []Sample {
+	var out []Sample
+	for _, c := range casts {
+		bites, ok := labels[c.Cast]
+		if !ok || len(c.Frames) == 0 {
+			continue
+		}
+		first := c.Frames[0].Time
+		for _, f := range c.Frames {
+			at := f.Time.Sub(first)
+			bite := false
+			for _, b := range bites {
+				if at >= b-win.Early && at <= b+win.Late {
+					bite = true
+					break
+				}
+			}
+			out = append(out, Sample{X: fishing.ModelInputs(f), Bite: bite})
+		}
+	}
+	return out
+}
+
+// SplitHoldout sets aside about frac of the labelled casts for evaluation
+// and returns the rest for training. Casts with and without bites are split
+// separately, taking every n-th cast, so both sets span the session and hold
+// bites. Unlabelled casts are dropped.
+func SplitHoldout(casts []fishing.TracedCast, labels map[int][]time.Duration, frac float64) (train, holdout []fishing.TracedCast) {
+	accBite, accCalm := 0.5, 0.5 // round to the nearest cast
+	for _, c := range casts {
+		bites, ok := labels[c.Cast]
+		if !ok {
+			continue
+		}
+		acc := &accCalm
+		if len(bites) > 0 {
+			acc = &accBite
+		}
+		if *acc += frac; *acc >= 1 {
+			*acc--
+			holdout = append(holdout, c)
+		} else {
+			train = append(train, c)
+		}
+	}
+	return train, holdout
+}
+
+// TrainOptions configure TrainLogistic.
+type TrainOptions struct {
+	Iterations   int     // full-batch gradient descent steps
+	LearningRate float64 // step size on standardised inputs
+	L2           float64 // weight decay
+}
+
+// DefaultTrainOptions suit the few thousand frames of a fishing session.
+var DefaultTrainOptions = TrainOptions{Iterations: 2000, LearningRate: 0.5, L2: 1e-3}
+
+// FrameMetrics scores a model's per-frame decisions against labels.
+type FrameMetrics struct {
+	TP, FP, FN, TN        int
+	Precision, Recall, F1 float64
+}
+
+// TrainLogistic fits a logistic regression to samples with gradient descent
+// on standardised inputs. Bite frames are rare, so both classes are weighted
+// to contribute equally. The threshold is the probability, in steps of 0.05,
+// that maximises the frame F1 on samples.
+func TrainLogistic(samples []Sample, opt TrainOptions) (*fishing.BiteModel, FrameMetrics, error) {
+	var pos int
+	for _, s := range samples {
+		if s.Bite {
+			pos++
+		}
+	}
+	if pos == 0 || pos == len(samples) {
+		return nil, FrameMetrics{}, errors.New("training needs both bite and non-bite frames")
+	}
+	n := len(fishing.BiteModelFeatures)
+	m := &fishing.BiteModel{
+		Kind:     fishing.BiteModelLogistic,
+		Features: append([]string(nil), fishing.BiteModelFeatures...),
+		Mean:     make([]float64, n),
+		Scale:    make([]float64, n),
+		Weights:  make([]float64, n),
+	}
+	for _, s := range samples {
+		for j, v := range s.X {
+			m.Mean[j] += v
+		}
+	}
+	for j := range m.Mean {
+		m.Mean[j] /= float64(len(samples))
+	}
+	for _, s := range samples {
+		for j, v := range s.X {
+			m.Scale[j] += (v - m.Mean[j]) * (v - m.Mean[j])
+		}
+	}
+	for j := range m.Scale {
+		m.Scale[j] = math.Sqrt(m.Scale[j] / float64(len(samples)))
+		if m.Scale[j] < 1e-9 {
+			m.Scale[j] = 1
+		}
+	}
+	z := make([][]float64, len(samples))
+	for i, s := range samples {
+		z[i] = make([]float64, n)
+		for j, v := range s.X {
+			z[i][j] = (v - m.Mean[j]) / m.Scale[j]
+		}
+	}
+	wPos := 0.5 / float64(pos)
+	wNeg := 0.5 / float64(len(samples)-pos)
+	grad := make([]float64, n)
+	for it := 0; it < opt.Iterations; it++ {
+		for j := range grad {
+			grad[j] = opt.L2 * m.Weights[j]
+		}
+		var gBias float64
+		for i, s := range samples {
+			p := logistic(m.Bias + dot(m.Weights, z[i]))
+			w, y := wNeg, 0.0
+			if s.Bite {
+				w, y = wPos, 1
+			}
+			e := w * (p - y)
+			for j, v := range z[i] {
+				grad[j] += e * v
+			}
+			gBias += e
+		}
+		for j := range m.Weights {
+			m.Weights[j] -= opt.LearningRate * grad[j]
+		}
+		m.Bias -= opt.LearningRate * gBias
+	}
+	bestT, bestF1 := 0.5, -1.0
+	for i := 1; i < 20; i++ {
+		m.Threshold = float64(i) / 20
+		if fm := EvaluateModel(m, samples); fm.F1 > bestF1 {
+			bestT, bestF1 = m.Threshold, fm.F1
+		}
+	}
+	m.Threshold = bestT
+	return m, EvaluateModel(m, samples), nil
+}
+
+// EvaluateModel returns the per-frame metrics of m on samples.
+func EvaluateModel(m *fishing.BiteModel, samples []Sample) FrameMetrics {
+	var fm FrameMetrics
+	for _, s := range samples {
+		hit := m.Score(s.X) >= m.Threshold
+		switch {
+		case hit && s.Bite:
+			fm.TP++
+		case hit:
+			fm.FP++
+		case s.Bite:
+			fm.FN++
+		default:
+			fm.TN++
+		}
+	}
+	if fm.TP+fm.FP > 0 {
+		fm.Precision = float64(fm.TP) / float64(fm.TP+fm.FP)
+	}
+	if fm.TP+fm.FN > 0 {
+		fm.Recall = float64(fm.TP) / float64(fm.TP+fm.FN)
+	}
+	if fm.Precision+fm.Recall > 0 {
+		fm.F1 = 2 * fm.Precision * fm.Recall / (fm.Precision + fm.Recall)
+	}
+	return fm
+}
+
+func logistic(v float64) float64 { return 1 / (1 + math.Exp(-v)) }
+
+func dot(a, b []float64) float64 {
+	var s float64
+	for i := range a {
+		s += a[i] * b[i]
+	}
+	return s
+}
@@ -0,0 +1,143 @@
+package tuning
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/soocke/pixel-bot-go/config"
+	"github.com/soocke/pixel-bot-go/domain/fishing"
+)
+
+// modelDetector returns a model bite detector using m, saved in dir.
+func modelDetector(t *testing.T, dir string, m *fishing.BiteModel) *fishing.ModelDetector {
+	t.Helper()
+	cfg := config.DefaultConfig()
+	cfg.BiteModel = filepath.Join(dir, "model.json")
+	if err := m.Save(cfg.BiteModel); err != nil {
+		t.Fatalf("save model: %v", err)
+	}
+	return fishing.NewModelDetector(cfg, nil)
+}
+
+// feed plays seq through d and returns the index of the triggering frame or
+// -1; trace, when set, records the features of every frame as cast.
+func feed(d *fishing.ModelDetector, seq *Sequence, trace fishing.BiteTrace, cast int) int {
+	d.Reset()
+	start := time.Now()
+	for i, f := range seq.Frames {
+		if d.FeedFrame(f.Img, start.Add(f.At)) {
+			return i
+		}
+		if feat, ok := d.LastFeatures(); ok && trace != nil {
+			trace.Frame(cast, feat)
+		}
+	}
+	return -1
+}
+
+func TestTrainLogistic_LearnsBitesFromLabelledTrace(t *testing.T) {
+	dir := t.TempDir()
+	n := len(fishing.BiteModelFeatures)
+	// An untrained model never fires, so every frame of a cast is traced.
+	recorder := modelDetector(t, dir, &fishing.BiteModel{
+		Kind: fishing.BiteModelLogistic, Features: fishing.BiteModelFeatures,
+		Mean: make([]float64, n), Scale: make([]float64, n), Weights: make([]float64, n), Threshold: 0.99,
+	})
+	path := filepath.Join(dir, "session.jsonl")
+	tw, err := fishing.OpenTrace(path)
+	if err != nil {
+		t.Fatalf("create trace: %v", err)
+	}
+	labels := "{"
+	for cast := 1; cast <= 8; cast++ {
+		biteAt := -1
+		if cast%2 == 0 {
+			biteAt = 20 + cast
+		}
+		if feed(recorder, sequence(60, biteAt, true, int64(cast)), tw, cast) >= 0 {
+			t.Fatalf("untrained model fired")
+		}
+		tw.Outcome(cast, fishing.OutcomeLost, time.Now())
+		if cast > 1 {
+			labels += ","
+		}
+		// The first traced frame is frame 1, the first with a frame 50ms older.
+		bites := "[]"
+		if biteAt >= 0 {
+			bites = "[" + strconv.Itoa((biteAt-1)*int(frameInterval/time.Millisecond)) + "]"
+		}
+		labels += strconv.Quote(strconv.Itoa(cast)) + ":" + bites
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatalf("close trace: %v", err)
+	}
+	if err := os.WriteFile(LabelsPath(path), []byte(labels+"}"), 0o644); err != nil {
+		t.Fatalf("write labels: %v", err)
+	}
+
+	casts, err := fishing.ReadTrace(path)
+	if err != nil {
+		t.Fatalf("read trace: %v", err)
+	}
+	lab, err := LoadLabels(LabelsPath(path))
+	if err != nil {
+		t.Fatalf("load labels: %v", err)
+	}
+	samples := LabelCasts(casts, lab, Window{Late: frameInterval})
+	model, m, err := TrainLogistic(samples, DefaultTrainOptions)
+	if err != nil {
+		t.Fatalf("train: %v", err)
+	}
+	if m.Precision < 0.75 || m.Recall < 0.75 {
+		t.Fatalf("poor fit on training frames: %+v", m)
+	}
+
+	d := modelDetector(t, dir, model)
+	if idx := feed(d, sequence(60, 30, true, 99), nil, 0); idx != 30 && idx != 31 {
+		t.Fatalf("trained model: bite at frame 30 detected at %d", idx)
+	}
+	if idx := feed(d, sequence(60, -1, true, 100), nil, 0); idx >= 0 {
+		t.Fatalf("trained model: rough water triggered at frame %d", idx)
+	}
+}
+
+func TestTrainLogistic_NeedsBothClasses(t *testing.T) {
+	x := make([]float64, len(fishing.BiteModelFeatures))
+	if _, _, err := TrainLogistic([]Sample{{X: x}, {X: x}}, DefaultTrainOptions); err == nil {
+		t.Fatalf("expected an error without bite frames")
+	}
+}
+
+func TestSplitHoldout_HoldsOutBiteAndCalmCasts(t *testing.T) {
+	var casts []fishing.TracedCast
+	labels := map[int][]time.Duration{}
+	for c := 1; c <= 21; c++ {
+		casts = append(casts, fishing.TracedCast{Cast: c})
+		if c == 21 {
+			continue // unlabelled
+		}
+		labels[c] = nil
+		if c%2 == 0 {
+			labels[c] = []time.Duration{time.Second}
+		}
+	}
+	train, holdout := SplitHoldout(casts, labels, 0.2)
+	if len(train)+len(holdout) != 20 {
+		t.Fatalf("split %d+%d casts, want the 20 labelled ones", len(train), len(holdout))
+	}
+	var bites int
+	for _, c := range holdout {
+		if len(labels[c.Cast]) > 0 {
+			bites++
+		}
+	}
+	if len(holdout) != 4 || bites != 2 {
+		t.Fatalf("held out %d casts with %d bites, want 4 with 2", len(holdout), bites)
+	}
+	if train, holdout := SplitHoldout(casts, labels, 0); len(train) != 20 || len(holdout) != 0 {
+		t.Fatalf("-holdout 0 held out %d casts", len(holdout))
+	}
+}
@@ -2,13 +2,18 @@ package model
 
 import (
 	"image"
+	"time"
 )
 
-// DetectionModel holds the current global ROI rectangle.
+// DetectionModel holds the current global ROI rectangle and detection statistics.
 // The zero value (empty rectangle) means no active ROI.
 // No synchronization is required because updates happen on the UI thread.
 type DetectionModel struct {
-	roi image.Rectangle
+	roi          image.Rectangle
+	acquireCold  AcquireStat
+	acquirePrior AcquireStat
+	cancelled    int
+	reclaimed    time.Duration
 }
 
 // NewDetectionModel returns an initialized DetectionModel.
@@ -33,3 +38,62 @@ func (m *DetectionModel) ROI() image.Rectangle {
 	}
 	return m.roi
 }
+
+// AcquireStat aggregates time-to-acquire samples for one search mode.
+type AcquireStat struct {
+	Count int
+	Total time.Duration
+}
+
+// Avg returns the mean time-to-acquire (zero when no samples were recorded).
+func (s AcquireStat) Avg() time.Duration {
+	if s.Count == 0 {
+		return 0
+	}
+	return s.Total / time.Duration(s.Count)
+}
+
+// RecordAcquire records the time from entering search to target acquisition.
+// withPrior reports whether the learned spatial prior guided the search.
+func (m *DetectionModel) RecordAcquire(d time.Duration, withPrior bool) {
+	if m == nil || d < 0 {
+		return
+	}
+	if withPrior {
+		m.acquirePrior.Count++
+		m.acquirePrior.Total += d
+		return
+	}
+	m.acquireCold.Count++
+	m.acquireCold.Total += d
+}
+
+// AcquireStats returns time-to-acquire statistics for searches without
+// (cold) and with (prior) the learned spatial prior.
+func (m *DetectionModel) AcquireStats() (cold, prior AcquireStat) {
+	if m == nil {
+		return AcquireStat{}, AcquireStat{}
+	}
+	return m.acquireCold, m.acquirePrior
+}
+
+// RecordCancelled records a detection task abandoned because the fishing state
+// changed; reclaimed is the estimated worker time saved by not finishing it.
+func (m *DetectionModel) RecordCancelled(reclaimed time.Duration) {
+	if m == nil {
+		return
+	}
+	m.cancelled++
+	if reclaimed > 0 {
+		m.reclaimed += reclaimed
+	}
+}
+
+// CancelStats returns the number of cancelled detection tasks and the total
+// estimated worker time reclaimed by cancelling them.
+func (m *DetectionModel) CancelStats() (count int, reclaimed time.Duration) {
+	if m == nil {
+		return 0, 0
+	}
+	return m.cancelled, m.reclaimed
+}
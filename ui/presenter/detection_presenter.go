@@ -1,10 +1,18 @@
 package presenter
 
 import (
+	"context"
 	"errors"
+	"fmt"
+	"hash/fnv"
 	"image"
 	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"log/slog"
@@ -12,6 +20,7 @@ import (
 	"github.com/soocke/pixel-bot-go/config"
 	"github.com/soocke/pixel-bot-go/domain/capture"
 	"github.com/soocke/pixel-bot-go/domain/fishing"
+	"github.com/soocke/pixel-bot-go/domain/preprocess"
 	"github.com/soocke/pixel-bot-go/ui/images"
 	"github.com/soocke/pixel-bot-go/ui/model"
 )
@@ -39,6 +48,7 @@ type SelectionRectProvider interface {
 type DetectionView interface {
 	UpdateCapture(img image.Image)
 	UpdateDetection(img image.Image)
+	SetDetectionStat(key, text string)
 }
 
 type detectionTaskKind int
@@ -46,27 +56,75 @@ type detectionTaskKind int
 const (
 	detectionTaskSearch detectionTaskKind = iota + 1
 	detectionTaskMonitor
+	detectionTaskBackground
 )
 
+// maxPreprocessCache bounds the cache of preprocessed templates.
+const maxPreprocessCache = 64
+
+// adaptiveSaveDelay is how long an adaptive template update waits before it
+// is written, so the updates of consecutive catches cost one write.
+const adaptiveSaveDelay = 30 * time.Second
+
+// backgroundSettle is how long after entering cooldown background sampling
+// waits, so the reeled-in bobber has disappeared.
+const backgroundSettle = 1500 * time.Millisecond
+
 type detectionTask struct {
+	ctx          context.Context // cancelled when the fishing state changes
+	gen          uint64
 	kind         detectionTaskKind
 	snapshot     capture.FrameSnapshot
 	selection    image.Rectangle
 	hasSelection bool
 	cfg          *config.Config
+	limits       capture.SearchLimits // per-search threshold and scale range
 	target       image.Image
 	targetPoint  image.Point
+	negatives    []image.Image          // negative templates suppressing known false positives
+	windows      []image.Rectangle      // search windows tried before the full frame (frame coordinates)
+	usePrior     bool                   // windows include the spatial prior's hot region
+	preCast      *capture.FrameSnapshot // frame captured just before the cast, diffed once per cast
+	castSeq      uint64
+	bgKey        string // background model the sampled score belongs to
 }
 
 type detectionResult struct {
-	kind     detectionTaskKind
-	sequence uint64
-	err      error
-	found    bool
-	location image.Point
-	roi      *image.RGBA
-	roiRect  image.Rectangle
-	duration time.Duration
+	kind      detectionTaskKind
+	sequence  uint64
+	gen       uint64
+	err       error
+	cancelled bool          // task was abandoned after a state change
+	reclaimed time.Duration // estimated worker time saved by cancelling
+	found     bool
+	location  image.Point
+	roi       *image.RGBA // raw ROI
+	input     *image.RGBA // ROI after preprocessing, fed to the bite detector
+	captured  time.Time   // capture time of the monitored frame
+	roiRect   image.Rectangle
+	prep      []preprocess.Timing // per-stage preprocessing time
+	duration  time.Duration
+	window    image.Rectangle // search window derived from the splash diff (restrict mode)
+	castSeq   uint64
+	local     image.Point     // target centre in frame coordinates
+	box       image.Rectangle // matched area in frame coordinates
+	bounds    image.Rectangle // frame bounds the match refers to
+	prior     bool            // search was guided by the spatial prior
+	scale     float64         // winning template scale (0 when not template based)
+	score     float64
+	sharp     float64      // peak sharpness of the match (0 when not template based)
+	inliers   int          // consistent keypoint matches (keypoint detector only)
+	adapt     *adaptSample // patch for the adaptive template once the catch is confirmed
+	bgKey     string       // background model of a background sample
+}
+
+// adaptSample is a detected patch awaiting reel confirmation, with the
+// adaptive template settings of the search that found it.
+type adaptSample struct {
+	patch    image.Image
+	rate     float64
+	minScore float64
+	profile  string
 }
 
 // DetectionPresenter coordinates capture preview and detection scheduling.
@@ -79,7 +137,17 @@ type DetectionPresenter struct {
 	Config    *config.Config
 	TargetImg image.Image
 	Model     *model.DetectionModel
-	logger    *slog.Logger
+	Prior     *capture.SpatialPrior    // optional; learns confirmed landing positions
+	PriorPath string                   // persistence path for Prior (empty disables saving)
+	Negatives *capture.NegativeLibrary // optional; templates of known false positives
+	// TemplateDir is the template library directory holding the adaptive
+	// template of each profile (empty disables saving).
+	TemplateDir string
+	Scales      *capture.ScaleTracker // learns the winning template scale
+	// Background learns template scores of target-free cooldown frames for
+	// the automatic threshold.
+	Background *capture.BackgroundScores
+	logger     *slog.Logger
 
 	workerOnce sync.Once
 	workCh     chan detectionTask
@@ -89,6 +157,59 @@ type DetectionPresenter struct {
 	lastMonitorSeq uint64
 	lastSearchTime time.Time
 	searchDelay    time.Duration
+	lastBgTime     time.Time
+	bgDelay        time.Duration
+
+	// state tracking shared with the FSM listener.
+	stateMu       sync.Mutex
+	gen           uint64 // incremented on every state transition
+	genCtx        context.Context
+	genCancel     context.CancelFunc
+	castSeq       uint64
+	castAt        time.Time
+	preCast       capture.FrameSnapshot
+	splashPending bool
+	searchWindow  image.Rectangle
+	searchStart   time.Time
+	acquired      bool // a target was acquired this cycle and awaits reel confirmation
+	acquiredAt    image.Point
+	acquiredIn    image.Rectangle
+	acquiredPatch *adaptSample
+	cooldownAt    time.Time
+
+	priorSaveMu    sync.Mutex  // serialises background writes of Prior
+	priorTarget    image.Image // template priorTargetSum was computed for (UI thread)
+	priorTargetSum uint64      // pixel checksum of priorTarget
+
+	scaleLabel string          // last rendered effective scale range
+	lastScale  float64         // scale of the most recent acquisition (UI thread)
+	lastBox    image.Rectangle // frame area of the most recent acquisition (UI thread)
+	bgKey      string          // settings the background samples were taken with (UI thread)
+	bgLabel    string          // last rendered background statistics
+
+	// preprocessing (UI thread)
+	showProcessed bool                        // previews show preprocessed images
+	prepSpec      string                      // filter chain prepCache was built for
+	prepCache     map[image.Image]image.Image // preprocessed templates by source image
+	prepShownAt   time.Time                   // last preprocessing timing update
+
+	heatmapMsg atomic.Pointer[string] // outcome of a background heatmap export
+	adaptMsg   atomic.Pointer[string] // outcome of the last adaptive template update
+
+	adaptMu         sync.Mutex
+	adaptive        *capture.AdaptiveTemplate // adaptive template of adaptiveProfile
+	adaptiveProfile string
+	adaptPending    map[string]*capture.AdaptiveTemplate // templates awaiting a write, by path (adaptMu)
+	adaptTimer      *time.Timer                          // fires the pending write (adaptMu)
+	adaptSaveMu     sync.Mutex                           // serialises adaptive template file access
+
+	taskCost    [detectionTaskBackground + 1]time.Duration // moving average run time per task kind (worker goroutine only)
+	badDetector string                                     // last unknown detector name reported (worker goroutine only)
+
+	// target detector reused across searches (worker goroutine only)
+	detector    capture.TargetDetector
+	detectorKey string      // settings detector was built with, see detectorKey
+	detectorFor image.Image // search template detector was built for
 }
 
 // NewDetectionPresenter constructs a detection presenter.
@@ -105,10 +226,13 @@ func NewDetectionPresenter(enabled func() bool, source FrameSource, fsm Detectio
 		Config:         cfg,
 		TargetImg:      target,
 		Model:          model,
+		Scales:         capture.NewScaleTracker(0, 0),
+		Background:     capture.NewBackgroundScores(0),
 		logger:         logger,
 		workCh:         make(chan detectionTask, 1),
 		resultCh:       make(chan detectionResult, 1),
 		searchDelay:    65 * time.Millisecond,
+		bgDelay:        400 * time.Millisecond,
 		lastSearchSeq:  0,
 		lastMonitorSeq: 0,
 	}
@@ -132,6 +256,12 @@ func (p *DetectionPresenter) ProcessFrame() {
 	}
 
 drained:
+	if msg := p.heatmapMsg.Swap(nil); msg != nil {
+		p.View.SetDetectionStat("heatmap", *msg)
+	}
+	if msg := p.adaptMsg.Swap(nil); msg != nil {
+		p.View.SetDetectionStat("adaptive", *msg)
+	}
 	if !p.Enabled() || !p.Source.Running() {
 		return
 	}
@@ -142,7 +272,12 @@ drained:
 		return
 	}
 
-	p.View.UpdateCapture(frame)
+	if p.showProcessed {
+		processed, _ := preprocess.New(p.copyConfig().Preprocess).Apply(frame)
+		p.View.UpdateCapture(processed)
+	} else {
+		p.View.UpdateCapture(frame)
+	}
 
 	var selection image.Rectangle
 	hasSelection := false
@@ -158,6 +293,57 @@ drained:
 		p.maybeDispatchSearch(snapshot, selection, hasSelection)
 	case fishing.StateMonitoring:
 		p.maybeDispatchMonitor(snapshot, selection, hasSelection)
+	case fishing.StateCooldown:
+		p.maybeDispatchBackground(snapshot)
+	}
+}
+
+// OnState records cast transitions so the search can diff pre/post cast
+// frames. Register it as an FSM listener; it may run on the FSM goroutine.
+func (p *DetectionPresenter) OnState(prev, next fishing.FishingState) {
+	if p == nil {
+		return
+	}
+	p.stateMu.Lock()
+	defer p.stateMu.Unlock()
+	// Work scheduled for the previous state is stale; stop it early.
+	if p.genCancel != nil {
+		p.genCancel()
+	}
+	p.gen++
+	p.genCtx, p.genCancel = context.WithCancel(context.Background())
+	if prev == fishing.StateMonitoring && next == fishing.StateCooldown && p.acquired {
+		p.confirmPosition(p.acquiredAt, p.acquiredIn)
+		if p.acquiredPatch != nil {
+			p.adaptTemplate(*p.acquiredPatch)
+		}
+	}
+	if prev == fishing.StateSearching && next == fishing.StateCasting {
+		p.Scales.Miss()
+	}
+	if next != fishing.StateMonitoring {
+		p.acquired = false
+		p.acquiredPatch = nil
+	}
+	switch next {
+	case fishing.StateCasting:
+		p.castSeq++
+		p.castAt = time.Now()
+		p.searchWindow = image.Rectangle{}
+		p.splashPending = false
+		if p.Source != nil {
+			p.preCast = p.Source.LatestFrame()
+			p.splashPending = p.preCast.Image != nil
+		}
+	case fishing.StateSearching:
+		p.searchStart = time.Now()
+	case fishing.StateCooldown:
+		p.cooldownAt = time.Now()
+		p.splashPending = false
+		p.searchWindow = image.Rectangle{}
+	default:
+		p.splashPending = false
+		p.searchWindow = image.Rectangle{}
 	}
 }
 
@@ -167,9 +353,18 @@ func (p *DetectionPresenter) ensureWorker() {
 	})
 }
 
+// generation returns the context and number of the current state generation.
+// Caller must hold stateMu.
+func (p *DetectionPresenter) generation() (context.Context, uint64) {
+	if p.genCtx == nil {
+		p.genCtx, p.genCancel = context.WithCancel(context.Background())
+	}
+	return p.genCtx, p.gen
+}
+
 func (p *DetectionPresenter) runWorker() {
 	for task := range p.workCh {
-		res := p.executeTask(task)
+		res := p.runTask(task)
 		if res.kind == 0 {
 			continue
 		}
@@ -188,6 +383,37 @@ func (p *DetectionPresenter) runWorker() {
 	}
 }
 
+// runTask executes task unless its state generation has ended and tracks the
+// average run time per task kind to estimate the time reclaimed by
+// cancellation.
+func (p *DetectionPresenter) runTask(task detectionTask) detectionResult {
+	if task.ctx == nil {
+		task.ctx = context.Background()
+	}
+	expected := p.taskCost[task.kind]
+	if task.ctx.Err() != nil {
+		return detectionResult{kind: task.kind, sequence: task.snapshot.Sequence, gen: task.gen, cancelled: true, reclaimed: expected}
+	}
+	start := time.Now()
+	res := p.executeTask(task)
+	res.gen = task.gen
+	elapsed := time.Since(start)
+	if errors.Is(res.err, context.Canceled) || task.ctx.Err() != nil {
+		res.err = nil
+		res.cancelled = true
+		res.reclaimed = max(0, expected-elapsed)
+		return res
+	}
+	if res.err == nil {
+		if expected == 0 {
+			p.taskCost[task.kind] = elapsed
+		} else {
+			p.taskCost[task.kind] = (expected*7 + elapsed) / 8
+		}
+	}
+	return res
+}
+
 func (p *DetectionPresenter) maybeDispatchSearch(snapshot capture.FrameSnapshot, selection image.Rectangle, hasSelection bool) {
 	if p.TargetImg == nil {
 		return
@@ -200,17 +426,64 @@ func (p *DetectionPresenter) maybeDispatchSearch(snapshot capture.FrameSnapshot,
 	}
 	p.lastSearchSeq = snapshot.Sequence
 	p.lastSearchTime = time.Now()
+	cfg := p.searchConfig()
 	task := detectionTask{
 		kind:         detectionTaskSearch,
 		snapshot:     snapshot,
 		selection:    selection,
 		hasSelection: hasSelection,
-		cfg:          p.copyConfig(),
-		target:       p.TargetImg,
+		cfg:          cfg,
+		target:       p.preprocessed(cfg, p.searchTarget(cfg)),
+		negatives:    p.Negatives.Templates(),
+	}
+	for i, neg := range task.negatives {
+		task.negatives[i] = p.preprocessed(cfg, neg)
+	}
+	p.stateMu.Lock()
+	task.ctx, task.gen = p.generation()
+	task.castSeq = p.castSeq
+	if !p.searchWindow.Empty() {
+		task.windows = append(task.windows, p.searchWindow)
+	}
+	if p.splashPending && task.cfg.SplashSearch != config.SplashSearchOff &&
+		time.Since(p.castAt) >= time.Duration(task.cfg.SplashSettleMs)*time.Millisecond {
+		pre := p.preCast
+		task.preCast = &pre
+		p.splashPending = false
+	}
+	p.stateMu.Unlock()
+	task.limits = p.searchLimits(task.cfg)
+	if task.cfg.SpatialPrior && snapshot.Image != nil {
+		p.bindPrior(selection, hasSelection)
+		if hot, ok := p.Prior.HotRegion(snapshot.Image.Bounds(), 0); ok {
+			task.windows = append(task.windows, p.expandWindow(hot, task.target, snapshot.Image.Bounds(), task.cfg))
+			task.usePrior = true
+		}
 	}
 	p.dispatchTask(task)
 }
 
+// searchConfig returns the settings of a new search task. The colour blob
+// detector searches raw frames: its colours are sampled from raw frames, and
+// stages such as channel or gamma would move the bobber out of their ranges.
+func (p *DetectionPresenter) searchConfig() *config.Config {
+	cfg := p.copyConfig()
+	if cfg.Detector == config.DetectorHSV {
+		cfg.Preprocess = nil
+	}
+	return cfg
+}
+
+// searchLimits returns the learned threshold and the adaptive scale range of
+// a search with cfg. They change from search to search, so they are passed
+// to the detector per search instead of rebuilding it.
+func (p *DetectionPresenter) searchLimits(cfg *config.Config) capture.SearchLimits {
+	local := *cfg
+	p.applyAutoThreshold(&local)
+	p.applyScaleRange(&local)
+	return capture.SearchLimits{Threshold: local.Threshold, StopOnScore: local.StopOnScore, MinScale: local.MinScale, MaxScale: local.MaxScale}
+}
+
 func (p *DetectionPresenter) maybeDispatchMonitor(snapshot capture.FrameSnapshot, selection image.Rectangle, hasSelection bool) {
 	if snapshot.Sequence == 0 || snapshot.Sequence == p.lastMonitorSeq {
 		return
@@ -228,6 +501,45 @@ func (p *DetectionPresenter) maybeDispatchMonitor(snapshot capture.FrameSnapshot
 		cfg:          p.copyConfig(),
 		targetPoint:  image.Pt(px, py),
 	}
+	p.stateMu.Lock()
+	task.ctx, task.gen = p.generation()
+	p.stateMu.Unlock()
+	p.dispatchTask(task)
+}
+
+// maybeDispatchBackground samples the template score of a cooldown frame,
+// which holds no bobber, for the background distribution.
+func (p *DetectionPresenter) maybeDispatchBackground(snapshot capture.FrameSnapshot) {
+	if p.TargetImg == nil || p.Background == nil || snapshot.Sequence == 0 {
+		return
+	}
+	if !p.lastBgTime.IsZero() && time.Since(p.lastBgTime) < p.bgDelay {
+		return
+	}
+	p.stateMu.Lock()
+	settled := time.Since(p.cooldownAt) >= backgroundSettle
+	ctx, gen := p.generation()
+	p.stateMu.Unlock()
+	if !settled {
+		return
+	}
+	p.lastBgTime = time.Now()
+	cfg := p.copyConfig()
+	key := backgroundKey(cfg)
+	if key != p.bgKey {
+		// Scores depend on these settings; start over when they change.
+		p.Background.Reset()
+		p.bgKey = key
+	}
+	task := detectionTask{
+		ctx:      ctx,
+		gen:      gen,
+		kind:     detectionTaskBackground,
+		snapshot: snapshot,
+		cfg:      cfg,
+		target:   p.preprocessed(cfg, p.searchTarget(cfg)),
+		bgKey:    key,
+	}
 	p.dispatchTask(task)
 }
 
@@ -262,53 +574,187 @@ func (p *DetectionPresenter) executeTask(task detectionTask) detectionResult {
 		return p.doSearch(task, frame, cfg)
 	case detectionTaskMonitor:
 		return p.doMonitor(task, frame, cfg)
+	case detectionTaskBackground:
+		frame, res.prep = preprocess.New(cfg.Preprocess).Apply(frame)
+		score, err := capture.BackgroundScore(task.ctx, analysisFrame(frame, cfg.AnalysisScale), task.target, cfg)
+		res.score, res.err, res.bgKey = score, err, task.bgKey
+		return res
 	default:
 		res.err = errors.New("unknown detection task kind")
 		return res
 	}
 }
 
-func (p *DetectionPresenter) doSearch(task detectionTask, frame *image.RGBA, cfg *config.Config) detectionResult {
-	res := detectionResult{kind: detectionTaskSearch, sequence: task.snapshot.Sequence}
-	analysis := frame
-	scaleX, scaleY := 1.0, 1.0
-	if cfg.AnalysisScale > 0 && cfg.AnalysisScale < 1.0 {
-		w := int(math.Max(1, math.Round(float64(frame.Bounds().Dx())*cfg.AnalysisScale)))
-		h := int(math.Max(1, math.Round(float64(frame.Bounds().Dy())*cfg.AnalysisScale)))
-		scaled := images.ScaleToFit(frame, w, h)
-		if scaled != nil && scaled.Bounds().Dx() > 0 && scaled.Bounds().Dy() > 0 {
-			analysis = scaled
-			scaleX = float64(frame.Bounds().Dx()) / float64(analysis.Bounds().Dx())
-			scaleY = float64(frame.Bounds().Dy()) / float64(analysis.Bounds().Dy())
+func (p *DetectionPresenter) doSearch(task detectionTask, frame *image.RGBA, cfg *config.Config) (res detectionResult) {
+	res = detectionResult{kind: detectionTaskSearch, sequence: task.snapshot.Sequence, castSeq: task.castSeq, bounds: frame.Bounds(), prior: task.usePrior}
+	start := time.Now()
+	defer func() { res.duration = time.Since(start) }()
+	windows := task.windows
+	if task.preCast != nil {
+		splash := capture.LocateSplash(task.preCast.Image, frame, capture.SplashOptions{})
+		if splash.Found {
+			if p.logger != nil {
+				p.logger.Debug("splash located", "x", splash.X, "y", splash.Y, "area", splash.Area, "region", splash.Region)
+			}
+			if cfg.SplashSearch == config.SplashSearchAcquire {
+				res.found = true
+				res.local = image.Pt(splash.X, splash.Y)
+				res.location = p.toGlobal(task, res.local)
+				return res
+			}
+			res.window = p.expandWindow(splash.Region, task.target, frame.Bounds(), cfg)
+			windows = append([]image.Rectangle{res.window}, windows...)
 		}
 	}
-	start := time.Now()
-	match, err := capture.DetectTemplateDetailed(analysis, task.target, cfg)
-	res.duration = time.Since(start)
+	// The raw frame feeds the adaptive template, which is preprocessed on use.
+	raw := frame
+	frame, res.prep = preprocess.New(cfg.Preprocess).Apply(frame)
+	detector, err := p.targetDetector(cfg, task.target)
 	if err != nil {
 		res.err = err
 		return res
 	}
-	if !match.Found {
-		return res
+	// Negatives are cropped from full-size frames; match them at analysis size.
+	negScale := 1.0
+	if cfg.AnalysisScale > 0 && cfg.AnalysisScale < 1 {
+		negScale = cfg.AnalysisScale
 	}
-	x := match.X
-	y := match.Y
-	if analysis != frame {
-		x = int(math.Round(float64(x) * scaleX))
-		y = int(math.Round(float64(y) * scaleY))
+	detector = capture.WithNegatives(detector, task.target, task.negatives, negScale, capture.MatchModeFromConfig(cfg.MatchMode))
+	// Try the likely windows first and fall back to the full frame.
+	for _, region := range append(windows, frame.Bounds()) {
+		if region.Empty() {
+			continue
+		}
+		cands, err := p.matchRegion(task.ctx, detector, frame, region, cfg, task.limits)
+		if err != nil {
+			res.err = err
+			return res
+		}
+		if len(cands) > 0 {
+			best := cands[0]
+			res.found = true
+			res.scale = best.Scale
+			res.score, res.sharp, res.inliers = best.Score, best.Sharpness, best.Inliers
+			res.local = best.Anchor()
+			res.box = best.Bounds
+			res.location = p.toGlobal(task, res.local)
+			if cfg.AdaptiveTemplate {
+				if box := best.Bounds.Intersect(frame.Bounds()); !box.Empty() {
+					res.adapt = &adaptSample{patch: raw.SubImage(box), rate: cfg.AdaptiveRate, minScore: cfg.AdaptiveMinScore, profile: cfg.Profile}
+				}
+			}
+			return res
+		}
+	}
+	return res
+}
+
+// targetDetector returns the configured target detector, falling back to
+// template matching when the configured name is unknown. Building a detector
+// can be expensive (keypoint detectors describe the template at every scale),
+// so it is reused until its settings or the search template change; the
+// template changes with preprocessing and adaptive template updates.
+func (p *DetectionPresenter) targetDetector(cfg *config.Config, target image.Image) (capture.TargetDetector, error) {
+	key := detectorKey(cfg)
+	if p.detector != nil && p.detectorFor == target && p.detectorKey == key {
+		return p.detector, nil
+	}
+	detector, err := capture.NewTargetDetector(cfg.Detector, cfg, target)
+	if err != nil && cfg.Detector != config.DetectorNCC {
+		if p.logger != nil && p.badDetector != cfg.Detector {
+			p.logger.Warn("target detector unavailable; using ncc", "detector", cfg.Detector, "error", err)
+		}
+		p.badDetector = cfg.Detector
+		detector, err = capture.NewTargetDetector(config.DetectorNCC, cfg, target)
+	}
+	if err != nil {
+		p.detector = nil
+		return nil, err
+	}
+	p.detector, p.detectorKey, p.detectorFor = detector, key, target
+	return detector, nil
+}
+
+// detectorKey returns the settings a target detector is built from. The
+// threshold and the adaptive scale range are left out: they arrive with each
+// search as capture.SearchLimits.
+func detectorKey(cfg *config.Config) string {
+	return fmt.Sprintf("%s|%s|%d|%t|%t|%.3f|%.3f-%.3f|%d|%v|%d-%d|%s",
+		cfg.Detector, cfg.MatchMode, cfg.Stride, cfg.Refine, cfg.ReturnBestEven, cfg.ScaleStep,
+		cfg.MinScale, cfg.MaxScale, cfg.KeypointMinInliers, cfg.BlobColors, cfg.BlobMinArea, cfg.BlobMaxArea,
+		config.FormatStages(cfg.Preprocess))
+}
+
+// matchRegion runs the detector on the region of frame, applying the
+// configured analysis downscale, and returns candidates in frame coordinates.
+func (p *DetectionPresenter) matchRegion(ctx context.Context, detector capture.TargetDetector, frame *image.RGBA, region image.Rectangle, cfg *config.Config, lim capture.SearchLimits) ([]capture.Candidate, error) {
+	region = region.Intersect(frame.Bounds())
+	if region.Empty() {
+		return nil, nil
 	}
+	crop := frame
+	if region != frame.Bounds() {
+		crop = frame.SubImage(region).(*image.RGBA)
+	}
+	analysis := analysisFrame(crop, cfg.AnalysisScale)
+	cands, err := detector.Detect(ctx, analysis, lim)
+	if err != nil || analysis == crop {
+		// Sub-images keep frame coordinates.
+		return cands, err
+	}
+	scaleX := float64(region.Dx()) / float64(analysis.Bounds().Dx())
+	scaleY := float64(region.Dy()) / float64(analysis.Bounds().Dy())
+	for i := range cands {
+		c := &cands[i]
+		// Rescale the sub-pixel estimate so analysis rounding is not amplified.
+		c.FX = c.FX*scaleX + float64(region.Min.X)
+		c.FY = c.FY*scaleY + float64(region.Min.Y)
+		c.CX = c.CX*scaleX + float64(region.Min.X)
+		c.CY = c.CY*scaleY + float64(region.Min.Y)
+		x, y := int(math.Round(c.FX)), int(math.Round(c.FY))
+		w := int(math.Round(float64(c.Bounds.Dx()) * scaleX))
+		h := int(math.Round(float64(c.Bounds.Dy()) * scaleY))
+		c.Bounds = image.Rect(x, y, x+w, y+h)
+	}
+	return cands, nil
+}
+
+// analysisFrame downsizes img by scale for analysis; img is returned
+// unchanged when scale is not in (0, 1) or scaling fails.
+func analysisFrame(img *image.RGBA, scale float64) *image.RGBA {
+	if scale <= 0 || scale >= 1 {
+		return img
+	}
+	w := int(math.Max(1, math.Round(float64(img.Bounds().Dx())*scale)))
+	h := int(math.Max(1, math.Round(float64(img.Bounds().Dy())*scale)))
+	scaled := images.ScaleToFit(img, w, h)
+	if scaled == nil || scaled.Bounds().Dx() == 0 || scaled.Bounds().Dy() == 0 {
+		return img
+	}
+	return scaled
+}
+
+// expandWindow expands a candidate region by the largest expected template size so
+// the full template fits around any point of the region.
+func (p *DetectionPresenter) expandWindow(region image.Rectangle, target image.Image, bounds image.Rectangle, cfg *config.Config) image.Rectangle {
+	margin := 16
+	if target != nil {
+		tb := target.Bounds()
+		margin = int(math.Ceil(float64(max(tb.Dx(), tb.Dy())) * cfg.MaxScale))
+	}
+	return region.Inset(-margin).Intersect(bounds)
+}
+
+// toGlobal converts a selection-relative frame point to screen coordinates.
+func (p *DetectionPresenter) toGlobal(task detectionTask, pt image.Point) image.Point {
 	if task.hasSelection {
-		x += task.selection.Min.X
-		y += task.selection.Min.Y
+		return pt.Add(task.selection.Min)
 	}
-	res.found = true
-	res.location = image.Pt(x, y)
-	return res
+	return pt
 }
 
 func (p *DetectionPresenter) doMonitor(task detectionTask, frame *image.RGBA, cfg *config.Config) detectionResult {
-	res := detectionResult{kind: detectionTaskMonitor, sequence: task.snapshot.Sequence}
+	res := detectionResult{kind: detectionTaskMonitor, sequence: task.snapshot.Sequence, captured: task.snapshot.CapturedAt}
 	pt := task.targetPoint
 	localX := pt.X
 	localY := pt.Y
@@ -328,11 +774,23 @@ func (p *DetectionPresenter) doMonitor(task detectionTask, frame *image.RGBA, cf
 	res.found = true
 	res.location = pt
 	res.roi = roi
+	res.input, res.prep = preprocess.New(cfg.Preprocess).Apply(roi)
 	res.roiRect = globalRect
 	return res
 }
 
 func (p *DetectionPresenter) handleResult(res detectionResult) {
+	if res.cancelled {
+		p.recordCancelled(res)
+		return
+	}
+	p.stateMu.Lock()
+	stale := res.gen != p.gen
+	p.stateMu.Unlock()
+	if stale {
+		// Finished after the state it was scheduled for ended.
+		return
+	}
 	if res.err != nil {
 		if p.logger != nil {
 			p.logger.Error("detection", "error", res.err)
@@ -341,18 +799,585 @@ func (p *DetectionPresenter) handleResult(res detectionResult) {
 	}
 	switch res.kind {
 	case detectionTaskSearch:
+		if !res.window.Empty() {
+			p.stateMu.Lock()
+			if res.castSeq == p.castSeq {
+				p.searchWindow = res.window
+			}
+			p.stateMu.Unlock()
+		}
+		p.showPreprocess(res.prep, "search")
 		if res.found {
+			p.recordAcquire(res)
 			p.FSM.EventTargetAcquiredAt(res.location.X, res.location.Y)
 		}
+	case detectionTaskBackground:
+		if res.bgKey == p.bgKey {
+			p.Background.Add(res.score)
+			p.showBackground(p.copyConfig())
+		}
 	case detectionTaskMonitor:
 		if res.roi != nil {
 			if p.Model != nil {
 				p.Model.SetROI(res.roiRect)
 			}
-			p.View.UpdateDetection(res.roi)
-			p.FSM.ProcessMonitoringFrame(res.roi, time.Now())
+			p.showPreprocess(res.prep, "bite")
+			if p.showProcessed {
+				p.View.UpdateDetection(res.input)
+			} else {
+				p.View.UpdateDetection(res.roi)
+			}
+			// The bite detector normalises by frame timestamps; the capture
+			// time excludes the variable delay of the worker and UI tick.
+			at := res.captured
+			if at.IsZero() {
+				at = time.Now()
+			}
+			p.FSM.ProcessMonitoringFrame(res.input, at)
+		}
+	}
+}
+
+// recordAcquire notes an acquisition for reel confirmation and updates the
+// time-to-acquire statistics.
+func (p *DetectionPresenter) recordAcquire(res detectionResult) {
+	p.stateMu.Lock()
+	if res.castSeq != p.castSeq {
+		p.stateMu.Unlock()
+		return
+	}
+	started := p.searchStart
+	p.acquired, p.acquiredAt, p.acquiredIn = true, res.local, res.bounds
+	p.acquiredPatch = res.adapt
+	p.stateMu.Unlock()
+	p.Scales.Hit(res.scale)
+	if res.scale > 0 {
+		p.lastScale = res.scale
+	}
+	p.lastBox = res.box
+	if started.IsZero() || p.Model == nil {
+		return
+	}
+	elapsed := time.Since(started)
+	p.Model.RecordAcquire(elapsed, res.prior)
+	if p.logger != nil {
+		p.logger.Info("target acquired", "time_to_acquire", elapsed, "prior", res.prior, "score", res.score, "scale", res.scale, "sharpness", res.sharp, "inliers", res.inliers)
+	}
+	switch {
+	case res.inliers > 0:
+		p.View.SetDetectionStat("match", fmt.Sprintf("Match: %.2f @%.2fx %d inliers", res.score, res.scale, res.inliers))
+	case res.scale > 0:
+		p.View.SetDetectionStat("match", fmt.Sprintf("Match: %.2f @%.2fx sharp %.1f", res.score, res.scale, res.sharp))
+	}
+	cold, prior := p.Model.AcquireStats()
+	p.View.SetDetectionStat("acquire", fmt.Sprintf("Acquire: full %s (n=%d) | prior %s (n=%d)",
+		formatSeconds(cold.Avg()), cold.Count, formatSeconds(prior.Avg()), prior.Count))
+}
+
+// LearnBlobColor samples the colour around pt (coordinates of the latest
+// captured frame) and stores it in the configuration for the colour blob
+// detector. add appends the range instead of replacing the learned colours.
+// Samples are ignored unless the colour blob detector is selected, so stray
+// clicks cannot replace the learned colours. It reports whether the
+// configuration changed.
+func (p *DetectionPresenter) LearnBlobColor(pt image.Point, add bool) bool {
+	if p == nil || p.Source == nil || p.Config == nil {
+		return false
+	}
+	if p.Config.Detector != config.DetectorHSV {
+		if p.View != nil {
+			p.View.SetDetectionStat("blob", "Blob colour: select the hsv detector to sample")
+		}
+		return false
+	}
+	frame := p.Source.LatestFrame().Image
+	rng, ok := capture.LearnHSVRange(frame, pt, 3)
+	if !ok {
+		if p.View != nil {
+			p.View.SetDetectionStat("blob", "Blob colour: sample has no hue")
+		}
+		return false
+	}
+	if add {
+		p.Config.BlobColors = append(slices.Clone(p.Config.BlobColors), rng)
+	} else {
+		p.Config.BlobColors = []config.HSVRange{rng}
+	}
+	if p.logger != nil {
+		p.logger.Info("blob colour learned", "x", pt.X, "y", pt.Y, "range", rng, "ranges", len(p.Config.BlobColors))
+	}
+	if p.View != nil {
+		p.View.SetDetectionStat("blob", fmt.Sprintf("Blob colour: H %.0f-%.0f S %.2f-%.2f V %.2f-%.2f (%d)",
+			rng.HMin, rng.HMax, rng.SMin, rng.SMax, rng.VMin, rng.VMax, len(p.Config.BlobColors)))
+	}
+	return true
+}
+
+// RejectDetection stores the area around pt (coordinates of the latest
+// captured frame) as a negative template so the scene element there is no
+// longer taken for the bobber. Clicking inside the most recent detection
+// stores exactly the detected area; elsewhere a template-sized area centred
+// on pt is used. It reports whether a negative was added.
+func (p *DetectionPresenter) RejectDetection(pt image.Point) bool {
+	if p == nil || p.Source == nil || p.Negatives == nil {
+		return false
+	}
+	frame := p.Source.LatestFrame().Image
+	if frame == nil || !pt.In(frame.Bounds()) {
+		return false
+	}
+	box := p.lastBox
+	if !pt.In(box) {
+		w, h := 16, 16
+		if p.TargetImg != nil {
+			scale := p.lastScale
+			if scale <= 0 {
+				scale = 1
+			}
+			w = int(math.Round(float64(p.TargetImg.Bounds().Dx()) * scale))
+			h = int(math.Round(float64(p.TargetImg.Bounds().Dy()) * scale))
+		}
+		box = image.Rect(pt.X-w/2, pt.Y-h/2, pt.X-w/2+w, pt.Y-h/2+h)
+	}
+	box = box.Intersect(frame.Bounds())
+	if box.Dx() < 3 || box.Dy() < 3 {
+		return false
+	}
+	path, err := p.Negatives.Add(frame.SubImage(box))
+	if err != nil {
+		if p.logger != nil {
+			p.logger.Error("negative template save failed", "error", err)
+		}
+		p.setNegativeStat("save failed")
+		return false
+	}
+	if p.logger != nil {
+		p.logger.Info("negative template added", "path", path, "area", box)
+	}
+	p.setNegativeStat("added")
+	return true
+}
+
+// ForgetNegative removes the negative templates showing the spot around pt,
+// e.g. a bobber that is now wrongly suppressed. It reports whether any
+// template was removed.
+func (p *DetectionPresenter) ForgetNegative(pt image.Point) bool {
+	if p == nil || p.Source == nil || p.Negatives == nil {
+		return false
+	}
+	n, err := p.Negatives.RemoveAt(p.Source.LatestFrame().Image, pt)
+	if err != nil && p.logger != nil {
+		p.logger.Error("negative template removal failed", "error", err)
+	}
+	if p.logger != nil && n > 0 {
+		p.logger.Info("negative templates removed", "count", n, "x", pt.X, "y", pt.Y)
+	}
+	p.setNegativeStat(fmt.Sprintf("removed %d", n))
+	return n > 0
+}
+
+func (p *DetectionPresenter) setNegativeStat(action string) {
+	if p.View != nil {
+		p.View.SetDetectionStat("negatives", fmt.Sprintf("Negatives: %d (%s)", p.Negatives.Len(), action))
+	}
+}
+
+// recordCancelled updates the cancellation statistics.
+func (p *DetectionPresenter) recordCancelled(res detectionResult) {
+	if p.logger != nil {
+		p.logger.Debug("detection task cancelled", "kind", res.kind, "sequence", res.sequence, "reclaimed", res.reclaimed)
+	}
+	if p.Model == nil {
+		return
+	}
+	p.Model.RecordCancelled(res.reclaimed)
+	count, reclaimed := p.Model.CancelStats()
+	p.View.SetDetectionStat("cancel", fmt.Sprintf("Cancelled: %d (saved %s)", count, formatSeconds(reclaimed)))
+}
+
+// applyScaleRange narrows cfg's scale range around recently winning scales
+// when adaptive scaling is enabled and reports the effective range.
+func (p *DetectionPresenter) applyScaleRange(cfg *config.Config) {
+	mode := "static"
+	if cfg.AdaptiveScale {
+		lo, hi, narrowed := p.Scales.Range(cfg.MinScale, cfg.MaxScale, cfg.ScaleStep)
+		if narrowed && hi-lo >= cfg.ScaleStep {
+			cfg.MinScale, cfg.MaxScale = lo, hi
+			mode = "adaptive"
+		} else {
+			mode = "full"
+		}
+	}
+	label := fmt.Sprintf("Scales: %.2f-%.2f (%s)", cfg.MinScale, cfg.MaxScale, mode)
+	if label != p.scaleLabel {
+		p.scaleLabel = label
+		p.View.SetDetectionStat("scales", label)
+	}
+}
+
+// SetShowProcessed switches the previews between raw and preprocessed images.
+func (p *DetectionPresenter) SetShowProcessed(on bool) {
+	if p != nil {
+		p.showProcessed = on
+	}
+}
+
+// preprocessed returns img with the template stages of cfg's filter chain
+// applied, cached per source image while the chain is unchanged.
+func (p *DetectionPresenter) preprocessed(cfg *config.Config, img image.Image) image.Image {
+	if len(cfg.Preprocess) == 0 || img == nil {
+		return img
+	}
+	spec := config.FormatStages(cfg.Preprocess)
+	if spec != p.prepSpec || len(p.prepCache) >= maxPreprocessCache {
+		p.prepSpec, p.prepCache = spec, make(map[image.Image]image.Image)
+	}
+	out, ok := p.prepCache[img]
+	if !ok {
+		out = preprocess.New(cfg.Preprocess).ApplyTemplate(img)
+		p.prepCache[img] = out
+	}
+	return out
+}
+
+// showPreprocess reports the per-stage preprocessing time of a search or
+// bite frame, at most once a second.
+func (p *DetectionPresenter) showPreprocess(timings []preprocess.Timing, what string) {
+	if len(timings) == 0 || time.Since(p.prepShownAt) < time.Second {
+		return
+	}
+	p.prepShownAt = time.Now()
+	parts := make([]string, len(timings))
+	for i, t := range timings {
+		parts[i] = fmt.Sprintf("%s %.1fms", t.Stage, float64(t.Duration.Microseconds())/1000)
+	}
+	p.View.SetDetectionStat("preprocess", fmt.Sprintf("Preprocess (%s): %s", what, strings.Join(parts, ", ")))
+}
+
+// backgroundKey identifies the settings background scores depend on.
+func backgroundKey(cfg *config.Config) string {
+	return fmt.Sprintf("%s|%s|%.2f|%.2f-%.2f|%s", cfg.Profile, cfg.MatchMode, cfg.AnalysisScale, cfg.MinScale, cfg.MaxScale, config.FormatStages(cfg.Preprocess))
+}
+
+// effectiveThreshold returns the threshold searches with cfg use and whether
+// it was learned from the background scores.
+func (p *DetectionPresenter) effectiveThreshold(cfg *config.Config) (float64, bool) {
+	if !cfg.AutoThreshold || backgroundKey(cfg) != p.bgKey {
+		return cfg.Threshold, false
+	}
+	if t, ok := p.Background.Threshold(cfg.FalsePositiveRate); ok {
+		return t, true
+	}
+	return cfg.Threshold, false
+}
+
+// applyAutoThreshold replaces cfg's threshold with the learned one when the
+// automatic threshold is enabled and ready. The early stop never undercuts it.
+func (p *DetectionPresenter) applyAutoThreshold(cfg *config.Config) {
+	p.showBackground(cfg)
+	if t, auto := p.effectiveThreshold(cfg); auto {
+		cfg.Threshold = t
+		cfg.StopOnScore = math.Max(cfg.StopOnScore, t)
+	}
+}
+
+// showBackground reports the learned background distribution and the
+// effective threshold for cfg.
+func (p *DetectionPresenter) showBackground(cfg *config.Config) {
+	st := p.Background.Stats()
+	threshold, auto := p.effectiveThreshold(cfg)
+	mode := "fixed"
+	if auto {
+		mode = fmt.Sprintf("auto @%.2g%% FP", cfg.FalsePositiveRate*100)
+	}
+	label := fmt.Sprintf("Background: n=%d | Threshold %.2f (%s)", st.Samples, threshold, mode)
+	if st.Samples > 0 {
+		label = fmt.Sprintf("Background: %.2f±%.2f p95 %.2f max %.2f (n=%d) | Threshold %.2f (%s)",
+			st.Mean, st.StdDev, st.P95, st.Max, st.Samples, threshold, mode)
+	}
+	if label != p.bgLabel {
+		p.bgLabel = label
+		p.View.SetDetectionStat("background", label)
+	}
+}
+
+// confirmPosition feeds a reel-confirmed position into the spatial prior and
+// persists it in the background. Called with stateMu held, so the file write
+// must not run here.
+func (p *DetectionPresenter) confirmPosition(pt image.Point, bounds image.Rectangle) {
+	if p.Prior == nil {
+		return
+	}
+	p.Prior.Add(pt, bounds)
+	if p.PriorPath == "" {
+		return
+	}
+	go p.savePrior()
+}
+
+// bindPrior ties the spatial prior to the current capture area and template.
+// Its positions are relative to the captured frame, so after the selection
+// moves or the template is replaced they point at the wrong place and are
+// dropped.
+func (p *DetectionPresenter) bindPrior(selection image.Rectangle, hasSelection bool) {
+	if p.Prior == nil {
+		return
+	}
+	if p.priorTarget != p.TargetImg {
+		p.priorTarget, p.priorTargetSum = p.TargetImg, imageChecksum(p.TargetImg)
+	}
+	area := "screen"
+	if hasSelection {
+		area = selection.String()
+	}
+	if p.Prior.Bind(fmt.Sprintf("%s %016x", area, p.priorTargetSum)) {
+		if p.logger != nil {
+			p.logger.Info("spatial prior reset: capture area or template changed", "area", area)
+		}
+		if p.PriorPath != "" {
+			go p.savePrior()
+		}
+	}
+}
+
+// imageChecksum returns an FNV-1a hash of the RGBA pixels of img.
+func imageChecksum(img image.Image) uint64 {
+	h := fnv.New64a()
+	if img == nil {
+		return h.Sum64()
+	}
+	b := img.Bounds()
+	px := make([]byte, 0, 4*b.Dx())
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		px = px[:0]
+		for x := b.Min.X; x < b.Max.X; x++ {
+			r, g, bl, a := img.At(x, y).RGBA()
+			px = append(px, byte(r>>8), byte(g>>8), byte(bl>>8), byte(a>>8))
+		}
+		h.Write(px)
+	}
+	return h.Sum64()
+}
+
+// savePrior writes the spatial prior to PriorPath. Saves are serialised so
+// a slower, older snapshot cannot overwrite a newer one.
+func (p *DetectionPresenter) savePrior() {
+	p.priorSaveMu.Lock()
+	defer p.priorSaveMu.Unlock()
+	if err := p.Prior.Save(p.PriorPath); err != nil && p.logger != nil {
+		p.logger.Error("spatial prior save failed", "error", err)
+	}
+}
+
+// searchTarget returns the template to search for: the profile's adaptive
+// template when enabled, the base template otherwise.
+func (p *DetectionPresenter) searchTarget(cfg *config.Config) image.Image {
+	if !cfg.AdaptiveTemplate || p.TargetImg == nil {
+		return p.TargetImg
+	}
+	p.adaptMu.Lock()
+	defer p.adaptMu.Unlock()
+	return p.adaptiveFor(cfg.Profile).Image()
+}
+
+// adaptivePath returns the template library file of profile's adaptive
+// template, or "" when persistence is disabled.
+func (p *DetectionPresenter) adaptivePath(profile string) string {
+	if p.TemplateDir == "" {
+		return ""
+	}
+	return filepath.Join(p.TemplateDir, "adaptive_"+profile+".png")
+}
+
+// adaptiveFor returns the adaptive template of profile, loading it from the
+// template library on first use. Caller must hold adaptMu.
+func (p *DetectionPresenter) adaptiveFor(profile string) *capture.AdaptiveTemplate {
+	if p.adaptive != nil && p.adaptiveProfile == profile {
+		return p.adaptive
+	}
+	at := capture.NewAdaptiveTemplate(p.TargetImg)
+	if path := p.adaptivePath(profile); path != "" {
+		loaded, err := capture.LoadAdaptiveTemplate(path, p.TargetImg)
+		if err != nil && p.logger != nil {
+			p.logger.Warn("adaptive template load failed; starting from base", "path", path, "error", err)
+		}
+		at = loaded
+	}
+	p.adaptive, p.adaptiveProfile = at, profile
+	return at
+}
+
+// adaptTemplate blends a reel-confirmed patch into the profile's adaptive
+// template and schedules a save. Called with stateMu held on the FSM
+// goroutine, so the outcome is handed to the UI through adaptMsg.
+func (p *DetectionPresenter) adaptTemplate(sample adaptSample) {
+	if p.TargetImg == nil {
+		return
+	}
+	p.adaptMu.Lock()
+	defer p.adaptMu.Unlock()
+	at := p.adaptiveFor(sample.profile)
+	var msg string
+	if err := at.Update(sample.patch, sample.rate, sample.minScore); err != nil {
+		msg = fmt.Sprintf("Adaptive: %d updates (skipped: %s)", at.Updates(), adaptSkipReason(err))
+		if p.logger != nil {
+			p.logger.Debug("adaptive template update skipped", "error", err)
+		}
+	} else {
+		msg = fmt.Sprintf("Adaptive: %d updates", at.Updates())
+		if path := p.adaptivePath(sample.profile); path != "" {
+			p.scheduleAdaptiveSave(path, at)
+		}
+	}
+	p.adaptMsg.Store(&msg)
+}
+
+// scheduleAdaptiveSave queues at to be written to path adaptiveSaveDelay
+// after the first queued update. Caller must hold adaptMu.
+func (p *DetectionPresenter) scheduleAdaptiveSave(path string, at *capture.AdaptiveTemplate) {
+	if p.adaptPending == nil {
+		p.adaptPending = make(map[string]*capture.AdaptiveTemplate)
+	}
+	p.adaptPending[path] = at
+	if p.adaptTimer == nil {
+		p.adaptTimer = time.AfterFunc(adaptiveSaveDelay, p.flushAdaptive)
+	}
+}
+
+// flushAdaptive writes the queued adaptive templates.
+func (p *DetectionPresenter) flushAdaptive() {
+	p.adaptSaveMu.Lock()
+	defer p.adaptSaveMu.Unlock()
+	p.adaptMu.Lock()
+	pending := p.adaptPending
+	p.adaptPending = nil
+	if p.adaptTimer != nil {
+		p.adaptTimer.Stop()
+		p.adaptTimer = nil
+	}
+	p.adaptMu.Unlock()
+	for path, at := range pending {
+		if err := saveAdaptive(at, path); err != nil && p.logger != nil {
+			p.logger.Error("adaptive template save failed", "path", path, "error", err)
+		}
+	}
+}
+
+// Close writes pending adaptive template updates and waits for a running
+// spatial prior save. Call it once detection has stopped, before exiting.
+func (p *DetectionPresenter) Close() {
+	if p == nil {
+		return
+	}
+	p.flushAdaptive()
+	p.priorSaveMu.Lock()
+	p.priorSaveMu.Unlock()
+}
+
+func saveAdaptive(at *capture.AdaptiveTemplate, path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return at.Save(path)
+}
+
+// adaptSkipReason renders an adaptive template update error for the UI.
+func adaptSkipReason(err error) string {
+	switch {
+	case errors.Is(err, capture.ErrAdaptLowScore):
+		return "low score"
+	case errors.Is(err, capture.ErrAdaptDrift):
+		return "drift guard"
+	default:
+		return "error"
+	}
+}
+
+// ResetAdaptiveTemplate discards the adaptation of the configured profile and
+// deletes its saved template.
+func (p *DetectionPresenter) ResetAdaptiveTemplate() {
+	if p == nil || p.TargetImg == nil {
+		return
+	}
+	profile := p.copyConfig().Profile
+	p.adaptSaveMu.Lock()
+	p.adaptMu.Lock()
+	p.adaptiveFor(profile).Reset()
+	path := p.adaptivePath(profile)
+	delete(p.adaptPending, path)
+	p.adaptMu.Unlock()
+	if path != "" {
+		if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) && p.logger != nil {
+			p.logger.Error("adaptive template delete failed", "path", path, "error", err)
+		}
+	}
+	p.adaptSaveMu.Unlock()
+	if p.logger != nil {
+		p.logger.Info("adaptive template reset", "profile", profile)
+	}
+	if p.View != nil {
+		p.View.SetDetectionStat("adaptive", "Adaptive: reset")
+	}
+}
+
+// SaveHeatmap computes the score map of the latest frame at the most
+// recent winning scale and writes a false-colour overlay PNG to the working
+// directory. The work runs in the background; the outcome is shown in the
+// detection stats on a later ProcessFrame.
+func (p *DetectionPresenter) SaveHeatmap() {
+	if p == nil || p.Source == nil || p.View == nil {
+		return
+	}
+	snapshot := p.Source.LatestFrame()
+	if snapshot.Image == nil || p.TargetImg == nil {
+		p.View.SetDetectionStat("heatmap", "Heatmap: no frame")
+		return
+	}
+	scale := p.lastScale
+	if scale <= 0 {
+		scale = 1
+	}
+	cfg := p.copyConfig()
+	target := p.preprocessed(cfg, p.searchTarget(cfg))
+	path := fmt.Sprintf("pixel_bot_heatmap_%s.png", time.Now().Format("20060102_150405"))
+	p.View.SetDetectionStat("heatmap", "Heatmap: computing...")
+	go func() {
+		msg := "Heatmap: " + path
+		frame, _ := preprocess.New(cfg.Preprocess).Apply(snapshot.Image)
+		if err := writeHeatmap(path, frame, target, scale, cfg); err != nil {
+			msg = "Heatmap: failed"
+			if p.logger != nil {
+				p.logger.Error("heatmap export failed", "error", err)
+			}
+		} else if p.logger != nil {
+			p.logger.Info("heatmap saved", "path", path, "scale", scale)
 		}
+		p.heatmapMsg.Store(&msg)
+	}()
+}
+
+// writeHeatmap renders the score map of target on frame in cfg's match mode
+// (downscaled like the search when AnalysisScale < 1) and writes it to path.
+func writeHeatmap(path string, frame *image.RGBA, target image.Image, scale float64, cfg *config.Config) error {
+	frame = analysisFrame(frame, cfg.AnalysisScale)
+	m, err := capture.ComputeScoreMap(frame, target, scale, capture.MatchModeFromConfig(cfg.MatchMode))
+	if err != nil {
+		return err
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := capture.WriteHeatmapPNG(f, frame, m); err != nil {
+		f.Close()
+		return err
 	}
+	return f.Close()
+}
+
+// formatSeconds renders d as seconds with one decimal place.
+func formatSeconds(d time.Duration) string {
+	return fmt.Sprintf("%.1fs", d.Seconds())
 }
 
 func (p *DetectionPresenter) copyConfig() *config.Config {
@@ -360,5 +1385,7 @@ func (p *DetectionPresenter) copyConfig() *config.Config {
 		return config.DefaultConfig()
 	}
 	clone := *p.Config
+	clone.BlobColors = slices.Clone(p.Config.BlobColors)
+	clone.Preprocess = slices.Clone(p.Config.Preprocess)
 	return &clone
 }
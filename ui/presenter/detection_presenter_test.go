@@ -0,0 +1,174 @@
+package presenter
+
+import (
+	"context"
+	"errors"
+	"image"
+	"image/color"
+	"os"
+	"testing"
+
+	"github.com/soocke/pixel-bot-go/config"
+	"github.com/soocke/pixel-bot-go/domain/capture"
+)
+
+// discFrame returns a blue frame with a red disc of radius r at c.
+func discFrame(w, h int, c image.Point, r int) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			px := color.RGBA{20, 50, 90, 255}
+			if dx, dy := x-c.X, y-c.Y; dx*dx+dy*dy <= r*r {
+				px = color.RGBA{200, 30, 30, 255}
+			}
+			img.SetRGBA(x, y, px)
+		}
+	}
+	return img
+}
+
+func TestDetectionPresenter_ReusesTargetDetector(t *testing.T) {
+	p := &DetectionPresenter{}
+	target := image.NewRGBA(image.Rect(0, 0, 21, 21))
+	cfg := config.DefaultConfig()
+	cfg.Detector = config.DetectorKeypoint
+	first, err := p.targetDetector(cfg, target)
+	if err != nil {
+		t.Fatalf("build detector: %v", err)
+	}
+	// Every search task carries its own copy of the settings.
+	same := *cfg
+	if d, _ := p.targetDetector(&same, target); d != first {
+		t.Fatalf("detector rebuilt for unchanged settings")
+	}
+	// The learned threshold and the adaptive scale range vary per search.
+	tuned := same
+	tuned.Threshold, tuned.StopOnScore = 0.91, 0.95
+	if d, _ := p.targetDetector(&tuned, target); d != first {
+		t.Fatalf("detector rebuilt for a per-search threshold")
+	}
+	changed := same
+	changed.Preprocess = []config.FilterStage{{Filter: config.FilterEqualize}}
+	second, _ := p.targetDetector(&changed, target)
+	if second == first {
+		t.Fatalf("detector reused after the preprocessing changed")
+	}
+	adapted := image.NewRGBA(target.Bounds())
+	if d, _ := p.targetDetector(&changed, adapted); d == second {
+		t.Fatalf("detector reused after the search template changed")
+	}
+}
+
+func TestDetectionPresenter_HSVAcquiresBlobCentre(t *testing.T) {
+	p := &DetectionPresenter{}
+	centre := image.Pt(70, 45)
+	frame := discFrame(160, 120, centre, 8)
+	cfg := config.DefaultConfig()
+	cfg.Detector = config.DetectorHSV
+	task := detectionTask{
+		ctx:          context.Background(),
+		snapshot:     capture.FrameSnapshot{Image: frame},
+		cfg:          cfg,
+		target:       image.NewRGBA(image.Rect(0, 0, 17, 17)),
+		selection:    image.Rect(300, 200, 460, 320),
+		hasSelection: true,
+	}
+	res := p.doSearch(task, frame, cfg)
+	if res.err != nil || !res.found {
+		t.Fatalf("hsv search: found=%v err=%v", res.found, res.err)
+	}
+	if res.local != centre {
+		t.Fatalf("acquired at %v, want the blob centre %v", res.local, centre)
+	}
+	if want := centre.Add(task.selection.Min); res.location != want {
+		t.Fatalf("click location %v, want %v", res.location, want)
+	}
+}
+
+// frameSource serves a fixed frame.
+type frameSource struct{ frame *image.RGBA }
+
+func (s frameSource) Running() bool { return true }
+func (s frameSource) LatestFrame() capture.FrameSnapshot {
+	return capture.FrameSnapshot{Image: s.frame}
+}
+
+func TestDetectionPresenter_LearnBlobColorNeedsHSVDetector(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Detector = config.DetectorNCC
+	before := cfg.BlobColors
+	p := &DetectionPresenter{Source: frameSource{discFrame(64, 64, image.Pt(32, 32), 8)}, Config: cfg}
+	if p.LearnBlobColor(image.Pt(32, 32), false) || len(cfg.BlobColors) != len(before) || cfg.BlobColors[0] != before[0] {
+		t.Fatalf("colour sampled while the ncc detector is selected")
+	}
+	cfg.Detector = config.DetectorHSV
+	if !p.LearnBlobColor(image.Pt(32, 32), false) {
+		t.Fatalf("colour not sampled with the hsv detector selected")
+	}
+}
+
+func TestDetectionPresenter_DefersAdaptiveTemplateSave(t *testing.T) {
+	target := discFrame(21, 21, image.Pt(10, 10), 8)
+	p := &DetectionPresenter{TargetImg: target, TemplateDir: t.TempDir()}
+	path := p.adaptivePath("default")
+	for i := 0; i < 3; i++ {
+		p.adaptTemplate(adaptSample{patch: target, rate: 0.1, minScore: 0.5, profile: "default"})
+	}
+	if _, err := os.Stat(path); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("adaptive template written on update: %v", err)
+	}
+	p.Close()
+	if _, err := os.Stat(path); err != nil {
+		t.Fatalf("adaptive template not written on close: %v", err)
+	}
+}
+
+// statView discards detection output.
+type statView struct{}
+
+func (statView) UpdateCapture(image.Image)       {}
+func (statView) UpdateDetection(image.Image)     {}
+func (statView) SetDetectionStat(string, string) {}
+
+func TestDetectionPresenter_HSVSearchesRawFrames(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Detector = config.DetectorHSV
+	cfg.Preprocess = []config.FilterStage{{Filter: config.FilterChannel, Channel: "g"}}
+	frame := discFrame(160, 120, image.Pt(70, 45), 8)
+	p := NewDetectionPresenter(nil, nil, nil, nil, statView{}, cfg, image.NewRGBA(image.Rect(0, 0, 17, 17)), nil, nil)
+	task := detectionTask{ctx: context.Background(), snapshot: capture.FrameSnapshot{Image: frame}, cfg: p.searchConfig()}
+	task.target = p.preprocessed(task.cfg, p.TargetImg)
+	if res := p.doSearch(task, frame, task.cfg); !res.found || res.local != image.Pt(70, 45) {
+		t.Fatalf("hsv search with a channel filter: found=%v at %v (err %v)", res.found, res.local, res.err)
+	}
+	cfg.Detector = config.DetectorNCC
+	if got := p.searchConfig().Preprocess; len(got) != 1 {
+		t.Fatalf("ncc search lost its preprocessing: %v", got)
+	}
+}
+
+func TestDetectionPresenter_PriorFollowsSelectionAndTemplate(t *testing.T) {
+	bounds := image.Rect(0, 0, 160, 120)
+	p := &DetectionPresenter{Prior: capture.NewSpatialPrior(0), TargetImg: discFrame(17, 17, image.Pt(8, 8), 6)}
+	sel := image.Rect(300, 200, 460, 320)
+	p.bindPrior(sel, true)
+	for i := 0; i < 6; i++ {
+		p.Prior.Add(image.Pt(70, 45), bounds)
+	}
+	p.bindPrior(sel, true)
+	if p.Prior.Samples() != 6 {
+		t.Fatalf("prior reset without a change")
+	}
+	p.bindPrior(sel.Add(image.Pt(200, 0)), true)
+	if p.Prior.Samples() != 0 {
+		t.Fatalf("prior kept after the selection moved")
+	}
+	for i := 0; i < 6; i++ {
+		p.Prior.Add(image.Pt(70, 45), bounds)
+	}
+	p.TargetImg = discFrame(17, 17, image.Pt(8, 8), 4)
+	p.bindPrior(sel.Add(image.Pt(200, 0)), true)
+	if p.Prior.Samples() != 0 {
+		t.Fatalf("prior kept after the template changed")
+	}
+}
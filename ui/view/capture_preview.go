@@ -2,6 +2,7 @@ package view
 
 import (
 	"image"
+	"strconv"
 
 	"github.com/soocke/pixel-bot-go/ui/images"
 
@@ -15,6 +16,13 @@ type CapturePreview interface {
 	UpdateCapture(img image.Image)
 	UpdateDetection(img image.Image)
 	Reset()
+	// OnSample registers fn for ctrl-clicks on the capture preview. pt is in
+	// the coordinates of the last image passed to UpdateCapture; add reports
+	// a ctrl-shift-click.
+	OnSample(fn func(pt image.Point, add bool))
+	// OnReject registers fn for right-clicks on the capture preview, in the
+	// same coordinates as OnSample; remove reports a shift-right-click.
+	OnReject(fn func(pt image.Point, remove bool))
 }
 
 type capturePreview struct {
@@ -22,8 +30,10 @@ type capturePreview struct {
 	detectionLabel     *LabelWidget
 	targetW            int
 	targetH            int
-	prevCapturePhoto   *Img // last Tk photo image instance for capture
-	prevDetectionPhoto *Img // last Tk photo image instance for detection
+	prevCapturePhoto   *Img            // last Tk photo image instance for capture
+	prevDetectionPhoto *Img            // last Tk photo image instance for detection
+	src                image.Rectangle // bounds of the last captured image
+	dispW, dispH       int             // displayed (scaled) size of that image
 }
 
 // Internal state tracks current preview photos so we can dispose old images
@@ -61,6 +71,8 @@ func (v *capturePreview) UpdateCapture(img image.Image) {
 	}
 	// Scale for display only; allocate a fresh scaled image each call.
 	scaled := images.ScaleToFit(img, w, h)
+	v.src = img.Bounds()
+	v.dispW, v.dispH = scaled.Bounds().Dx(), scaled.Bounds().Dy()
 	pngBytes := images.EncodePNG(scaled)
 	// Replace previous photo to avoid retaining obsolete pixel buffers.
 	if v.prevCapturePhoto != nil {
@@ -116,3 +128,49 @@ func (v *capturePreview) setTargetSize(w, h int) {
 	}
 	v.targetW, v.targetH = w, h
 }
+
+func (v *capturePreview) OnSample(fn func(pt image.Point, add bool)) {
+	if v == nil || v.captureLabel == nil || fn == nil {
+		return
+	}
+	handler := func(add bool) func(*Event) {
+		return func(e *Event) {
+			if pt, ok := v.toSource(e.X, e.Y); ok {
+				fn(pt, add)
+			}
+		}
+	}
+	Bind(v.captureLabel, "<Control-Button-1>", Command(handler(false)))
+	Bind(v.captureLabel, "<Control-Shift-Button-1>", Command(handler(true)))
+}
+
+func (v *capturePreview) OnReject(fn func(pt image.Point, remove bool)) {
+	if v == nil || v.captureLabel == nil || fn == nil {
+		return
+	}
+	handler := func(remove bool) func(*Event) {
+		return func(e *Event) {
+			if pt, ok := v.toSource(e.X, e.Y); ok {
+				fn(pt, remove)
+			}
+		}
+	}
+	Bind(v.captureLabel, "<Button-3>", Command(handler(false)))
+	Bind(v.captureLabel, "<Shift-Button-3>", Command(handler(true)))
+}
+
+// toSource maps a click on the capture label to source image coordinates.
+// The label centres the scaled image, so any surplus label area is ignored.
+func (v *capturePreview) toSource(x, y int) (image.Point, bool) {
+	if v.dispW <= 0 || v.dispH <= 0 || v.src.Empty() {
+		return image.Point{}, false
+	}
+	lw, _ := strconv.Atoi(WinfoWidth(v.captureLabel.Window))
+	lh, _ := strconv.Atoi(WinfoHeight(v.captureLabel.Window))
+	x -= max(0, (lw-v.dispW)/2)
+	y -= max(0, (lh-v.dispH)/2)
+	if x < 0 || y < 0 || x >= v.dispW || y >= v.dispH {
+		return image.Point{}, false
+	}
+	return image.Pt(v.src.Min.X+x*v.src.Dx()/v.dispW, v.src.Min.Y+y*v.src.Dy()/v.dispH), true
+}
@@ -57,7 +57,10 @@ func (v *configPanel) Build(startRow int, parent ...Widget) (row int) {
 	makeRow("minScale", "Min Scale", fmt.Sprintf("%.2f", c.MinScale))
 	makeRow("maxScale", "Max Scale", fmt.Sprintf("%.2f", c.MaxScale))
 	makeRow("scaleStep", "Scale Step", fmt.Sprintf("%.3f", c.ScaleStep))
+	makeRow("adaptiveScale", "Adaptive Scale (true/false)", fmt.Sprintf("%t", c.AdaptiveScale))
 	makeRow("threshold", "Threshold", fmt.Sprintf("%.3f", c.Threshold))
+	makeRow("autoThreshold", "Auto Threshold (true/false)", fmt.Sprintf("%t", c.AutoThreshold))
+	makeRow("falsePositiveRate", "False Positive Rate", fmt.Sprintf("%.4f", c.FalsePositiveRate))
 	makeRow("stride", "Stride", fmt.Sprintf("%d", c.Stride))
 	makeRow("stopOnScore", "Stop On Score", fmt.Sprintf("%.3f", c.StopOnScore))
 	makeRow("refine", "Refine (true/false)", fmt.Sprintf("%t", c.Refine))
@@ -66,7 +69,28 @@ func (v *configPanel) Build(startRow int, parent ...Widget) (row int) {
 	makeRow("roiSizePx", "ROI Size Px", fmt.Sprintf("%d", c.ROISizePx))
 	makeRow("cooldownSeconds", "Cooldown Seconds", fmt.Sprintf("%d", c.CooldownSeconds))
 	makeRow("maxCastDurationSeconds", "Max Cast Duration Seconds", fmt.Sprintf("%d", c.MaxCastDurationSeconds))
+	makeRow("biteDetector", "Bite Detector (motion/displacement/splash/periodic/ssim/model/ensemble)", c.BiteDetector)
+	makeRow("biteModel", "Bite Model File (model detector)", c.BiteModel)
+	makeRow("ensembleMembers", "Ensemble Members (e.g. motion, splash)", strings.Join(c.Ensemble.Members, ", "))
+	makeRow("ensembleWeights", "Ensemble Weights (e.g. 1, 2)", formatFloats(c.Ensemble.Weights))
+	makeRow("ensembleStrategy", "Ensemble Strategy (any/majority/weighted/window)", c.Ensemble.Strategy)
+	makeRow("ensembleThreshold", "Ensemble Weight Threshold (0-1)", fmt.Sprintf("%.2f", c.Ensemble.Threshold))
+	makeRow("ensembleWindowFrames", "Ensemble Window Frames", fmt.Sprintf("%d", c.Ensemble.WindowFrames))
+	makeRow("ensembleMinVotes", "Ensemble Min Votes", fmt.Sprintf("%d", c.Ensemble.MinVotes))
+	makeRow("bitePreset", "Bite Preset (calm/choppy/night/custom or saved name)", c.BitePreset)
+	for _, f := range biteFields(&c.Bite) {
+		makeRow(f.id, f.label, "")
+	}
+	v.showBite()
+	makeRow("detector", "Target Detector (ncc/hsv/orb)", c.Detector)
+	makeRow("matchMode", "Match Mode (luma/gradient)", c.MatchMode)
+	makeRow("preprocess", "Preprocess (e.g. blur:1, clahe:2)", config.FormatStages(c.Preprocess))
 	makeRow("analysisScale", "Analysis Scale (0.2-1.0)", fmt.Sprintf("%.2f", c.AnalysisScale))
+	makeRow("splashSearch", "Splash Search (off/restrict/acquire)", c.SplashSearch)
+	makeRow("splashSettleMs", "Splash Settle Ms", fmt.Sprintf("%d", c.SplashSettleMs))
+	makeRow("spatialPrior", "Spatial Prior (true/false)", fmt.Sprintf("%t", c.SpatialPrior))
+	makeRow("adaptiveTemplate", "Adaptive Template (true/false)", fmt.Sprintf("%t", c.AdaptiveTemplate))
+	makeRow("profile", "Profile", c.Profile)
 	v.applyBtn = Button(Txt("Apply Changes"), Background(pal.Primary), Foreground("white"), Relief("raised"), Borderwidth(1), Command(func() { v.ApplyChanges() }))
 	Grid(v.applyBtn, In(target), Row(row), Column(0), Columnspan(2), Sticky("we"), Padx("0.4m"), Pady("0.3m"))
 	row++
@@ -131,24 +155,98 @@ func (v *configPanel) ApplyChanges() {
 	assignFloat("minScale", &cfg.MinScale)
 	assignFloat("maxScale", &cfg.MaxScale)
 	assignFloat("scaleStep", &cfg.ScaleStep)
+	assignBool("adaptiveScale", &cfg.AdaptiveScale)
 	assignFloat("threshold", &cfg.Threshold)
+	assignBool("autoThreshold", &cfg.AutoThreshold)
+	assignFloat("falsePositiveRate", &cfg.FalsePositiveRate)
 	assignInt("stride", &cfg.Stride)
 	assignFloat("stopOnScore", &cfg.StopOnScore)
 	assignBool("refine", &cfg.Refine)
 	assignBool("returnBestEven", &cfg.ReturnBestEven)
 	assignInt("cooldownSeconds", &cfg.CooldownSeconds)
 	assignInt("maxCastDurationSeconds", &cfg.MaxCastDurationSeconds)
+	applyBite(&cfg, func(id string) (string, bool) {
+		w := v.widgets[id]
+		if w == nil {
+			return "", false
+		}
+		return v.text(w), true
+	})
+	assignFloat("ensembleThreshold", &cfg.Ensemble.Threshold)
+	assignInt("ensembleWindowFrames", &cfg.Ensemble.WindowFrames)
+	assignInt("ensembleMinVotes", &cfg.Ensemble.MinVotes)
 	assignFloat("analysisScale", &cfg.AnalysisScale)
+	assignInt("splashSettleMs", &cfg.SplashSettleMs)
+	assignBool("spatialPrior", &cfg.SpatialPrior)
+	assignBool("adaptiveTemplate", &cfg.AdaptiveTemplate)
 	if w := v.widgets["reelKey"]; w != nil {
 		val := strings.TrimSpace(v.text(w))
 		if val != "" {
 			cfg.ReelKey = val
 		}
 	}
+	if w := v.widgets["detector"]; w != nil {
+		if val := strings.ToLower(strings.TrimSpace(v.text(w))); val != "" {
+			cfg.Detector = val
+		}
+	}
+	if w := v.widgets["biteDetector"]; w != nil {
+		if val := strings.ToLower(strings.TrimSpace(v.text(w))); val != "" {
+			cfg.BiteDetector = val
+		}
+	}
+	if w := v.widgets["biteModel"]; w != nil {
+		if val := strings.TrimSpace(v.text(w)); val != "" {
+			cfg.BiteModel = val
+		}
+	}
+	if w := v.widgets["ensembleMembers"]; w != nil {
+		cfg.Ensemble.Members = splitList(v.text(w))
+	}
+	if w := v.widgets["ensembleWeights"]; w != nil {
+		var weights []float64
+		for _, s := range splitList(v.text(w)) {
+			if f, ok := parseFloatField(s); ok {
+				weights = append(weights, f)
+			}
+		}
+		cfg.Ensemble.Weights = weights
+	}
+	if w := v.widgets["ensembleStrategy"]; w != nil {
+		if val := strings.ToLower(strings.TrimSpace(v.text(w))); val != "" {
+			cfg.Ensemble.Strategy = val
+		}
+	}
+	if w := v.widgets["matchMode"]; w != nil {
+		if val := strings.ToLower(strings.TrimSpace(v.text(w))); val != "" {
+			cfg.MatchMode = val
+		}
+	}
+	if w := v.widgets["preprocess"]; w != nil {
+		stages, err := config.ParseStages(strings.TrimSpace(v.text(w)))
+		if err != nil {
+			if v.logger != nil {
+				v.logger.Warn("preprocess filters not applied", "error", err)
+			}
+		} else {
+			cfg.Preprocess = stages
+		}
+	}
+	if w := v.widgets["profile"]; w != nil {
+		if val := strings.TrimSpace(v.text(w)); val != "" {
+			cfg.Profile = val
+		}
+	}
+	if w := v.widgets["splashSearch"]; w != nil {
+		if val := strings.ToLower(strings.TrimSpace(v.text(w))); val != "" {
+			cfg.SplashSearch = val
+		}
+	}
 	if verr := cfg.Validate(); verr != nil {
 		return
 	}
 	*v.cfg = cfg
+	v.showBite()
 	if err := v.cfg.Save(v.cfgPath); err != nil {
 		if v.logger != nil {
 			v.logger.Error("config save failed", "error", err)
@@ -160,6 +258,102 @@ func (v *configPanel) ApplyChanges() {
 	}
 }
 
+// biteField is one editable bite detector parameter; exactly one of i and f
+// points into the BiteParams it was built from.
+type biteField struct {
+	id, label string
+	i         *int
+	f         *float64
+}
+
+func biteFields(p *config.BiteParams) []biteField {
+	return []biteField{
+		{id: "biteWindowMs", label: "Bite Window (ms)", i: &p.WindowMs},
+		{id: "biteMinStatsMs", label: "Bite Min Stats Span (ms)", i: &p.MinStatsMs},
+		{id: "bitePixelRate", label: "Bite Pixel Change Rate (/s)", f: &p.PixelRateThreshold},
+		{id: "biteRatioSpike", label: "Bite Spike Changed Ratio", f: &p.RatioThresholdSpike},
+		{id: "biteRatioBase", label: "Bite Baseline Changed Ratio", f: &p.RatioThresholdBase},
+		{id: "biteBaselineDiff", label: "Bite Baseline Diff Threshold", f: &p.BaselineDiffThresh},
+		{id: "biteStdDev", label: "Bite Std Dev Multiplier", f: &p.StdDevMultiplier},
+		{id: "biteBigRatio", label: "Bite Immediate Changed Ratio", f: &p.BigImmediateRatio},
+		{id: "biteBigRate", label: "Bite Immediate Change Rate (/s)", f: &p.BigImmediateRate},
+		{id: "biteEmaMs", label: "Bite Background Time Constant (ms)", i: &p.EMATimeConstantMs},
+		{id: "biteDebounceMs", label: "Bite Debounce (ms)", i: &p.DebounceMs},
+	}
+}
+
+// text formats the parameter for its row. Floats use the shortest exact
+// form, so an unedited row parses back to the same value.
+func (f biteField) text() string {
+	if f.i != nil {
+		return strconv.Itoa(*f.i)
+	}
+	return strconv.FormatFloat(*f.f, 'g', -1, 64)
+}
+
+// applyBite reads the bite preset and parameter rows through text, which
+// reports false for rows that are not shown, into cfg. Editing a parameter
+// without choosing another preset makes it custom; choosing a named preset
+// replaces the parameters in Validate.
+func applyBite(cfg *config.Config, text func(id string) (string, bool)) {
+	preset, bite := cfg.BitePreset, cfg.Bite
+	for _, f := range biteFields(&cfg.Bite) {
+		s, ok := text(f.id)
+		if !ok {
+			continue
+		}
+		if f.i != nil {
+			if i, ok := parseIntField(s); ok {
+				*f.i = i
+			}
+		} else if x, ok := parseFloatField(s); ok {
+			*f.f = x
+		}
+	}
+	if s, ok := text("bitePreset"); ok {
+		if val := strings.ToLower(strings.TrimSpace(s)); val != "" {
+			cfg.BitePreset = val
+		}
+	}
+	if cfg.BitePreset == preset && cfg.Bite != bite {
+		cfg.BitePreset = config.BitePresetCustom
+	}
+}
+
+// showBite writes the current bite preset and parameters into their rows, so
+// the rows follow a newly chosen preset.
+func (v *configPanel) showBite() {
+	set := func(id, value string) {
+		if w := v.widgets[id]; w != nil {
+			w.Delete("1.0", END)
+			w.Insert("1.0", value)
+		}
+	}
+	set("bitePreset", v.cfg.BitePreset)
+	for _, f := range biteFields(&v.cfg.Bite) {
+		set(f.id, f.text())
+	}
+}
+
+// splitList splits a comma separated list, dropping empty entries.
+func splitList(s string) []string {
+	var out []string
+	for _, part := range strings.Split(s, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			out = append(out, part)
+		}
+	}
+	return out
+}
+
+func formatFloats(vals []float64) string {
+	parts := make([]string, len(vals))
+	for i, v := range vals {
+		parts[i] = strconv.FormatFloat(v, 'g', 3, 64)
+	}
+	return strings.Join(parts, ", ")
+}
+
 func parseFloatField(s string) (float64, bool) {
 	f, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
 	if err != nil {
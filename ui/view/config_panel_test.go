@@ -0,0 +1,46 @@
+package view
+
+import (
+	"testing"
+
+	"github.com/soocke/pixel-bot-go/config"
+)
+
+// shownBite returns the rows showBite would display for cfg, as read back
+// from Tk text widgets.
+func shownBite(cfg *config.Config) map[string]string {
+	rows := map[string]string{"bitePreset": cfg.BitePreset + "\n"}
+	for _, f := range biteFields(&cfg.Bite) {
+		rows[f.id] = f.text() + "\n"
+	}
+	return rows
+}
+
+func TestApplyBite_KeepsTunedPresetWithoutEdits(t *testing.T) {
+	cfg := config.DefaultConfig()
+	tuned := cfg.Bite
+	tuned.PixelRateThreshold = 123.456789
+	tuned.RatioThresholdSpike = 0.0123456789
+	tuned.StdDevMultiplier = 2.7182818
+	cfg.BitePresets = map[string]config.BiteParams{"lake": tuned}
+	cfg.BitePreset = "lake"
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("validate: %v", err)
+	}
+	want := cfg.Bite
+	rows := shownBite(cfg)
+	text := func(id string) (string, bool) {
+		s, ok := rows[id]
+		return s, ok
+	}
+	applyBite(cfg, text)
+	if cfg.BitePreset != "lake" || cfg.Bite != want {
+		t.Fatalf("apply without edits: preset %q, bite %+v, want lake %+v", cfg.BitePreset, cfg.Bite, want)
+	}
+
+	rows["biteStdDev"] = "3\n"
+	applyBite(cfg, text)
+	if cfg.BitePreset != config.BitePresetCustom || cfg.Bite.StdDevMultiplier != 3 {
+		t.Fatalf("edited parameter: preset %q, std dev %v", cfg.BitePreset, cfg.Bite.StdDevMultiplier)
+	}
+}
@@ -0,0 +1,58 @@
+package view
+
+import (
+	"github.com/soocke/pixel-bot-go/ui/theme"
+
+	//lint:ignore ST1001 Dot import for concise Tk widget DSL.
+	. "modernc.org/tk9.0"
+)
+
+// DetectionStats shows keyed detection diagnostics (one label per key) in a row.
+type DetectionStats interface {
+	SetStat(key, text string)
+	ApplyPalette()
+}
+
+type detectionStats struct {
+	parent *FrameWidget
+	row    int
+	col    int
+	labels map[string]*LabelWidget
+}
+
+// NewDetectionStats creates an empty stats row inside parent. Labels are added
+// lazily from column startCol onwards in the order their keys are first set.
+func NewDetectionStats(parent *FrameWidget, row, startCol int) DetectionStats {
+	return &detectionStats{parent: parent, row: row, col: startCol, labels: make(map[string]*LabelWidget)}
+}
+
+// SetStat updates (or creates) the label identified by key.
+func (s *detectionStats) SetStat(key, text string) {
+	if s == nil {
+		return
+	}
+	lbl := s.labels[key]
+	if lbl == nil {
+		pal := theme.CurrentPalette()
+		lbl = Label(Anchor("w"), Background(pal.Surface), Foreground(pal.TextMuted))
+		if s.parent != nil {
+			Grid(lbl, In(s.parent), Row(s.row), Column(s.col), Sticky("w"), Padx("0.4m"), Pady("0.2m"))
+		} else {
+			Grid(lbl, Row(s.row), Column(s.col), Sticky("w"), Padx("0.4m"), Pady("0.2m"))
+		}
+		s.col++
+		s.labels[key] = lbl
+	}
+	lbl.Configure(Txt(text))
+}
+
+// ApplyPalette recolours existing labels after a theme change.
+func (s *detectionStats) ApplyPalette() {
+	if s == nil {
+		return
+	}
+	pal := theme.CurrentPalette()
+	for _, lbl := range s.labels {
+		lbl.Configure(Background(pal.Surface), Foreground(pal.TextMuted))
+	}
+}
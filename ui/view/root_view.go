@@ -23,6 +23,7 @@ type RootView struct {
 	logger  *slog.Logger
 
 	Session          SessionStats
+	DetectionStats   DetectionStats
 	ConfigPanel      ConfigPanel
 	CapturePrev      CapturePreview
 	StateLabel       *TLabelWidget
@@ -33,6 +34,8 @@ type RootView struct {
 	detectionLabel   *LabelWidget
 	captureBtn       *ButtonWidget
 	selectionBtn     *ButtonWidget
+	heatmapBtn       *ButtonWidget
+	resetTmplBtn     *ButtonWidget
 	exitBtn          *ButtonWidget
 	captureRow       int
 	configFrame      *FrameWidget
@@ -46,6 +49,9 @@ type RootView struct {
 	scaleBound       bool
 	darkMode         bool
 	darkToggleBtn    *ButtonWidget
+	processedBtn     *ButtonWidget
+	showProcessed    bool
+	onProcessed      func(on bool)
 }
 
 // UI abstracts view operations needed by presenters.
@@ -55,6 +61,7 @@ type UI interface {
 	UpdateCapture(img image.Image)
 	UpdateDetection(img image.Image)
 	SetSession(session, total time.Duration)
+	SetDetectionStat(key, text string)
 }
 
 func NewRootView(cfg *config.Config, cfgPath string, logger *slog.Logger) *RootView {
@@ -62,7 +69,7 @@ func NewRootView(cfg *config.Config, cfgPath string, logger *slog.Logger) *RootV
 }
 
 // Build constructs the layout with window titles for selection dropdown.
-func (rv *RootView) Build(titles []string, onToggleCapture func(), onSelectionGrid func(), onExit func(), onWindowChanged func(title string)) {
+func (rv *RootView) Build(titles []string, onToggleCapture func(), onSelectionGrid func(), onSaveHeatmap func(), onResetTemplate func(), onExit func(), onWindowChanged func(title string)) {
 	if rv == nil {
 		return
 	}
@@ -101,6 +108,8 @@ func (rv *RootView) Build(titles []string, onToggleCapture func(), onSelectionGr
 	GridColumnConfigure(rv.actionsFrame, 2, Weight(0))
 	GridColumnConfigure(rv.actionsFrame, 3, Weight(0))
 	GridColumnConfigure(rv.actionsFrame, 4, Weight(0))
+	GridColumnConfigure(rv.actionsFrame, 5, Weight(0))
+	GridColumnConfigure(rv.actionsFrame, 6, Weight(0))
 
 	rv.StateLabel = TLabel(Txt("State: <none>"))
 	Grid(rv.StateLabel, In(rv.headerFrame), Row(0), Column(2), Sticky("e"), Padx("0.3m"))
@@ -128,8 +137,12 @@ func (rv *RootView) Build(titles []string, onToggleCapture func(), onSelectionGr
 	Grid(rv.captureBtn, In(rv.actionsFrame), Row(0), Column(2), Sticky("we"), Padx("0.2m"), Pady("0.2m"))
 	rv.selectionBtn = Button(Txt("Selection"), Background(pal.Primary), Foreground("white"), Relief("raised"), Borderwidth(1), Command(onSelectionGrid))
 	Grid(rv.selectionBtn, In(rv.actionsFrame), Row(0), Column(3), Sticky("we"), Padx("0.2m"), Pady("0.2m"))
+	rv.heatmapBtn = Button(Txt("Save Detection Heatmap"), Background(pal.Primary), Foreground("white"), Relief("raised"), Borderwidth(1), Command(onSaveHeatmap))
+	Grid(rv.heatmapBtn, In(rv.actionsFrame), Row(0), Column(4), Sticky("we"), Padx("0.2m"), Pady("0.2m"))
+	rv.resetTmplBtn = Button(Txt("Reset Template"), Background(pal.Primary), Foreground("white"), Relief("raised"), Borderwidth(1), Command(onResetTemplate))
+	Grid(rv.resetTmplBtn, In(rv.actionsFrame), Row(0), Column(5), Sticky("we"), Padx("0.2m"), Pady("0.2m"))
 	rv.exitBtn = Button(Txt("Exit"), Background(pal.Danger), Foreground("white"), Relief("raised"), Borderwidth(1), Command(onExit))
-	Grid(rv.exitBtn, In(rv.actionsFrame), Row(0), Column(4), Sticky("we"), Padx("0.2m"), Pady("0.2m"))
+	Grid(rv.exitBtn, In(rv.actionsFrame), Row(0), Column(6), Sticky("we"), Padx("0.2m"), Pady("0.2m"))
 
 	rv.configVisible = false
 	rv.configFrame = nil
@@ -168,6 +181,7 @@ func (rv *RootView) Build(titles []string, onToggleCapture func(), onSelectionGr
 	Grid(rv.statusBarFrame, Row(2), Column(0), Columnspan(2), Sticky("we"))
 	rv.StatusLabel = Label(Txt("Ready"), Anchor("w"))
 	Grid(rv.StatusLabel, In(rv.statusBarFrame), Row(0), Column(0), Sticky("w"), Padx("0.4m"), Pady("0.2m"))
+	rv.DetectionStats = NewDetectionStats(rv.statusBarFrame, 0, 1)
 
 	rv.toggleConfigBtn = Button(Txt("Show Config"), Background(pal.Primary), Foreground("white"), Relief("raised"), Borderwidth(1),
 		Command(func() { rv.toggleConfig() }))
@@ -178,6 +192,11 @@ func (rv *RootView) Build(titles []string, onToggleCapture func(), onSelectionGr
 		Command(func() { rv.toggleDarkMode() }))
 	Grid(rv.darkToggleBtn, In(rv.leftInlineFrame), Row(0), Column(3), Sticky("w"), Padx("0.2m"), Pady("0.1m"))
 
+	// Preprocessed preview toggle
+	rv.processedBtn = Button(Txt("Show Processed"), Background(pal.Primary), Foreground("white"), Relief("raised"), Borderwidth(1),
+		Command(func() { rv.toggleProcessed() }))
+	Grid(rv.processedBtn, In(rv.leftInlineFrame), Row(0), Column(4), Sticky("w"), Padx("0.2m"), Pady("0.1m"))
+
 	// Apply initial palette to labels
 	rv.applyPalette()
 }
@@ -213,6 +232,48 @@ func (rv *RootView) UpdateDetection(img image.Image) {
 	}
 }
 
+// OnPreviewSample registers fn for ctrl-clicks on the capture preview (see
+// CapturePreview.OnSample).
+func (rv *RootView) OnPreviewSample(fn func(pt image.Point, add bool)) {
+	if rv != nil && rv.CapturePrev != nil {
+		rv.CapturePrev.OnSample(fn)
+	}
+}
+
+// OnPreviewReject registers fn for right-clicks on the capture preview (see
+// CapturePreview.OnReject).
+func (rv *RootView) OnPreviewReject(fn func(pt image.Point, remove bool)) {
+	if rv != nil && rv.CapturePrev != nil {
+		rv.CapturePrev.OnReject(fn)
+	}
+}
+
+// OnPreviewProcessed registers fn for the preview toggle; on reports that the
+// previews should show images after the preprocessing filters.
+func (rv *RootView) OnPreviewProcessed(fn func(on bool)) {
+	if rv != nil {
+		rv.onProcessed = fn
+	}
+}
+
+// toggleProcessed switches the previews between raw and preprocessed images.
+func (rv *RootView) toggleProcessed() {
+	if rv == nil {
+		return
+	}
+	rv.showProcessed = !rv.showProcessed
+	if rv.processedBtn != nil {
+		if rv.showProcessed {
+			rv.processedBtn.Configure(Txt("Show Raw"))
+		} else {
+			rv.processedBtn.Configure(Txt("Show Processed"))
+		}
+	}
+	if rv.onProcessed != nil {
+		rv.onProcessed(rv.showProcessed)
+	}
+}
+
 // SetSession updates both session and total capture durations.
 func (rv *RootView) SetSession(session, total time.Duration) {
 	if rv == nil || rv.Session == nil {
@@ -222,6 +283,13 @@ func (rv *RootView) SetSession(session, total time.Duration) {
 	rv.Session.SetTotal(total)
 }
 
+// SetDetectionStat updates a keyed detection diagnostic in the status bar.
+func (rv *RootView) SetDetectionStat(key, text string) {
+	if rv != nil && rv.DetectionStats != nil {
+		rv.DetectionStats.SetStat(key, text)
+	}
+}
+
 // --- CapturePresenter view contract methods ---
 // PreviewReset clears the capture preview canvas.
 func (rv *RootView) PreviewReset() {
@@ -318,6 +386,9 @@ func (rv *RootView) applyPalette() {
 	if rv.StatusLabel != nil {
 		rv.StatusLabel.Configure(Background(pal.Surface), Foreground(pal.TextMuted))
 	}
+	if rv.DetectionStats != nil {
+		rv.DetectionStats.ApplyPalette()
+	}
 	if rv.captureLabel != nil {
 		rv.captureLabel.Configure(Background(pal.Surface))
 	}
@@ -336,12 +407,21 @@ func (rv *RootView) applyPalette() {
 		}
 		rv.darkToggleBtn.Configure(Background(pal.Primary), Foreground("white"))
 	}
+	if rv.processedBtn != nil {
+		rv.processedBtn.Configure(Background(pal.Primary), Foreground("white"))
+	}
 	if rv.captureBtn != nil {
 		rv.captureBtn.Configure(Background(pal.Primary), Foreground("white"))
 	}
 	if rv.selectionBtn != nil {
 		rv.selectionBtn.Configure(Background(pal.Primary), Foreground("white"))
 	}
+	if rv.heatmapBtn != nil {
+		rv.heatmapBtn.Configure(Background(pal.Primary), Foreground("white"))
+	}
+	if rv.resetTmplBtn != nil {
+		rv.resetTmplBtn.Configure(Background(pal.Primary), Foreground("white"))
+	}
 	if rv.exitBtn != nil {
 		rv.exitBtn.Configure(Background(pal.Danger), Foreground("white"))
 	}
@@ -8,6 +8,7 @@ import (
 	"strconv"
 	"strings"
 	"sync/atomic"
+	"time"
 
 	"github.com/soocke/pixel-bot-go/config"
 
@@ -21,6 +22,10 @@ type SelectionOverlay interface {
 	OpenOrFocus()
 	Clear()
 	ActiveRect() *image.Rectangle
+	// SetSuggester enables the overlay's "Suggest Water" button, which moves
+	// the overlay onto the rectangle returned by fn. fn runs on a background
+	// goroutine, so it may take screenshots without freezing the UI.
+	SetSuggester(fn func() (image.Rectangle, error))
 }
 
 type selectionOverlay struct {
@@ -29,6 +34,18 @@ type selectionOverlay struct {
 	cfgPath   string
 	selection atomic.Value // stores image.Rectangle
 	win       *ToplevelWidget
+	suggest   func() (image.Rectangle, error)
+	// suggesting is set while a suggestion is computed (UI thread only).
+	suggesting bool
+}
+
+// suggestPoll is how often the UI thread checks for a finished suggestion.
+const suggestPoll = 50 * time.Millisecond
+
+// suggestion is the outcome of a water region suggestion.
+type suggestion struct {
+	rect image.Rectangle
+	err  error
 }
 
 // NewSelectionOverlay creates a new overlay manager.
@@ -81,6 +98,10 @@ func (v *selectionOverlay) OpenOrFocus() {
 	Grid(cancel, In(controls), Row(0), Column(1), Sticky("we"), Padx("0.2m"), Pady("0.2m"))
 	clear := win.Button(Txt("Clear"), Command(v.Clear))
 	Grid(clear, In(controls), Row(0), Column(2), Sticky("we"), Padx("0.2m"), Pady("0.2m"))
+	if v.suggest != nil {
+		suggest := win.Button(Txt("Suggest Water"), Command(v.suggestWater))
+		Grid(suggest, In(controls), Row(0), Column(3), Sticky("we"), Padx("0.2m"), Pady("0.2m"))
+	}
 	Bind(win, "<Return>", Command(v.confirm))
 	Bind(win, "<Escape>", Command(v.cancel))
 }
@@ -109,6 +130,48 @@ func (v *selectionOverlay) confirm() {
 	v.destroy()
 }
 
+func (v *selectionOverlay) SetSuggester(fn func() (image.Rectangle, error)) { v.suggest = fn }
+
+// suggestWater pre-fills the overlay with the suggested water area; the user
+// still confirms it. The suggestion is computed in the background and
+// applied on the UI thread once it is ready.
+func (v *selectionOverlay) suggestWater() {
+	if v.win == nil || v.suggest == nil || v.suggesting {
+		return
+	}
+	v.suggesting = true
+	var done atomic.Pointer[suggestion]
+	go func() {
+		rect, err := v.suggest()
+		done.Store(&suggestion{rect: rect, err: err})
+	}()
+	var poll func()
+	poll = func() {
+		s := done.Load()
+		if s == nil {
+			TclAfter(suggestPoll, poll)
+			return
+		}
+		v.suggesting = false
+		v.applySuggestion(*s)
+	}
+	TclAfter(suggestPoll, poll)
+}
+
+// applySuggestion moves the overlay, if still open, onto a suggested area.
+func (v *selectionOverlay) applySuggestion(s suggestion) {
+	if s.err != nil {
+		if v.logger != nil {
+			v.logger.Warn("water region suggestion failed", "error", s.err)
+		}
+		return
+	}
+	if v.win == nil {
+		return
+	}
+	WmGeometry(v.win.Window, fmt.Sprintf("%dx%d+%d+%d", s.rect.Dx(), s.rect.Dy(), s.rect.Min.X, s.rect.Min.Y))
+}
+
 func (v *selectionOverlay) cancel() { v.destroy() }
 
 func (v *selectionOverlay) destroy() {